@@ -0,0 +1,115 @@
+package runtime
+
+import (
+    "context"
+    "fmt"
+
+    "slack-jira-integration/chat"
+)
+
+// AccessPolicy restricts who may escalate, deny rules are checked before
+// allow rules and an empty allow list lets everyone not denied through
+type AccessPolicy struct {
+    AllowUsers []string
+    DenyUsers []string
+    AllowGroups []string
+    DenyGroups []string
+    DenyGuests bool
+}
+
+// WithAccessPolicies, register the default policy and the named policies
+// routes can select with backend@policy
+func (r *runtime) WithAccessPolicies(defaultPolicy *AccessPolicy, policies map[string]*AccessPolicy) (*runtime, error) {
+    for _, route := range r.Routes {
+        if _, exists := policies[route.Policy]; route.Policy != "" && !exists {
+            return nil, fmt.Errorf("route for channel '%s' uses unknown access policy '%s'", route.Channel, route.Policy)
+        }
+    }
+
+    r.AccessPolicy = defaultPolicy
+    r.AccessPolicies = policies
+
+    return r, nil
+
+}
+
+func contains(values []string, value string) bool {
+    for _, v := range values {
+        if v == value {
+            return true
+        }
+    }
+
+    return false
+
+}
+
+// inAnyGroup, whether user belongs to one of the user groups, looked up with
+// the frontend e.g. usergroups.users.list on Slack
+func inAnyGroup(ctx context.Context, frontend chat.Frontend, groups []string, user string) (bool, error) {
+    for _, group := range groups {
+        members, err := frontend.UserGroupMembers(ctx, group)
+        if err != nil {
+            return false, err
+        }
+
+        if contains(members, user) {
+            return true, nil
+        }
+    }
+
+    return false, nil
+
+}
+
+// Authorize, whether user may escalate under the policy, the reason explains
+// a rejection to the user, a nil policy allows everyone
+func (p *AccessPolicy) Authorize(ctx context.Context, frontend chat.Frontend, user string) (string, bool, error) {
+    if p == nil {
+        return "", true, nil
+    }
+
+    if contains(p.DenyUsers, user) {
+        return "you are not allowed to create Jira issues here", false, nil
+    }
+
+    if p.DenyGuests {
+        guest, err := frontend.IsGuest(ctx, user)
+        if err != nil {
+            return "", false, err
+        }
+
+        if guest {
+            return "guests cannot create Jira issues", false, nil
+        }
+    }
+
+    denied, err := inAnyGroup(ctx, frontend, p.DenyGroups, user)
+    if err != nil {
+        return "", false, err
+    }
+
+    if denied {
+        return "your user group is not allowed to create Jira issues here", false, nil
+    }
+
+    if len(p.AllowUsers) == 0 && len(p.AllowGroups) == 0 {
+        return "", true, nil
+    }
+
+    if contains(p.AllowUsers, user) {
+        return "", true, nil
+    }
+
+    allowed, err := inAnyGroup(ctx, frontend, p.AllowGroups, user)
+    if err != nil {
+        return "", false, err
+    }
+
+    if !allowed {
+        return "only approved users can create Jira issues here", false, nil
+    }
+
+    return "", true, nil
+
+}
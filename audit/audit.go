@@ -0,0 +1,239 @@
+package audit
+
+import (
+    "bufio"
+    "context"
+    "crypto/subtle"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "strconv"
+    "sync"
+    "time"
+)
+
+const (
+    OutcomeCreated = "created"
+    OutcomeFailed = "failed"
+    OutcomeDenied = "denied"
+    OutcomeRateLimited = "rate_limited"
+    OutcomeVoted = "voted"
+    OutcomeWithdrawn = "withdrawn"
+    OutcomeLinked = "linked"
+    OutcomeUpdated = "updated"
+)
+
+// ErrNotSearchable is returned by Search for sinks that can only be written to
+var ErrNotSearchable = errors.New("audit sink is not searchable")
+
+// Record is one append-only audit entry for an escalation
+type Record struct {
+    Time time.Time `json:"time"`
+    Action string `json:"action"`
+    TeamID string `json:"team_id,omitempty"`
+    UserID string `json:"user_id"`
+    ChannelID string `json:"channel_id"`
+    MessageTS string `json:"message_ts"`
+    Emoji string `json:"emoji"`
+    Project string `json:"project,omitempty"`
+    IssueKey string `json:"issue_key,omitempty"`
+    Outcome string `json:"outcome"`
+    Error string `json:"error,omitempty"`
+}
+
+// Query filters Search results, empty fields match everything
+type Query struct {
+    UserID string
+    ChannelID string
+    IssueKey string
+    Since time.Time
+    Until time.Time
+    Limit int
+}
+
+// Matches, whether record satisfies every filter set on q
+func (q Query) Matches(record Record) bool {
+    return (q.UserID == "" || q.UserID == record.UserID) &&
+        (q.ChannelID == "" || q.ChannelID == record.ChannelID) &&
+        (q.IssueKey == "" || q.IssueKey == record.IssueKey) &&
+        (q.Since.IsZero() || !record.Time.Before(q.Since)) &&
+        (q.Until.IsZero() || record.Time.Before(q.Until))
+
+}
+
+// Sink stores audit records, records are only ever appended
+type Sink interface {
+    Write(ctx context.Context, record Record) error
+    Search(ctx context.Context, query Query) ([]Record, error)
+}
+
+// writerSink appends JSON lines to an io.Writer such as stdout for a log
+// pipeline to collect, it cannot be searched
+type writerSink struct {
+    mu sync.Mutex
+    out io.Writer
+}
+
+// NewWriterSink, construct a Sink writing one JSON record per line to out
+func NewWriterSink(out io.Writer) Sink {
+    return &writerSink{out: out}
+
+}
+
+func (s *writerSink) Write(ctx context.Context, record Record) error {
+    line, err := json.Marshal(record)
+    if err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    _, err = s.out.Write(append(line, '\n'))
+    return err
+
+}
+
+func (s *writerSink) Search(ctx context.Context, query Query) ([]Record, error) {
+    return nil, ErrNotSearchable
+
+}
+
+// fileSink appends JSON lines to a file opened in append-only mode
+type fileSink struct {
+    writerSink
+    path string
+}
+
+// NewFileSink, construct a Sink appending JSON lines to the file at path,
+// creating it if needed
+func NewFileSink(path string) (Sink, error) {
+    file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+    if err != nil {
+        return nil, fmt.Errorf("open audit log err: %w", err)
+    }
+
+    return &fileSink{writerSink: writerSink{out: file}, path: path}, nil
+
+}
+
+func (s *fileSink) Search(ctx context.Context, query Query) ([]Record, error) {
+    file, err := os.Open(s.path)
+    if err != nil {
+        return nil, fmt.Errorf("open audit log err: %w", err)
+    }
+    defer file.Close()
+
+    records := []Record{}
+    scanner := bufio.NewScanner(file)
+    for scanner.Scan() {
+        var record Record
+        if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+            return nil, fmt.Errorf("read audit log err: %w", err)
+        }
+
+        if query.Matches(record) {
+            records = append(records, record)
+        }
+        if query.Limit > 0 && len(records) == query.Limit {
+            break
+        }
+    }
+
+    return records, scanner.Err()
+
+}
+
+// NewSink, construct the Sink named by kind, "file" appends to target as a
+// path, "sql" stores in the database at DSN target using driver, "stdout"
+// writes to stdout
+func NewSink(kind string, target string, driver string) (Sink, error) {
+    switch kind {
+    case "stdout":
+        return NewWriterSink(os.Stdout), nil
+
+    case "file":
+        return NewFileSink(target)
+
+    case "sql":
+        return OpenSQLSink(driver, target)
+
+    default:
+        return nil, fmt.Errorf("unknown audit sink '%s'", kind)
+    }
+
+}
+
+// ParseQuery, read a Query from the user, channel, issue, since, until and
+// limit parameters, dates are RFC 3339
+func ParseQuery(params map[string][]string) (Query, error) {
+    get := func(key string) string {
+        if values := params[key]; len(values) > 0 {
+            return values[0]
+        }
+        return ""
+    }
+
+    query := Query{
+        UserID: get("user"),
+        ChannelID: get("channel"),
+        IssueKey: get("issue"),
+    }
+
+    var err error
+    if since := get("since"); since != "" {
+        if query.Since, err = time.Parse(time.RFC3339, since); err != nil {
+            return query, fmt.Errorf("invalid since: %w", err)
+        }
+    }
+    if until := get("until"); until != "" {
+        if query.Until, err = time.Parse(time.RFC3339, until); err != nil {
+            return query, fmt.Errorf("invalid until: %w", err)
+        }
+    }
+    if limit := get("limit"); limit != "" {
+        if query.Limit, err = strconv.Atoi(limit); err != nil {
+            return query, fmt.Errorf("invalid limit: %w", err)
+        }
+    }
+
+    return query, nil
+
+}
+
+// Handler, admin endpoint searching sink with the ParseQuery parameters,
+// records name users and messages so requests must carry token as a Bearer
+// token, an empty token refuses every request
+func Handler(sink Sink, token string) http.HandlerFunc {
+    return func(resp http.ResponseWriter, req *http.Request) {
+        given := []byte(req.Header.Get("Authorization"))
+        if token == "" || subtle.ConstantTimeCompare(given, []byte("Bearer " + token)) != 1 {
+            http.Error(resp, "unauthorized", http.StatusUnauthorized)
+            return
+        }
+
+        query, err := ParseQuery(req.URL.Query())
+        if err != nil {
+            http.Error(resp, err.Error(), http.StatusBadRequest)
+            return
+        }
+
+        records, err := sink.Search(req.Context(), query)
+        if errors.Is(err, ErrNotSearchable) {
+            http.Error(resp, err.Error(), http.StatusNotImplemented)
+            return
+        }
+        if err != nil {
+            http.Error(resp, err.Error(), http.StatusInternalServerError)
+            return
+        }
+
+        resp.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(resp).Encode(records)
+
+    }
+
+}
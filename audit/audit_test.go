@@ -0,0 +1,154 @@
+package audit
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "regexp"
+    "testing"
+    "time"
+
+    "github.com/DATA-DOG/go-sqlmock"
+    "github.com/stretchr/testify/assert"
+)
+
+var (
+    day = time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)
+
+    records = []Record{
+        {Time: day, Action: "escalate", UserID: "U1", ChannelID: "C1", Emoji: "mega", IssueKey: "TEST-1", Outcome: OutcomeCreated},
+        {Time: day.Add(time.Hour), Action: "escalate", UserID: "U2", ChannelID: "C1", Emoji: "mega", Outcome: OutcomeFailed, Error: "some error"},
+        {Time: day.Add(48 * time.Hour), Action: "escalate", UserID: "U1", ChannelID: "C2", Emoji: "mega", IssueKey: "TEST-2", Outcome: OutcomeCreated},
+    }
+)
+
+func TestQueryMatches(t *testing.T) {
+    tests := []struct {
+        query Query
+        matches int
+    }{
+        {Query{}, 3},
+        {Query{UserID: "U1"}, 2},
+        {Query{ChannelID: "C1", UserID: "U1"}, 1},
+        {Query{IssueKey: "TEST-2"}, 1},
+        {Query{Since: day, Until: day.Add(24 * time.Hour)}, 2},
+    }
+
+    for _, test := range tests {
+        matches := 0
+        for _, record := range records {
+            if test.query.Matches(record) {
+                matches++
+            }
+        }
+        assert.Equal(t, test.matches, matches, test.query)
+    }
+
+}
+
+func TestFileSink(t *testing.T) {
+    sink, err := NewFileSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+    assert.Nil(t, err)
+
+    for _, record := range records {
+        assert.Nil(t, sink.Write(context.Background(), record))
+    }
+
+    found, err := sink.Search(context.Background(), Query{UserID: "U1"})
+    assert.Nil(t, err)
+    assert.Equal(t, []Record{records[0], records[2]}, found)
+
+    found, err = sink.Search(context.Background(), Query{Limit: 1})
+    assert.Nil(t, err)
+    assert.Equal(t, []Record{records[0]}, found)
+
+}
+
+func TestWriterSink(t *testing.T) {
+    var out bytes.Buffer
+    sink := NewWriterSink(&out)
+
+    assert.Nil(t, sink.Write(context.Background(), records[1]))
+
+    var record Record
+    assert.Nil(t, json.Unmarshal(out.Bytes(), &record))
+    assert.Equal(t, records[1], record)
+
+    _, err := sink.Search(context.Background(), Query{})
+    assert.Equal(t, ErrNotSearchable, err)
+
+}
+
+func TestSQLSink(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    assert.Nil(t, err)
+    defer db.Close()
+
+    mock.ExpectExec("CREATE TABLE IF NOT EXISTS audit_log").WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectExec(regexp.QuoteMeta("INSERT INTO audit_log (" + columns + ") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)")).
+        WithArgs(day, "escalate", "", "U1", "C1", "", "mega", "", "TEST-1", OutcomeCreated, "").
+        WillReturnResult(sqlmock.NewResult(1, 1))
+    mock.ExpectQuery(regexp.QuoteMeta("SELECT " + columns + " FROM audit_log WHERE user_id = $1 AND time >= $2 ORDER BY time LIMIT 10")).
+        WithArgs("U1", day).
+        WillReturnRows(sqlmock.NewRows([]string{"time", "action", "team_id", "user_id", "channel_id", "message_ts", "emoji", "project", "issue_key", "outcome", "error"}).
+            AddRow(day, "escalate", "", "U1", "C1", "", "mega", "", "TEST-1", OutcomeCreated, ""))
+
+    sink, err := NewSQLSink(db, "postgres")
+    assert.Nil(t, err)
+
+    assert.Nil(t, sink.Write(context.Background(), records[0]))
+
+    found, err := sink.Search(context.Background(), Query{UserID: "U1", Since: day, Limit: 10})
+    assert.Nil(t, err)
+    assert.Equal(t, []Record{records[0]}, found)
+
+    assert.Nil(t, mock.ExpectationsWereMet())
+
+    // only the postgres driver is built in
+    _, err = NewSQLSink(db, "mysql")
+    assert.ErrorContains(t, err, "unsupported audit sql driver 'mysql'")
+
+}
+
+func TestHandler(t *testing.T) {
+    sink, err := NewFileSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+    assert.Nil(t, err)
+    for _, record := range records {
+        assert.Nil(t, sink.Write(context.Background(), record))
+    }
+
+    request := func(target string) *http.Request {
+        req := httptest.NewRequest("GET", target, nil)
+        req.Header.Set("Authorization", "Bearer some-token")
+        return req
+    }
+
+    rr := httptest.NewRecorder()
+    Handler(sink, "some-token")(rr, request("/audit?issue=TEST-2"))
+    assert.Equal(t, http.StatusOK, rr.Code)
+
+    var found []Record
+    assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &found))
+    assert.Equal(t, []Record{records[2]}, found)
+
+    rr = httptest.NewRecorder()
+    Handler(sink, "some-token")(rr, request("/audit?since=yesterday"))
+    assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+    rr = httptest.NewRecorder()
+    Handler(NewWriterSink(&bytes.Buffer{}), "some-token")(rr, request("/audit"))
+    assert.Equal(t, http.StatusNotImplemented, rr.Code)
+
+    // records are only shown with the token
+    rr = httptest.NewRecorder()
+    Handler(sink, "some-token")(rr, httptest.NewRequest("GET", "/audit", nil))
+    assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+    rr = httptest.NewRecorder()
+    Handler(sink, "")(rr, httptest.NewRequest("GET", "/audit", nil))
+    assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+}
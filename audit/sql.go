@@ -0,0 +1,135 @@
+package audit
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "strings"
+
+    _ "github.com/lib/pq"
+)
+
+const createTable = `CREATE TABLE IF NOT EXISTS audit_log (
+    time TIMESTAMP NOT NULL,
+    action TEXT NOT NULL,
+    team_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    channel_id TEXT NOT NULL,
+    message_ts TEXT NOT NULL,
+    emoji TEXT NOT NULL,
+    project TEXT NOT NULL,
+    issue_key TEXT NOT NULL,
+    outcome TEXT NOT NULL,
+    error TEXT NOT NULL
+)`
+
+const columns = "time, action, team_id, user_id, channel_id, message_ts, emoji, project, issue_key, outcome, error"
+
+// sqlSink stores records in the audit_log table, only ever inserting
+type sqlSink struct {
+    db *sql.DB
+}
+
+// OpenSQLSink, open the database at dsn and create the audit_log table if
+// it does not exist yet
+func OpenSQLSink(driver string, dsn string) (Sink, error) {
+    db, err := sql.Open(driver, dsn)
+    if err != nil {
+        return nil, fmt.Errorf("open audit database err: %w", err)
+    }
+
+    return NewSQLSink(db, driver)
+
+}
+
+// NewSQLSink, construct a Sink on db, only postgres is supported as only its
+// driver is built in
+func NewSQLSink(db *sql.DB, driver string) (Sink, error) {
+    if driver != "postgres" {
+        return nil, fmt.Errorf("unsupported audit sql driver '%s', only postgres is built in", driver)
+    }
+
+    if _, err := db.Exec(createTable); err != nil {
+        return nil, fmt.Errorf("create audit table err: %w", err)
+    }
+
+    return &sqlSink{db: db}, nil
+
+}
+
+// placeholder, the n-th query parameter in postgres' style
+func placeholder(n int) string {
+    return fmt.Sprintf("$%d", n)
+
+}
+
+func (s *sqlSink) Write(ctx context.Context, record Record) error {
+    placeholders := make([]string, 11)
+    for i := range placeholders {
+        placeholders[i] = placeholder(i + 1)
+    }
+
+    _, err := s.db.ExecContext(ctx,
+        fmt.Sprintf("INSERT INTO audit_log (%s) VALUES (%s)", columns, strings.Join(placeholders, ", ")),
+        record.Time.UTC(), record.Action, record.TeamID, record.UserID, record.ChannelID, record.MessageTS,
+        record.Emoji, record.Project, record.IssueKey, record.Outcome, record.Error)
+    if err != nil {
+        return fmt.Errorf("insert audit record err: %w", err)
+    }
+
+    return nil
+
+}
+
+func (s *sqlSink) Search(ctx context.Context, query Query) ([]Record, error) {
+    var conditions []string
+    var args []interface{}
+    where := func(condition string, arg interface{}) {
+        args = append(args, arg)
+        conditions = append(conditions, condition + " " + placeholder(len(args)))
+    }
+
+    if query.UserID != "" {
+        where("user_id =", query.UserID)
+    }
+    if query.ChannelID != "" {
+        where("channel_id =", query.ChannelID)
+    }
+    if query.IssueKey != "" {
+        where("issue_key =", query.IssueKey)
+    }
+    if !query.Since.IsZero() {
+        where("time >=", query.Since.UTC())
+    }
+    if !query.Until.IsZero() {
+        where("time <", query.Until.UTC())
+    }
+
+    statement := fmt.Sprintf("SELECT %s FROM audit_log", columns)
+    if len(conditions) > 0 {
+        statement += " WHERE " + strings.Join(conditions, " AND ")
+    }
+    statement += " ORDER BY time"
+    if query.Limit > 0 {
+        statement += fmt.Sprintf(" LIMIT %d", query.Limit)
+    }
+
+    rows, err := s.db.QueryContext(ctx, statement, args...)
+    if err != nil {
+        return nil, fmt.Errorf("search audit records err: %w", err)
+    }
+    defer rows.Close()
+
+    records := []Record{}
+    for rows.Next() {
+        var record Record
+        if err := rows.Scan(&record.Time, &record.Action, &record.TeamID, &record.UserID, &record.ChannelID,
+            &record.MessageTS, &record.Emoji, &record.Project, &record.IssueKey, &record.Outcome, &record.Error); err != nil {
+            return nil, fmt.Errorf("read audit record err: %w", err)
+        }
+        records = append(records, record)
+    }
+
+    return records, rows.Err()
+
+}
@@ -0,0 +1,97 @@
+package runtime
+
+import (
+    "context"
+
+    "slack-jira-integration/chat"
+)
+
+// TicketBackend is where escalations are tracked, *jira.JiraEnv is one
+// implementation and *github.GitHubEnv another, keys are whatever the backend
+// identifies its tickets by e.g. "OPS-1" or "org/repo#12"
+type TicketBackend interface {
+    // CreateTicket, open a ticket with the given description, returns its key
+    CreateTicket(ctx context.Context, description string) (string, error)
+    CommentTicket(ctx context.Context, key string, comment string) error
+    // TicketStatus, the backend's name for the ticket's current state
+    TicketStatus(ctx context.Context, key string) (string, error)
+    // TicketURL, the link posted back to the thread
+    TicketURL(key string) string
+    // TicketProject, the project or repository new tickets go to, used to
+    // label metrics and audit records
+    TicketProject() string
+    // Ping, whether the backend is reachable with the configured credentials
+    Ping(ctx context.Context) error
+}
+
+// TicketDetailCreator is implemented by backends that know what a ticket
+// looks like as they create it, the card of a new ticket is then shown
+// without looking the ticket up
+type TicketDetailCreator interface {
+    CreateTicketDetails(ctx context.Context, description string) (*chat.Ticket, error)
+}
+
+// TicketDeleter is implemented by backends that can delete a ticket, needed to
+// undo escalations with UndoDelete
+type TicketDeleter interface {
+    DeleteTicket(ctx context.Context, key string) error
+}
+
+// TicketTransitioner is implemented by backends that can move a ticket to
+// another state by name, needed to undo escalations with UndoTransition
+type TicketTransitioner interface {
+    TransitionTicket(ctx context.Context, key string, name string) error
+}
+
+// TicketGetter is implemented by backends that can look a ticket up, needed to
+// link threads to existing tickets and to show tickets as cards
+type TicketGetter interface {
+    // GetTicket, what a chat shows of the ticket, an error when it does not exist
+    GetTicket(ctx context.Context, key string) (*chat.Ticket, error)
+}
+
+// TicketLinker is implemented by backends that can point a ticket back at the
+// thread it is discussed in
+type TicketLinker interface {
+    LinkTicket(ctx context.Context, key string, url string, title string) error
+}
+
+// TicketUnlinker is implemented by linkers that can remove the link to a
+// thread again, needed to undo linking a thread to an existing ticket
+type TicketUnlinker interface {
+    UnlinkTicket(ctx context.Context, key string, url string) error
+}
+
+// TicketKeyFinder is implemented by backends that can recognise their keys in
+// message text, a reaction on a thread mentioning a key links the thread to
+// that ticket instead of creating a new one
+type TicketKeyFinder interface {
+    FindTicketKeys(text string) []string
+}
+
+// TicketAssigner is implemented by backends that can assign a ticket to the
+// account with an email address, needed by the card's "Assign to me" button
+type TicketAssigner interface {
+    AssignTicket(ctx context.Context, key string, email string) error
+}
+
+// TicketTransitionLister is implemented by transitioners that can tell which
+// transitions are currently available, needed by the card's "Transition" button
+type TicketTransitionLister interface {
+    TicketTransitioner
+    TicketTransitions(ctx context.Context, key string) ([]string, error)
+}
+
+// TicketPrioritizer is implemented by backends that can change a ticket's
+// priority, needed by the card's "Change priority" button
+type TicketPrioritizer interface {
+    // TicketPriorities, the names of the priorities tickets can be given
+    TicketPriorities(ctx context.Context) ([]string, error)
+    PrioritizeTicket(ctx context.Context, key string, name string) error
+}
+
+// TicketURLParser is implemented by backends that can tell which ticket a
+// link opens, needed to unfurl links to tickets
+type TicketURLParser interface {
+    TicketKeyFromURL(link string) (string, bool)
+}
@@ -0,0 +1,74 @@
+package chat
+
+import (
+    "context"
+)
+
+// Message is one message of a thread, the thread's root comes first, Bot
+// is set on the messages of the integration itself
+type Message struct {
+    ID string
+    User string
+    Text string
+    Bot bool
+}
+
+// Ticket is what a chat shows of a ticket, Assignee and Priority are empty
+// when the ticket has none or the backend has no such notion
+type Ticket struct {
+    Key string
+    URL string
+    Summary string
+    Status string
+    Assignee string
+    Priority string
+}
+
+// Frontend is a chat service escalations come from and are answered in,
+// *slack.SlackEnv is one implementation and *mattermost.MattermostEnv
+// another, channels, users and messages are identified by the service's own ids
+type Frontend interface {
+    // Name, the service e.g. "slack", used to label logs, traces and events
+    Name() string
+    // ThreadMessages, every message of the thread messageID belongs to
+    ThreadMessages(ctx context.Context, channel string, messageID string) ([]Message, error)
+    // PostMessageToThread, reply in the thread of messageID, returns the id
+    // of the reply so it can be edited later
+    PostMessageToThread(ctx context.Context, channel string, messageID string, msgBody string) (string, error)
+    UpdateMessage(ctx context.Context, channel string, messageID string, msgBody string) error
+    // Permalink, the link opening the message in the chat
+    Permalink(ctx context.Context, channel string, messageID string) (string, error)
+    // PostEphemeral, send msgBody to user in channel, visible only to them
+    PostEphemeral(ctx context.Context, channel string, user string, msgBody string) error
+    IsGuest(ctx context.Context, user string) (bool, error)
+    UserGroupMembers(ctx context.Context, group string) ([]string, error)
+    // ChannelName, the configured name of channelID, empty when it is not configured
+    ChannelName(channelID string) string
+    // ChannelEmoji, the reaction escalating threads in channelID
+    ChannelEmoji(channelID string) (string, bool)
+}
+
+// Handler receives the reactions a frontend observes, implemented by the
+// runtime, it returns before the reaction is acted on so a frontend reading
+// events in a loop is never held up by an escalation
+type Handler interface {
+    HandleReactionAdded(ctx context.Context, frontend Frontend, ev *ReactionAdded)
+    HandleReactionRemoved(ctx context.Context, frontend Frontend, ev *ReactionRemoved)
+}
+
+// CardFrontend is implemented by frontends that can show a ticket as a card
+// with controls and update that card in place as the ticket changes
+type CardFrontend interface {
+    // PostTicketCard, reply in the thread of messageID with a card of ticket,
+    // returns the id of the reply
+    PostTicketCard(ctx context.Context, channel string, messageID string, ticket Ticket) (string, error)
+    UpdateTicketCard(ctx context.Context, channel string, messageID string, ticket Ticket) error
+    // ReplaceTicketCard, replace the card with the text msgBody
+    ReplaceTicketCard(ctx context.Context, channel string, messageID string, msgBody string) error
+}
+
+// MemberFrontend is implemented by frontends that can tell who is in a
+// channel, commands naming a channel are refused to everyone else
+type MemberFrontend interface {
+    IsChannelMember(ctx context.Context, channel string, user string) (bool, error)
+}
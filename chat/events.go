@@ -0,0 +1,131 @@
+package chat
+
+// the events below are the runtime's own model of what happens in a chat,
+// frontends translate their service's events into ReactionAdded and
+// ReactionRemoved, the runtime publishes the rest to its listeners
+
+// ReactionAdded, User reacted with Reaction to the message MessageID in Channel
+type ReactionAdded struct {
+    TeamID string
+    User string
+    Channel string
+    MessageID string
+    Reaction string
+}
+
+// ReactionRemoved, User took their Reaction to the message MessageID in Channel back
+type ReactionRemoved struct {
+    TeamID string
+    User string
+    Channel string
+    MessageID string
+    Reaction string
+}
+
+// Event is published by the runtime as an escalation progresses
+type Event interface {
+    EventType() string
+    // EventFrontend, the name of the frontend the escalation came from
+    EventFrontend() string
+}
+
+// ThreadEscalated, a ticket was created from the thread of MessageID
+type ThreadEscalated struct {
+    Frontend string
+    Channel string
+    MessageID string
+    User string
+    Reaction string
+    Project string
+    TicketKey string
+    TicketURL string
+}
+
+// ThreadLinked, the thread of MessageID was linked to the existing TicketKey
+// instead of escalating it to a new ticket
+type ThreadLinked struct {
+    Frontend string
+    Channel string
+    MessageID string
+    User string
+    Project string
+    TicketKey string
+    TicketURL string
+}
+
+// ReplyPosted, the runtime replied in the thread of MessageID
+type ReplyPosted struct {
+    Frontend string
+    Channel string
+    MessageID string
+    ReplyID string
+    Text string
+}
+
+// EscalationDenied, User may not escalate in Channel for Reason
+type EscalationDenied struct {
+    Frontend string
+    Channel string
+    MessageID string
+    User string
+    Reason string
+}
+
+// EscalationWithdrawn, User withdrew the escalation creating TicketKey
+type EscalationWithdrawn struct {
+    Frontend string
+    Channel string
+    MessageID string
+    User string
+    TicketKey string
+}
+
+func (ThreadEscalated) EventType() string {
+    return "thread_escalated"
+
+}
+
+func (ThreadLinked) EventType() string {
+    return "thread_linked"
+
+}
+
+func (ReplyPosted) EventType() string {
+    return "reply_posted"
+
+}
+
+func (EscalationDenied) EventType() string {
+    return "escalation_denied"
+
+}
+
+func (EscalationWithdrawn) EventType() string {
+    return "escalation_withdrawn"
+
+}
+
+func (e ThreadEscalated) EventFrontend() string {
+    return e.Frontend
+
+}
+
+func (e ThreadLinked) EventFrontend() string {
+    return e.Frontend
+
+}
+
+func (e ReplyPosted) EventFrontend() string {
+    return e.Frontend
+
+}
+
+func (e EscalationDenied) EventFrontend() string {
+    return e.Frontend
+
+}
+
+func (e EscalationWithdrawn) EventFrontend() string {
+    return e.Frontend
+
+}
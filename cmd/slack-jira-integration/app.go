@@ -0,0 +1,322 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	slackgo "github.com/slack-go/slack"
+
+    runtime "slack-jira-integration"
+    "slack-jira-integration/audit"
+    "slack-jira-integration/chat"
+    "slack-jira-integration/github"
+    "slack-jira-integration/health"
+    "slack-jira-integration/jira"
+    "slack-jira-integration/logging"
+    "slack-jira-integration/mattermost"
+    "slack-jira-integration/metrics"
+    "slack-jira-integration/server"
+    "slack-jira-integration/slack"
+)
+
+// app is everything main serves, built from the environment by newApp so
+// tests can drive the same routers
+type app struct {
+    Router http.Handler
+    AdminRouter http.Handler
+    Config server.Config
+    AdminConfig server.Config
+    SlackBotToken string
+    // SocketMode, the handlers of events received over Socket Mode
+    SocketMode slack.SocketModeHandlers
+    // Connections receive events over connections the app opens itself,
+    // each runs until ctx is done
+    Connections []func(context.Context) error
+    Wait func(context.Context) error
+    Drain func(context.Context) error
+}
+
+// newApp, read the configuration from the environment, construct the
+// runtime and register every route on the public and admin routers
+func (e environment) newApp() (*app, error) {
+	slackSigningSecret := e.getEnv("SLACK_SIGNING_SECRET")
+	slackBotToken := e.getEnv("SLACK_BOT_TOKEN")
+    slackChannels := strings.Split(e.getEnv("SLACK_CHANNELS"),",")
+    slackEmojis := e.getEmojisByChannel("SLACK_EMOJI", slackChannels)
+
+    jiraRoutes, err := runtime.ParseRoutes(e.getEnv("JIRA_ROUTES"))
+    if err != nil {
+        return nil, fmt.Errorf("invalid jira routes: %w", err)
+
+    }
+
+	slackClientID := e.getEnv("SLACK_CLIENT_ID")
+	slackClientSecret := e.getEnv("SLACK_CLIENT_SECRET")
+	slackRedirectURL := e.getEnv("SLACK_REDIRECT_URL")
+	slackScopes := strings.Split(e.getEnv("SLACK_SCOPES"), ",")
+	slackTokenStoreFile := e.getEnv("SLACK_TOKEN_STORE_FILE")
+
+    // SLACK_API_URL points the clients at another Slack API e.g. a fake in tests
+    var slackOptions []slackgo.Option
+    if slackAPIURL := e.getEnv("SLACK_API_URL"); slackAPIURL != "" {
+        slackOptions = append(slackOptions, slackgo.OptionAPIURL(slackAPIURL))
+    }
+
+    // a single workspace bot token is optional once the OAuth install flow is configured
+    var slackEnv *slack.SlackEnv
+    if slackBotToken != "" || slackClientID == "" {
+        slackEnv, err = slack.NewEnv(slack.NewClient(slackBotToken, slackOptions...), slackSigningSecret, slackEmojis, slackChannels)
+        if err != nil {
+            return nil, fmt.Errorf("slack env setup failed: %w", err)
+
+        }
+        slackEnv.UserGroupCacheTTL = e.getDuration("ACCESS_GROUP_CACHE_TTL", slack.DefaultUserGroupCacheTTL)
+    }
+
+    // MATTERMOST_URL adds a Mattermost team as a second chat frontend
+    var mattermostEnv *mattermost.MattermostEnv
+    mattermostChannels := e.getList("MATTERMOST_CHANNELS")
+    if mattermostURL := e.getEnv("MATTERMOST_URL"); mattermostURL != "" {
+        mattermostEmojis := e.getEmojisByChannel("MATTERMOST_EMOJI", mattermostChannels)
+        mattermostEnv, err = mattermost.NewEnv(mattermostURL, e.getEnv("MATTERMOST_TOKEN"), e.getEnv("MATTERMOST_TEAM"), mattermostEmojis, mattermostChannels)
+        if err != nil {
+            return nil, fmt.Errorf("mattermost env setup failed: %w", err)
+
+        }
+        mattermostEnv.WebhookToken = e.getEnv("MATTERMOST_WEBHOOK_TOKEN")
+    }
+
+    backend, backends, err := e.getBackends()
+    if err != nil {
+        return nil, fmt.Errorf("ticket backend setup failed: %w", err)
+
+    }
+
+    r, err := runtime.New(slackEnv, backend).WithBackends(backends, jiraRoutes)
+    if err != nil {
+        return nil, fmt.Errorf("runtime setup failed: %w", err)
+
+    }
+
+    r, err = r.WithAccessPolicies(e.getAccessPolicies())
+    if err != nil {
+        return nil, fmt.Errorf("access policy setup failed: %w", err)
+
+    }
+
+    votes, err := e.getVotes(append(slackChannels, mattermostChannels...))
+    if err != nil {
+        return nil, fmt.Errorf("escalation threshold setup failed: %w", err)
+
+    }
+    if votes != nil {
+        r.WithVotes(votes)
+    }
+
+    // removing the reaction within UNDO_GRACE_PERIOD withdraws the escalation
+    if gracePeriod := e.getDuration("UNDO_GRACE_PERIOD", 0); gracePeriod > 0 {
+        undoTransition := e.getEnv("UNDO_TRANSITION")
+        if undoTransition == "" {
+            undoTransition = "Won't Do"
+        }
+
+        undo, err := runtime.NewUndo(gracePeriod, e.getEnv("UNDO_ACTION"), undoTransition)
+        if err != nil {
+            return nil, fmt.Errorf("undo setup failed: %w", err)
+
+        }
+
+        // e.g. GitHub issues cannot be deleted, refuse to start rather than
+        // fail the first withdrawal
+        if _, err := r.WithUndo(undo); err != nil {
+            return nil, fmt.Errorf("undo setup failed: %w", err)
+
+        }
+    }
+
+    // SLACK_UNFURL_CHANNELS opts channels into showing links to tickets as
+    // cards, "*" opts in every channel
+    unfurlChannels := e.getList("SLACK_UNFURL_CHANNELS")
+    if len(unfurlChannels) > 0 {
+        r.WithUnfurls(runtime.NewUnfurls(unfurlChannels, e.getDuration("SLACK_UNFURL_CACHE_TTL", runtime.DefaultUnfurlCacheTTL)))
+    }
+
+    rateLimiter, err := e.getRateLimiter()
+    if err != nil {
+        return nil, fmt.Errorf("rate limit setup failed: %w", err)
+
+    }
+    if rateLimiter != nil {
+        r.WithRateLimiter(rateLimiter)
+    }
+
+    r.WithListener(countEvent)
+
+    auditSink, err := e.getAuditSink()
+    if err != nil {
+        return nil, fmt.Errorf("audit sink setup failed: %w", err)
+
+    }
+    if auditSink != nil {
+        r.WithAudit(auditSink)
+    }
+
+    // only log what is safe to log, the envs hold tokens and passwords
+    logging.Logger().WithFields(logrus.Fields{
+        "slack_channels": slackChannels,
+        "slack_emojis": slackEmojis,
+        "jira_backends": len(backends),
+        "jira_routes": len(jiraRoutes),
+        "multi_workspace": slackClientID != "",
+        "mattermost_channels": mattermostChannels,
+        "unfurl_channels": unfurlChannels,
+    }).Info("starting slack-jira-integration")
+
+	router := mux.NewRouter()
+
+    if slackClientID != "" {
+        tokenStore := slack.NewMemoryTokenStore()
+        if slackTokenStoreFile != "" {
+            tokenStore, err = slack.NewFileTokenStore(slackTokenStoreFile)
+            if err != nil {
+                return nil, fmt.Errorf("slack token store setup failed: %w", err)
+
+            }
+        }
+
+        workspaces := slack.NewWorkspaces(tokenStore, slackSigningSecret, slackEmojis, slackChannels)
+        workspaces.UserGroupCacheTTL = e.getDuration("ACCESS_GROUP_CACHE_TTL", slack.DefaultUserGroupCacheTTL)
+        workspaces.NewClient = func(token string) slack.Slacker {
+            return slack.NewClient(token, slackOptions...)
+        }
+        r.WithWorkspaces(workspaces)
+
+        // the install flow is driven by a browser so it is not signed by Slack
+        oauthEnv := slack.NewOAuthEnv(slackClientID, slackClientSecret, slackRedirectURL, slackScopes, tokenStore)
+        router.HandleFunc("/slack/install", oauthEnv.InstallHandler)
+        router.HandleFunc("/slack/oauth/callback", oauthEnv.OAuthCallbackHandler)
+    }
+
+    config := e.getServerConfig()
+    adminConfig := config
+    adminConfig.Addr = e.getEnv("ADMIN_ADDR")
+    if adminConfig.Addr == "" {
+        adminConfig.Addr = ":8081"
+    }
+    adminConfig.TLSCertFile, adminConfig.TLSKeyFile = "", ""
+
+    // previous secrets stay valid while a rotated secret rolls out
+    verifierConfig := slack.NewVerifierConfig(append([]string{slackSigningSecret}, strings.Split(e.getEnv("SLACK_PREVIOUS_SIGNING_SECRETS"), ",")...)...)
+    verifierConfig.MaxBodyBytes = config.MaxBodyBytes
+    verifierConfig.MaxTimestampSkew = e.getDuration("SLACK_MAX_TIMESTAMP_SKEW", slack.DefaultMaxTimestampSkew)
+
+    // outgoing webhooks carry their own token instead of a Slack signature
+    var connections []func(context.Context) error
+    if mattermostEnv != nil {
+        if mattermostEnv.WebhookToken != "" {
+            router.HandleFunc("/mattermost/webhook", mattermostEnv.WebhookHandler(r)).Methods("POST")
+        }
+
+        connections = append(connections, func(ctx context.Context) error {
+            return mattermostEnv.Listen(ctx, r)
+        })
+    }
+
+	signed := router.NewRoute().Subrouter()
+	signed.Use(slack.ValidateSlackRequest(verifierConfig))
+	signed.HandleFunc("/slack/events", r.SlackEventsHandler)
+	signed.HandleFunc("/slack/commands", r.SlackCommandsHandler).Methods("POST")
+	signed.HandleFunc("/slack/interactions", r.SlackInteractionsHandler).Methods("POST")
+
+    // health endpoints live on their own admin port so probes bypass the
+    // Slack signature validation and are never exposed through the ingress
+    checker := health.NewChecker(e.getDuration("HEALTH_CACHE_TTL", 30 * time.Second), 5 * time.Second)
+    if slackEnv != nil {
+        checker.Add("slack", slackEnv.AuthTest)
+    }
+    if mattermostEnv != nil {
+        checker.Add("mattermost", mattermostEnv.Ping)
+    }
+    for name, backend := range backends {
+        checker.Add(backendKind(backend) + ":" + name, backend.Ping)
+    }
+
+    adminRouter := mux.NewRouter()
+    adminRouter.HandleFunc("/healthz", checker.LivenessHandler)
+    adminRouter.HandleFunc("/readyz", checker.ReadinessHandler)
+    // scraped from inside the cluster like the probes
+    adminRouter.Handle("/metrics", metrics.Handler())
+
+    // a stub Jira serves its browse pages itself, on the admin port as they
+    // are opened in a browser without any authentication
+    for name, backend := range backends {
+        jiraEnv, ok := backend.(*jira.JiraEnv)
+        if !ok {
+            continue
+        }
+
+        stub, ok := jiraEnv.JiraClient.(*jira.StubClient)
+        if !ok {
+            continue
+        }
+
+        stubUrl, err := url.Parse(jiraEnv.JiraUrl)
+        if err != nil {
+            return nil, fmt.Errorf("jira backend '%s' stub url err: %w", name, err)
+
+        }
+
+        prefix := strings.TrimSuffix(stubUrl.Path, "/")
+        if prefix == "" {
+            return nil, fmt.Errorf("jira backend '%s' stub url needs a path e.g. http://localhost:8081/jira/", name)
+
+        }
+        adminRouter.PathPrefix(prefix + "/").Handler(http.StripPrefix(prefix, stub))
+    }
+
+    // the admin port is reachable from inside the cluster, searching the
+    // audit log takes AUDIT_TOKEN
+    if auditToken := e.getEnv("AUDIT_TOKEN"); auditSink != nil && auditToken != "" {
+        adminRouter.HandleFunc("/audit", audit.Handler(auditSink, auditToken)).Methods("GET")
+    }
+
+
+    return &app{
+        Router: router,
+        AdminRouter: adminRouter,
+        Config: config,
+        AdminConfig: adminConfig,
+        SlackBotToken: slackBotToken,
+        SocketMode: slack.SocketModeHandlers{
+            EventsAPI: r.HandleEventsAPIEvent,
+            Interaction: r.HandleInteraction,
+            SlashCommand: r.HandleSlashCommand,
+        },
+        Connections: connections,
+        Wait: r.Wait,
+        Drain: r.Drain,
+    }, nil
+
+}
+
+// backendKind, what kind of ticket backend labels its health check
+func backendKind(backend runtime.TicketBackend) string {
+    if _, ok := backend.(*github.GitHubEnv); ok {
+        return "github"
+    }
+
+    return "jira"
+
+}
+
+// countEvent, count every event the runtime publishes by type and frontend
+func countEvent(ctx context.Context, event chat.Event) {
+    metrics.ChatEvents.WithLabelValues(event.EventType(), event.EventFrontend()).Inc()
+
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "net/url"
+    "os"
+
+    "slack-jira-integration/audit"
+)
+
+// getAuditSink, the sink selected by AUDIT_SINK (stdout, file or sql), nil
+// when auditing is disabled
+func (e environment) getAuditSink() (audit.Sink, error) {
+    kind := e.getEnv("AUDIT_SINK")
+    if kind == "" || kind == "none" {
+        return nil, nil
+    }
+
+    driver := e.getEnv("AUDIT_SQL_DRIVER")
+    if driver == "" {
+        driver = "postgres"
+    }
+
+    return audit.NewSink(kind, e.getEnv("AUDIT_TARGET"), driver)
+
+}
+
+// auditCommand, `slack-jira-integration audit [flags]` searches the configured
+// audit sink and prints the matching records as JSON lines
+func auditCommand(e environment, args []string) int {
+    flags := flag.NewFlagSet("audit", flag.ContinueOnError)
+    user := flags.String("user", "", "reacting Slack user ID")
+    channel := flags.String("channel", "", "Slack channel ID")
+    issue := flags.String("issue", "", "created Jira issue key")
+    since := flags.String("since", "", "earliest time, RFC 3339")
+    until := flags.String("until", "", "latest time (exclusive), RFC 3339")
+    limit := flags.String("limit", "", "maximum number of records")
+    if err := flags.Parse(args); err != nil {
+        return 2
+    }
+
+    query, err := audit.ParseQuery(url.Values{
+        "user": {*user},
+        "channel": {*channel},
+        "issue": {*issue},
+        "since": {*since},
+        "until": {*until},
+        "limit": {*limit},
+    })
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 2
+    }
+
+    sink, err := e.getAuditSink()
+    if err == nil && sink == nil {
+        err = fmt.Errorf("AUDIT_SINK is not configured")
+    }
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+
+    records, err := sink.Search(context.Background(), query)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+
+    encoder := json.NewEncoder(os.Stdout)
+    for _, record := range records {
+        encoder.Encode(record)
+    }
+
+    return 0
+
+}
@@ -0,0 +1,528 @@
+package main
+
+import (
+    "context"
+    "io/ioutil"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "net/url"
+    "strings"
+    "testing"
+    "time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+    "slack-jira-integration/metrics"
+    "slack-jira-integration/slack"
+    "slack-jira-integration/testing/fakes"
+)
+
+const e2eSigningSecret = "e2e-signing-secret"
+
+// e2e, the application wired from the environment against fake Slack and
+// Jira servers, serving its public router
+type e2e struct {
+    app *app
+    slack *fakes.Slack
+    jira *fakes.Jira
+    server *httptest.Server
+}
+
+func newE2E(t *testing.T, env map[string]string) *e2e {
+    slackAPI := fakes.NewSlack(t)
+    slackAPI.AddChannel("C0ALERTS", "alerts")
+    githubAPI := fakes.NewGitHub(t, "acme/ops")
+    githubAPI.Token = "ghp-e2e"
+    jiraAPI := fakes.NewJira(t, "OPS")
+
+    defaults := map[string]string{
+        "SLACK_SIGNING_SECRET": e2eSigningSecret,
+        "SLACK_BOT_TOKEN": "xoxb-e2e",
+        "SLACK_API_URL": slackAPI.URL,
+        "SLACK_CHANNELS": "alerts",
+        "SLACK_EMOJI_ALERTS": "ticket",
+        "JIRA_URL": jiraAPI.URL + "/",
+        "JIRA_AUTH_TYPE": "pat",
+        "JIRA_TOKEN": "e2e-token",
+        "JIRA_PROJECT": "OPS",
+        "JIRA_SUMMARY": "Escalated from Slack",
+        "JIRA_ISSUE_TYPE": "Task",
+    }
+    for key, value := range env {
+        defaults[key] = value
+    }
+    for key, value := range defaults {
+        t.Setenv(key, value)
+    }
+
+    a, err := environment{}.newApp()
+    require.NoError(t, err)
+
+    server := httptest.NewServer(a.Router)
+    t.Cleanup(server.Close)
+
+    return &e2e{app: a, slack: slackAPI, jira: jiraAPI, server: server}
+
+}
+
+// send, post the signed envelope to the events endpoint and wait until the
+// event it acked has been handled
+func (e *e2e) send(t *testing.T, body []byte) *http.Response {
+    req, err := fakes.NewSignedRequest(e.server.URL + "/slack/events", e2eSigningSecret, body)
+    require.NoError(t, err)
+
+    resp, err := http.DefaultClient.Do(req)
+    require.NoError(t, err)
+    resp.Body.Close()
+
+    require.NoError(t, e.app.Wait(context.Background()))
+
+    return resp
+
+}
+
+func TestE2EReactionCreatesIssue(t *testing.T) {
+    e := newE2E(t, nil)
+    e.slack.AddMessage("C0ALERTS", "1641160800.000100", "U1", "the checkout page is down")
+    e.slack.AddReply("C0ALERTS", "1641160800.000100", "U2", "confirmed from eu-west")
+
+    resp := e.send(t, fakes.ReactionAdded(e.slack.TeamID, "U1", "C0ALERTS", "1641160800.000100", "ticket"))
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    issues := e.jira.Issues()
+    require.Len(t, issues, 1)
+    assert.Equal(t, "OPS-1", issues[0].Key)
+    assert.Equal(t, "Escalated from Slack", issues[0].Fields.Summary)
+    assert.Equal(t, "fake-account-id", issues[0].Fields.Reporter.AccountID)
+    assert.Equal(t, "the checkout page is down", issues[0].Fields.Description)
+
+    posted := e.slack.Posted()
+    require.Len(t, posted, 1)
+    assert.Equal(t, "C0ALERTS", posted[0].Channel)
+    assert.Equal(t, "1641160800.000100", posted[0].ThreadTimestamp)
+    assert.Equal(t, e.jira.URL + "/browse/OPS-1", posted[0].Text)
+    assert.Len(t, posted[0].Blocks.BlockSet, 3)
+
+    // the issue links back to the thread
+    links := e.jira.RemoteLinks("OPS-1")
+    require.Len(t, links, 1)
+    assert.Equal(t, "https://fake.slack.com/archives/C0ALERTS/p1641160800000100", links[0].Object.URL)
+    assert.Equal(t, "#alerts", links[0].Object.Title)
+    assert.Equal(t, "Slack", links[0].Object.Icon.Title)
+
+}
+
+func TestE2ERemoteLinkFailureStillPosts(t *testing.T) {
+    e := newE2E(t, nil)
+    e.slack.AddMessage("C0ALERTS", "1641160800.000100", "U1", "the checkout page is down")
+    e.jira.Fail("addRemoteLink", http.StatusForbidden)
+
+    e.send(t, fakes.ReactionAdded(e.slack.TeamID, "U1", "C0ALERTS", "1641160800.000100", "ticket"))
+
+    assert.Len(t, e.jira.Issues(), 1)
+    assert.Empty(t, e.jira.RemoteLinks("OPS-1"))
+    require.Len(t, e.slack.Posted(), 1)
+
+}
+
+func TestE2EPaginatedThread(t *testing.T) {
+    e := newE2E(t, nil)
+    e.slack.PageSize = 2
+    e.slack.AddMessage("C0ALERTS", "1641160800.000100", "U1", "first")
+    for _, text := range []string{"second", "third", "fourth", "fifth"} {
+        e.slack.AddReply("C0ALERTS", "1641160800.000100", "U2", text)
+    }
+
+    e.send(t, fakes.ReactionAdded(e.slack.TeamID, "U1", "C0ALERTS", "1641160800.000100", "ticket"))
+
+    issues := e.jira.Issues()
+    require.Len(t, issues, 1)
+    assert.Equal(t, "first", issues[0].Fields.Description)
+    assert.Equal(t, 3, e.slack.Calls("conversations.replies"))
+
+}
+
+func TestE2EIgnoresOtherEmoji(t *testing.T) {
+    e := newE2E(t, nil)
+    e.slack.AddMessage("C0ALERTS", "1641160800.000100", "U1", "the checkout page is down")
+
+    resp := e.send(t, fakes.ReactionAdded(e.slack.TeamID, "U1", "C0ALERTS", "1641160800.000100", "eyes"))
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    assert.Empty(t, e.jira.Issues())
+    assert.Empty(t, e.slack.Posted())
+
+}
+
+func TestE2ERejectsUnsignedRequests(t *testing.T) {
+    e := newE2E(t, nil)
+    e.slack.AddMessage("C0ALERTS", "1641160800.000100", "U1", "the checkout page is down")
+
+    body := fakes.ReactionAdded(e.slack.TeamID, "U1", "C0ALERTS", "1641160800.000100", "ticket")
+    req, err := http.NewRequest(http.MethodPost, e.server.URL + "/slack/events", strings.NewReader(string(body)))
+    require.NoError(t, err)
+    require.NoError(t, fakes.SignRequest(req, "wrong-secret", time.Now()))
+
+    resp, err := http.DefaultClient.Do(req)
+    require.NoError(t, err)
+    resp.Body.Close()
+
+    assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+    assert.Empty(t, e.jira.Issues())
+
+}
+
+func TestE2EJiraErrorIsNotPosted(t *testing.T) {
+    e := newE2E(t, nil)
+    e.slack.AddMessage("C0ALERTS", "1641160800.000100", "U1", "the checkout page is down")
+    e.jira.Fail("createIssue", http.StatusBadRequest)
+
+    e.send(t, fakes.ReactionAdded(e.slack.TeamID, "U1", "C0ALERTS", "1641160800.000100", "ticket"))
+
+    assert.Equal(t, 1, e.jira.Calls("createIssue"))
+    assert.Empty(t, e.jira.Issues())
+    assert.Empty(t, e.slack.Posted())
+
+}
+
+func TestE2EDeniedUserIsToldWhy(t *testing.T) {
+    e := newE2E(t, map[string]string{"ACCESS_DENY_GUESTS": "true"})
+    e.slack.AddUser("UGUEST", true)
+    e.slack.AddMessage("C0ALERTS", "1641160800.000100", "U1", "the checkout page is down")
+
+    e.send(t, fakes.ReactionAdded(e.slack.TeamID, "UGUEST", "C0ALERTS", "1641160800.000100", "ticket"))
+
+    assert.Empty(t, e.jira.Issues())
+    ephemeral := e.slack.Ephemeral()
+    require.Len(t, ephemeral, 1)
+    assert.Equal(t, "UGUEST", ephemeral[0].User)
+    assert.Contains(t, ephemeral[0].Text, "guests cannot create Jira issues")
+
+}
+
+func TestE2EUndoDeletesIssue(t *testing.T) {
+    e := newE2E(t, map[string]string{"UNDO_GRACE_PERIOD": "1m", "UNDO_ACTION": "delete"})
+    e.slack.AddMessage("C0ALERTS", "1641160800.000100", "U1", "the checkout page is down")
+
+    e.send(t, fakes.ReactionAdded(e.slack.TeamID, "U1", "C0ALERTS", "1641160800.000100", "ticket"))
+    require.Len(t, e.jira.Issues(), 1)
+
+    e.send(t, fakes.ReactionRemoved(e.slack.TeamID, "U1", "C0ALERTS", "1641160800.000100", "ticket"))
+
+    assert.Empty(t, e.jira.Issues())
+    assert.Equal(t, []string{"OPS-1"}, e.jira.Deleted())
+    require.Len(t, e.slack.Updated(), 1)
+
+}
+
+func TestE2ESlashCommandLinksThread(t *testing.T) {
+    e := newE2E(t, nil)
+    key := e.jira.AddIssue("OPS", "checkout latency")
+    e.slack.AddMessage("C0ALERTS", "1641160800.000100", "U1", "the checkout page is down")
+    e.slack.AddMember("C0ALERTS", "U1")
+
+    form := fakes.SlashCommand(e.slack.TeamID, "U1", "C0ALERTS", "/jira", "link " + key + " <https://acme.slack.com/archives/C0ALERTS/p1641160800000100>", e.slack.ResponseURL)
+    req, err := fakes.NewSignedFormRequest(e.server.URL + "/slack/commands", e2eSigningSecret, form)
+    require.NoError(t, err)
+
+    resp, err := http.DefaultClient.Do(req)
+    require.NoError(t, err)
+    defer resp.Body.Close()
+
+    reply, err := ioutil.ReadAll(resp.Body)
+    require.NoError(t, err)
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    assert.Contains(t, string(reply), "Linking the thread to " + key)
+
+    // the command is run once it was answered
+    require.NoError(t, e.app.Wait(context.Background()))
+    responses := e.slack.Responses()
+    require.Len(t, responses, 1)
+    assert.Equal(t, "Linked the thread to " + key + ".", responses[0].Text)
+
+    assert.Len(t, e.jira.Issues(), 1)
+    links := e.jira.RemoteLinks(key)
+    require.Len(t, links, 1)
+    assert.Equal(t, "https://fake.slack.com/archives/C0ALERTS/p1641160800000100", links[0].Object.URL)
+
+    posted := e.slack.Posted()
+    require.Len(t, posted, 1)
+    assert.Len(t, posted[0].Blocks.BlockSet, 3)
+
+}
+
+func TestE2ECardButtonAssigns(t *testing.T) {
+    e := newE2E(t, nil)
+    e.slack.SetUserProfile("U1", "Ada Lovelace", "ada@example.com")
+    e.jira.AddUser("ada-account", "Ada Lovelace", "ada@example.com")
+    e.slack.AddMessage("C0ALERTS", "1641160800.000100", "U1", "the checkout page is down")
+
+    e.send(t, fakes.ReactionAdded(e.slack.TeamID, "U1", "C0ALERTS", "1641160800.000100", "ticket"))
+    posted := e.slack.Posted()
+    require.Len(t, posted, 1)
+    key := e.jira.Issues()[0].Key
+
+    form := fakes.BlockAction(e.slack.TeamID, "U1", "C0ALERTS", posted[0].Timestamp, slack.CardBlockID, slack.ActionAssignToMe, key)
+    req, err := fakes.NewSignedFormRequest(e.server.URL + "/slack/interactions", e2eSigningSecret, form)
+    require.NoError(t, err)
+
+    resp, err := http.DefaultClient.Do(req)
+    require.NoError(t, err)
+    resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    // the click is answered before the issue is assigned
+    require.NoError(t, e.app.Wait(context.Background()))
+    assert.Equal(t, "ada-account", e.jira.Issue(key).Fields.Assignee.AccountID)
+    updated := e.slack.Updated()
+    require.Len(t, updated, 1)
+    assert.Equal(t, posted[0].Timestamp, updated[0].Timestamp)
+    assert.Len(t, updated[0].Blocks.BlockSet, 3)
+
+}
+
+func TestE2ELinkUnfurl(t *testing.T) {
+    e := newE2E(t, map[string]string{"SLACK_UNFURL_CHANNELS": "alerts"})
+    key := e.jira.AddIssue("OPS", "checkout latency")
+    link := e.jira.URL + "/browse/" + key
+
+    resp := e.send(t, fakes.LinkShared(e.slack.TeamID, "U1", "C0ALERTS", "1641160800.000100", link))
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    unfurled := e.slack.Unfurled()
+    require.Len(t, unfurled, 1)
+    assert.Equal(t, "1641160800.000100", unfurled[0].Timestamp)
+    assert.Contains(t, unfurled[0].Unfurls, link)
+
+}
+
+func TestE2EPastedKeyUnfurl(t *testing.T) {
+    e := newE2E(t, map[string]string{"SLACK_UNFURL_CHANNELS": "alerts"})
+    key := e.jira.AddIssue("OPS", "checkout latency")
+
+    resp := e.send(t, fakes.Message(e.slack.TeamID, "U1", "C0ALERTS", "1641160800.000100", "is this " + key + " again?"))
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    posted := e.slack.Posted()
+    require.Len(t, posted, 1)
+    assert.Equal(t, "1641160800.000100", posted[0].ThreadTimestamp)
+    assert.Equal(t, e.jira.URL + "/browse/" + key, posted[0].Text)
+
+}
+
+func TestE2EMentionedIssueIsLinked(t *testing.T) {
+    e := newE2E(t, nil)
+    key := e.jira.AddIssue("OPS", "checkout latency")
+    e.slack.AddMessage("C0ALERTS", "1641160800.000100", "U1", "the checkout page is down")
+    e.slack.AddReply("C0ALERTS", "1641160800.000100", "U2", "looks like " + key + " again")
+
+    e.send(t, fakes.ReactionAdded(e.slack.TeamID, "U1", "C0ALERTS", "1641160800.000100", "ticket"))
+
+    assert.Equal(t, 0, e.jira.Calls("createIssue"))
+    assert.Len(t, e.jira.RemoteLinks(key), 1)
+    require.Len(t, e.slack.Posted(), 1)
+
+}
+
+func TestE2EJiraStub(t *testing.T) {
+    e := newE2E(t, map[string]string{
+        "JIRA_TYPE": "stub",
+        "JIRA_URL": "http://localhost:8081/jira/",
+        "JIRA_STUB_FILE": filepath.Join(t.TempDir(), "jira-stub.json"),
+    })
+    e.slack.AddMessage("C0ALERTS", "1641160800.000100", "U1", "the checkout page is down")
+
+    e.send(t, fakes.ReactionAdded(e.slack.TeamID, "U1", "C0ALERTS", "1641160800.000100", "ticket"))
+
+    assert.Equal(t, 0, e.jira.Calls("createIssue"))
+    posted := e.slack.Posted()
+    require.Len(t, posted, 1)
+    assert.Equal(t, "http://localhost:8081/jira/browse/OPS-1", posted[0].Text)
+
+    // the browse pages are not public
+    resp, err := http.Get(e.server.URL + "/jira/browse/OPS-1")
+    require.NoError(t, err)
+    resp.Body.Close()
+    assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    admin := httptest.NewServer(e.app.AdminRouter)
+    defer admin.Close()
+
+    resp, err = http.Get(admin.URL + "/jira/browse/OPS-1")
+    require.NoError(t, err)
+    defer resp.Body.Close()
+
+    page, err := ioutil.ReadAll(resp.Body)
+    require.NoError(t, err)
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    assert.Contains(t, string(page), "the checkout page is down")
+
+}
+
+func TestE2EGitHubBackend(t *testing.T) {
+    githubAPI := fakes.NewGitHub(t, "acme/ops")
+    githubAPI.Token = "ghp-e2e"
+
+    // backend names are trimmed like every other list
+    e := newE2E(t, map[string]string{
+        "JIRA_BACKENDS": " gh ",
+        "JIRA_GH_TYPE": "github",
+        "JIRA_GH_URL": githubAPI.URL,
+        "JIRA_GH_WEB_URL": "https://github.com",
+        "JIRA_GH_TOKEN": "ghp-e2e",
+        "JIRA_GH_PROJECT": "acme/ops",
+        "JIRA_GH_SUMMARY": "Escalated from Slack",
+        "JIRA_GH_LABELS": "slack,escalation",
+        "UNDO_GRACE_PERIOD": "1m",
+        "UNDO_ACTION": "transition",
+    })
+    e.slack.AddMessage("C0ALERTS", "1641160800.000100", "U1", "the checkout page is down")
+
+    e.send(t, fakes.ReactionAdded(e.slack.TeamID, "U1", "C0ALERTS", "1641160800.000100", "ticket"))
+
+    issues := githubAPI.Issues("acme/ops")
+    require.Len(t, issues, 1)
+    assert.Equal(t, "the checkout page is down", issues[0].Body)
+    assert.Equal(t, []string{"slack", "escalation"}, issues[0].Labels)
+
+    posted := e.slack.Posted()
+    require.Len(t, posted, 1)
+    assert.Equal(t, "https://github.com/acme/ops/issues/1", posted[0].Text)
+
+    e.send(t, fakes.ReactionRemoved(e.slack.TeamID, "U1", "C0ALERTS", "1641160800.000100", "ticket"))
+
+    issues = githubAPI.Issues("acme/ops")
+    assert.Equal(t, "closed", issues[0].State)
+    assert.Equal(t, "not_planned", issues[0].StateReason)
+
+    // the backend's health check is labelled by its kind
+    rr := httptest.NewRecorder()
+    e.app.AdminRouter.ServeHTTP(rr, httptest.NewRequest("GET", "/readyz", nil))
+    assert.Contains(t, rr.Body.String(), `"github:gh"`)
+    assert.NotContains(t, rr.Body.String(), `"jira:gh"`)
+
+}
+
+// metrics are scraped on the admin port, the public listener does not serve them
+func TestE2EMetricsOnAdminPort(t *testing.T) {
+    e := newE2E(t, nil)
+
+    rr := httptest.NewRecorder()
+    e.app.AdminRouter.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+    assert.Equal(t, http.StatusOK, rr.Code)
+
+    rr = httptest.NewRecorder()
+    e.app.Router.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+    assert.NotEqual(t, http.StatusOK, rr.Code)
+
+}
+
+func TestE2EGitHubBackendCannotUndoByDelete(t *testing.T) {
+    slackAPI := fakes.NewSlack(t)
+    slackAPI.AddChannel("C0ALERTS", "alerts")
+    githubAPI := fakes.NewGitHub(t, "acme/ops")
+    githubAPI.Token = "ghp-e2e"
+
+    t.Setenv("SLACK_SIGNING_SECRET", e2eSigningSecret)
+    t.Setenv("SLACK_BOT_TOKEN", "xoxb-e2e")
+    t.Setenv("SLACK_API_URL", slackAPI.URL)
+    t.Setenv("SLACK_CHANNELS", "alerts")
+    t.Setenv("JIRA_BACKENDS", "gh")
+    t.Setenv("JIRA_GH_TYPE", "github")
+    t.Setenv("JIRA_GH_URL", githubAPI.URL)
+    t.Setenv("JIRA_GH_TOKEN", "ghp-e2e")
+    t.Setenv("JIRA_GH_PROJECT", "acme/ops")
+    t.Setenv("UNDO_GRACE_PERIOD", "1m")
+    t.Setenv("UNDO_ACTION", "delete")
+
+    _, err := environment{}.newApp()
+    assert.ErrorContains(t, err, "ticket backend 'gh' cannot undo escalations by delete")
+
+}
+
+// newMattermostE2E, the application with a Mattermost frontend for the
+// incidents channel of the fake's acme team, its connections are running
+func newMattermostE2E(t *testing.T, env map[string]string) (*e2e, *fakes.Mattermost) {
+    mattermostAPI := fakes.NewMattermost(t, "acme")
+    mattermostAPI.Token = "mm-token"
+    mattermostAPI.AddChannel("incidentsid", "incidents")
+
+    defaults := map[string]string{
+        "MATTERMOST_URL": mattermostAPI.URL,
+        "MATTERMOST_TOKEN": "mm-token",
+        "MATTERMOST_TEAM": "acme",
+        "MATTERMOST_CHANNELS": "incidents",
+        "MATTERMOST_EMOJI_INCIDENTS": "ticket",
+    }
+    for key, value := range env {
+        defaults[key] = value
+    }
+
+    e := newE2E(t, defaults)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    t.Cleanup(cancel)
+    for _, listen := range e.app.Connections {
+        go listen(ctx)
+    }
+
+    return e, mattermostAPI
+
+}
+
+func TestE2EMattermostReactionCreatesIssue(t *testing.T) {
+    e, mattermostAPI := newMattermostE2E(t, nil)
+    require.Eventually(t, func() bool { return mattermostAPI.Connected() == 1 }, 5 * time.Second, 10 * time.Millisecond)
+
+    root := mattermostAPI.AddPost("incidentsid", "U1", "the checkout page is down")
+    mattermostAPI.AddReply(root, "U2", "confirmed from eu-west")
+
+    mattermostAPI.React("U1", root, "eyes")
+    mattermostAPI.React("U1", root, "ticket")
+
+    require.Eventually(t, func() bool { return len(mattermostAPI.Posted()) == 1 }, 5 * time.Second, 10 * time.Millisecond)
+
+    issues := e.jira.Issues()
+    require.Len(t, issues, 1)
+    assert.Equal(t, "the checkout page is down", issues[0].Fields.Description)
+
+    posted := mattermostAPI.Posted()[0]
+    assert.Equal(t, root, posted.RootID)
+    assert.Equal(t, e.jira.URL + "/browse/OPS-1", posted.Message)
+    assert.Empty(t, e.slack.Posted())
+
+}
+
+func TestE2EMattermostWebhook(t *testing.T) {
+    e, mattermostAPI := newMattermostE2E(t, map[string]string{"MATTERMOST_WEBHOOK_TOKEN": "hook-token"})
+
+    escalated := metrics.ChatEvents.WithLabelValues("thread_escalated", "mattermost")
+    before := testutil.ToFloat64(escalated)
+
+    root := mattermostAPI.AddPost("incidentsid", "U1", "the checkout page is down")
+    trigger := mattermostAPI.AddReply(root, "U2", ":ticket: please")
+
+    form := url.Values{
+        "token": {"hook-token"},
+        "channel_id": {"incidentsid"},
+        "user_id": {"U2"},
+        "post_id": {trigger},
+        "trigger_word": {":ticket:"},
+    }
+    resp, err := http.PostForm(e.server.URL + "/mattermost/webhook", form)
+    require.NoError(t, err)
+    resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    // the webhook is answered before the thread is escalated
+    require.NoError(t, e.app.Wait(context.Background()))
+    require.Len(t, e.jira.Issues(), 1)
+    posted := mattermostAPI.Posted()
+    require.Len(t, posted, 1)
+    assert.Equal(t, root, posted[0].RootID)
+    assert.Equal(t, before + 1, testutil.ToFloat64(escalated))
+
+}
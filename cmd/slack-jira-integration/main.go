@@ -3,80 +3,433 @@
 package main
 
 import (
+    "context"
 	"fmt"
+    "os"
+    "os/signal"
+    "strconv"
+    "syscall"
+    "time"
     "strings"
-	"net/http"
 
 	"github.com/spf13/viper"
 
-	"github.com/gorilla/mux"
-
     runtime "slack-jira-integration"
+    "slack-jira-integration/logging"
+    "slack-jira-integration/ratelimit"
+    "slack-jira-integration/server"
+    "slack-jira-integration/tracing"
     "slack-jira-integration/slack"
+    "slack-jira-integration/github"
     "slack-jira-integration/jira"
 
 )
 
-func getEmojisByChannel(prefix string, channels []string) map[string]string {
+// environment is where the configuration is read from, the process
+// environment unless overridden, e.g. replay points Slack and Jira at fakes
+// without touching the environment
+type environment struct {
+    overrides map[string]string
+}
+
+// commands, subcommands run instead of serving, replay registers itself when
+// built with -tags replay as it embeds the fake Slack and Jira servers, make
+// build and the image do
+var commands = map[string]func(environment, []string) int{
+    "audit": auditCommand,
+}
+
+func (e environment) getEmojisByChannel(prefix string, channels []string) map[string]string {
     emojis := make(map[string]string)
     for _, channel := range channels {
-        emojisEnvVar := fmt.Sprintf("%s_%s", prefix, channel)
-        viper.BindEnv(emojisEnvVar)
-        emojiFromEnv := viper.GetString(emojisEnvVar)
+        emojiFromEnv := e.getEnv(fmt.Sprintf("%s_%s", prefix, channel))
         emojis[channel] = emojiFromEnv 
     }
 
     return emojis
 }
 
+// getEnv, bind and read a single environment variable, an override wins
+func (e environment) getEnv(envVar string) string {
+    if value, exists := e.overrides[envVar]; exists {
+        return value
+    }
+
+    viper.BindEnv(envVar)
+    return viper.GetString(envVar)
+
+}
+
+// getDuration, read a duration such as 30s or 5m from envVar, defaultValue
+// when unset or invalid
+func (e environment) getDuration(envVar string, defaultValue time.Duration) time.Duration {
+    duration, err := time.ParseDuration(e.getEnv(envVar))
+    if err != nil {
+        return defaultValue
+    }
+
+    return duration
+
+}
+
+// getServerConfig, reads the HTTP listener settings, anything unset keeps the
+// server.DefaultConfig value
+func (e environment) getServerConfig() server.Config {
+    config := server.DefaultConfig()
+
+    if addr := e.getEnv("HTTP_ADDR"); addr != "" {
+        config.Addr = addr
+    }
+    config.ReadTimeout = e.getDuration("HTTP_READ_TIMEOUT", config.ReadTimeout)
+    config.WriteTimeout = e.getDuration("HTTP_WRITE_TIMEOUT", config.WriteTimeout)
+    config.IdleTimeout = e.getDuration("HTTP_IDLE_TIMEOUT", config.IdleTimeout)
+    if maxBodyBytes, err := strconv.ParseInt(e.getEnv("HTTP_MAX_BODY_BYTES"), 10, 64); err == nil {
+        config.MaxBodyBytes = maxBodyBytes
+    }
+    config.TLSCertFile = e.getEnv("TLS_CERT_FILE")
+    config.TLSKeyFile = e.getEnv("TLS_KEY_FILE")
+
+    return config
+
+}
+
+// getJiraAuth, reads the Jira authentication settings for the transport
+// selected by <prefix>_AUTH_TYPE, defaults to basic auth with username/password
+func (e environment) getJiraAuth(prefix string, usernameEnvVar string, passwordEnvVar string) jira.AuthConfig {
+    var scopes []string
+    if scopesFromEnv := e.getEnv(prefix + "_OAUTH_SCOPES"); scopesFromEnv != "" {
+        scopes = strings.Split(scopesFromEnv, ",")
+    }
+
+    return jira.AuthConfig{
+        Type: e.getEnv(prefix + "_AUTH_TYPE"),
+        Username: e.getEnv(usernameEnvVar),
+        Password: e.getEnv(passwordEnvVar),
+        Token: e.getEnv(prefix + "_TOKEN"),
+        ClientID: e.getEnv(prefix + "_OAUTH_CLIENT_ID"),
+        ClientSecret: e.getEnv(prefix + "_OAUTH_CLIENT_SECRET"),
+        TokenURL: e.getEnv(prefix + "_OAUTH_TOKEN_URL"),
+        RefreshToken: e.getEnv(prefix + "_OAUTH_REFRESH_TOKEN"),
+        Scopes: scopes,
+        TokenFile: e.getEnv(prefix + "_OAUTH_TOKEN_FILE"),
+        ConsumerKey: e.getEnv(prefix + "_OAUTH1_CONSUMER_KEY"),
+        PrivateKeyFile: e.getEnv(prefix + "_OAUTH1_PRIVATE_KEY_FILE"),
+        AccessToken: e.getEnv(prefix + "_OAUTH1_ACCESS_TOKEN"),
+        AccessSecret: e.getEnv(prefix + "_OAUTH1_ACCESS_SECRET"),
+    }
+
+}
+
+// getJiraEnv, construct the JiraEnv for one Jira site, every setting is read
+// from <prefix>_URL, <prefix>_PROJECT, ... and <prefix>_AUTH_TYPE, ...,
+// <prefix>_TYPE=stub swaps the site for a local stub storing issues in
+// <prefix>_STUB_FILE so the full flow runs offline
+func (e environment) getJiraEnv(prefix string, usernameEnvVar string, passwordEnvVar string) (*jira.JiraEnv, error) {
+    jiraUrl := e.getEnv(prefix + "_URL")
+
+    var jiraClient jira.Jiraer
+    var err error
+    switch jiraType := e.getEnv(prefix + "_TYPE"); jiraType {
+    case "", "jira":
+        jiraClient, err = jira.NewClient(jiraUrl, e.getJiraAuth(prefix, usernameEnvVar, passwordEnvVar))
+
+    case "stub":
+        // the admin server serves the stub's browse pages under the path of the url
+        if jiraUrl == "" {
+            jiraUrl = "http://localhost:8081/jira/"
+        }
+        jiraClient, err = jira.NewStubClient(e.getEnv(prefix + "_STUB_FILE"))
+
+    default:
+        err = fmt.Errorf("unknown %s_TYPE '%s'", prefix, jiraType)
+    }
+
+    if err != nil {
+        return nil, err
+    }
+
+    return jira.NewEnv(
+        jiraClient,
+        jiraUrl,
+        e.getEnv(prefix + "_PROJECT"),
+        e.getEnv(prefix + "_SUMMARY"),
+        e.getEnv(prefix + "_ISSUE_TYPE"))
+
+}
+
+// getGitHubEnv, construct a GitHubEnv opening issues in the owner/repo
+// <prefix>_PROJECT with <prefix>_TOKEN, titled <prefix>_SUMMARY and labelled
+// <prefix>_LABELS, <prefix>_URL is the API root for GitHub Enterprise
+func (e environment) getGitHubEnv(prefix string) (*github.GitHubEnv, error) {
+    return github.NewEnv(
+        github.NewClient(e.getEnv(prefix + "_TOKEN")),
+        e.getEnv(prefix + "_URL"),
+        e.getEnv(prefix + "_WEB_URL"),
+        e.getEnv(prefix + "_PROJECT"),
+        e.getEnv(prefix + "_SUMMARY"),
+        e.getList(prefix + "_LABELS"))
+
+}
+
+// getBackend, construct the ticket backend configured under <prefix>_*,
+// <prefix>_TYPE selects jira (the default), stub or github
+func (e environment) getBackend(prefix string, usernameEnvVar string, passwordEnvVar string) (runtime.TicketBackend, error) {
+    if e.getEnv(prefix + "_TYPE") == "github" {
+        githubEnv, err := e.getGitHubEnv(prefix)
+        if err != nil {
+            return nil, err
+        }
+
+        return githubEnv, nil
+    }
+
+    jiraEnv, err := e.getJiraEnv(prefix, usernameEnvVar, passwordEnvVar)
+    if err != nil {
+        return nil, err
+    }
+
+    return jiraEnv, nil
+
+}
+
+// getList, read a comma separated envVar, nil when unset
+func (e environment) getList(envVar string) []string {
+    return splitList(e.getEnv(envVar))
+
+}
+
+// splitList, the trimmed non-empty values of a comma separated list
+func splitList(list string) []string {
+    var values []string
+    for _, value := range strings.Split(list, ",") {
+        if value = strings.TrimSpace(value); value != "" {
+            values = append(values, value)
+        }
+    }
+
+    return values
+
+}
+
+// getAccessPolicy, read the allow/deny lists under <prefix>_*
+func (e environment) getAccessPolicy(prefix string) *runtime.AccessPolicy {
+    return &runtime.AccessPolicy{
+        AllowUsers: e.getList(prefix + "_ALLOW_USERS"),
+        DenyUsers: e.getList(prefix + "_DENY_USERS"),
+        AllowGroups: e.getList(prefix + "_ALLOW_GROUPS"),
+        DenyGroups: e.getList(prefix + "_DENY_GROUPS"),
+        DenyGuests: e.getEnv(prefix + "_DENY_GUESTS") == "true",
+    }
+
+}
+
+// getAccessPolicies, the default policy under ACCESS_* and every name in
+// ACCESS_POLICIES configured under ACCESS_<NAME>_* for routes to select
+func (e environment) getAccessPolicies() (*runtime.AccessPolicy, map[string]*runtime.AccessPolicy) {
+    policies := make(map[string]*runtime.AccessPolicy)
+    for _, name := range e.getList("ACCESS_POLICIES") {
+        policies[name] = e.getAccessPolicy(fmt.Sprintf("ACCESS_%s", strings.ToUpper(name)))
+    }
+
+    return e.getAccessPolicy("ACCESS"), policies
+
+}
+
+// getRateLimiter, the per user, per channel and global limits on issue
+// creation from RATE_LIMIT_USER, RATE_LIMIT_CHANNEL and RATE_LIMIT_GLOBAL
+// written as N/duration, nil when none is set
+func (e environment) getRateLimiter() (*ratelimit.Limiter, error) {
+    var limits []ratelimit.Limit
+    for _, envVar := range []string{"RATE_LIMIT_USER", "RATE_LIMIT_CHANNEL", "RATE_LIMIT_GLOBAL"} {
+        limit, err := ratelimit.ParseLimit(e.getEnv(envVar))
+        if err != nil {
+            return nil, fmt.Errorf("%s err: %w", envVar, err)
+        }
+        limits = append(limits, limit)
+    }
+
+    if limits[0] == (ratelimit.Limit{}) && limits[1] == (ratelimit.Limit{}) && limits[2] == (ratelimit.Limit{}) {
+        return nil, nil
+    }
+
+    return ratelimit.New(limits[0], limits[1], limits[2], e.getEnv("RATE_LIMIT_OVERFLOW"), e.getDuration("RATE_LIMIT_MAX_WAIT", time.Minute))
+
+}
+
+// getVotes, the distinct reactors needed to escalate, ESCALATION_THRESHOLD
+// for every channel or ESCALATION_THRESHOLD_<channel>, weighted by
+// ESCALATION_WEIGHTS, nil when every threshold is one
+func (e environment) getVotes(channels []string) (*runtime.Votes, error) {
+    threshold := 1
+    if fromEnv := e.getEnv("ESCALATION_THRESHOLD"); fromEnv != "" {
+        parsed, err := strconv.Atoi(fromEnv)
+        if err != nil {
+            return nil, fmt.Errorf("ESCALATION_THRESHOLD err: %w", err)
+        }
+        threshold = parsed
+    }
+
+    enabled := threshold > 1
+    thresholds := make(map[string]int)
+    for channel, fromEnv := range e.getEmojisByChannel("ESCALATION_THRESHOLD", channels) {
+        if fromEnv == "" {
+            continue
+        }
+
+        parsed, err := strconv.Atoi(fromEnv)
+        if err != nil {
+            return nil, fmt.Errorf("ESCALATION_THRESHOLD_%s err: %w", channel, err)
+        }
+        thresholds[channel] = parsed
+        enabled = enabled || parsed > 1
+    }
+
+    if !enabled {
+        return nil, nil
+    }
+
+    weights, err := runtime.ParseWeights(e.getEnv("ESCALATION_WEIGHTS"))
+    if err != nil {
+        return nil, err
+    }
+
+    return runtime.NewVotes(threshold, thresholds, weights, e.getDuration("ESCALATION_VOTE_WINDOW", 24 * time.Hour)), nil
+
+}
+
+// getBackends, construct a ticket backend for every name in JIRA_BACKENDS
+// configured under JIRA_<NAME>_*, the default backend is JIRA_DEFAULT_BACKEND
+// or the first one listed. Without JIRA_BACKENDS the single site configured
+// by JIRA_URL, USER_NAME, PASSWORD, ... is the default backend
+func (e environment) getBackends() (runtime.TicketBackend, map[string]runtime.TicketBackend, error) {
+    backendNames := e.getList("JIRA_BACKENDS")
+
+    if len(backendNames) == 0 {
+        backend, err := e.getBackend("JIRA", "USER_NAME", "PASSWORD")
+        if err != nil {
+            return nil, nil, err
+        }
+
+        return backend, map[string]runtime.TicketBackend{"default": backend}, nil
+
+    }
+
+    backends := make(map[string]runtime.TicketBackend)
+    var names []string
+    for _, name := range backendNames {
+        prefix := fmt.Sprintf("JIRA_%s", strings.ToUpper(name))
+
+        backend, err := e.getBackend(prefix, prefix + "_USER_NAME", prefix + "_PASSWORD")
+        if err != nil {
+            return nil, nil, fmt.Errorf("ticket backend '%s' err: %w", name, err)
+        }
+
+        backends[name] = backend
+        names = append(names, name)
+    }
+
+    defaultBackend := e.getEnv("JIRA_DEFAULT_BACKEND")
+    if defaultBackend == "" {
+        defaultBackend = names[0]
+    }
+
+    backend, exists := backends[defaultBackend]
+    if !exists {
+        return nil, nil, fmt.Errorf("unknown default ticket backend '%s'", defaultBackend)
+    }
+
+    return backend, backends, nil
+
+}
+
 func main() {
-	viper.BindEnv("USER_NAME")
-	viper.BindEnv("PASSWORD")
-	viper.BindEnv("SLACK_SIGNING_SECRET")
-	viper.BindEnv("SLACK_BOT_TOKEN")
-    viper.BindEnv("SLACK_CHANNELS")
-	viper.BindEnv("JIRA_URL")
-    viper.BindEnv("JIRA_PROJECT")
-    viper.BindEnv("JIRA_SUMMARY")
-    viper.BindEnv("JIRA_ISSUE_TYPE")
-
-	username := viper.GetString("USER_NAME")
-	password := viper.GetString("PASSWORD")
-
-	slackSigningSecret := viper.GetString("SLACK_SIGNING_SECRET")
-	slackBotToken := viper.GetString("SLACK_BOT_TOKEN")
-    slackChannels := strings.Split(viper.GetString("SLACK_CHANNELS"),",")
-    slackEmojis := getEmojisByChannel("SLACK_EMOJI", slackChannels)
-
-	jiraUrl := viper.GetString("JIRA_URL")
-	jiraProject := viper.GetString("JIRA_PROJECT")
-	jiraSummary := viper.GetString("JIRA_SUMMARY")
-	jiraIssueType := viper.GetString("JIRA_ISSUE_TYPE")
-
-    slackEnv, err := slack.NewEnv(slack.NewClient(slackBotToken), slackSigningSecret, slackEmojis, slackChannels)
+    e := environment{}
+
+    if len(os.Args) > 1 {
+        if command, exists := commands[os.Args[1]]; exists {
+            os.Exit(command(e, os.Args[2:]))
+        }
+    }
+
+    logging.Configure(e.getEnv("LOG_LEVEL"), e.getEnv("LOG_FORMAT"), e.getEnv("LOG_REDACT") != "false")
+    log := logging.Logger()
+
+    // SIGTERM from kubernetes starts the graceful shutdown
+    ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+    defer stop()
+
+    shutdownTracing, err := tracing.Setup(context.Background(), e.getEnv("OTEL_TRACES_EXPORTER"))
     if err != nil {
-        fmt.Println(fmt.Sprintf("slackEnv err: %+v", err)) 
+        log.WithError(err).Error("tracing setup failed")
         return
 
     }
+    defer shutdownTracing(context.Background())
 
-    jiraEnv, err := jira.NewEnv(jira.NewClient(jiraUrl, username, password), jiraProject, jiraSummary, jiraIssueType)
+    a, err := e.newApp()
     if err != nil {
-        fmt.Println(fmt.Sprintf("jiraEnv err: %+v", err)) 
+        log.WithError(err).Error("setup failed")
         return
 
     }
 
-    r := runtime.New(slackEnv, jiraEnv)
+    // socket mode receives events, interactions and slash commands over an
+    // outbound websocket, the HTTP server keeps serving the install flow
+    if e.getEnv("SLACK_TRANSPORT") == "socket" {
+        socketModeEnv := slack.NewSocketModeEnv(a.SlackBotToken, e.getEnv("SLACK_APP_TOKEN"))
+        go func() {
+            if err := socketModeEnv.Run(ctx, a.SocketMode); err != nil && ctx.Err() == nil {
+                log.WithError(err).Error("socket mode stopped")
+                stop()
+            }
+
+        }()
+    }
+
+    // other chat frontends connect out to their servers the same way
+    for _, listen := range a.Connections {
+        go func(listen func(context.Context) error) {
+            if err := listen(ctx); err != nil && ctx.Err() == nil {
+                log.WithError(err).Error("chat listener stopped")
+                stop()
+            }
+
+        }(listen)
+    }
 
-    fmt.Println(fmt.Sprintf("env: %+v %+v", slackEnv, jiraEnv))
+    shutdownTimeout := e.getDuration("SHUTDOWN_TIMEOUT", 25 * time.Second)
 
-	router := mux.NewRouter()
-	router.Use(slack.ValidateSlackRequest(slackSigningSecret))
-	router.HandleFunc("/slack/events", r.SlackEventsHandler)
-	http.Handle("/", router)
+    // SIGTERM starts one deadline shared by closing the listeners and draining
+    // the escalations, together they stay within terminationGracePeriodSeconds
+    shutdownDeadline := make(chan time.Time, 1)
+    go func() {
+        <-ctx.Done()
+        shutdownDeadline <- time.Now().Add(shutdownTimeout)
+    }()
 
-	http.ListenAndServe(":8000", router)
+    go func() {
+        if err := server.Run(ctx, server.New(a.AdminConfig, a.AdminRouter), a.AdminConfig, shutdownTimeout); err != nil {
+            log.WithError(err).Error("admin server stopped")
+        }
+
+    }()
+
+    if err := server.Run(ctx, server.New(a.Config, a.Router), a.Config, shutdownTimeout); err != nil {
+        log.WithError(err).Error("http server stopped")
+    }
+
+    // a failed listener shuts the rest down too
+    stop()
+
+    // the listener is closed, give escalations already running what is left
+    // of the deadline to finish
+    log.Info("shutting down, draining in-flight escalations")
+    drainCtx, cancel := context.WithDeadline(context.Background(), <-shutdownDeadline)
+    defer cancel()
+
+    if err := a.Drain(drainCtx); err != nil {
+        log.WithError(err).Error("drain incomplete")
+    }
 
 }
 
@@ -0,0 +1,335 @@
+//go:build replay
+// +build replay
+
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "strings"
+
+	"github.com/slack-go/slack/slackevents"
+
+    "slack-jira-integration/testing/fakes"
+)
+
+// replayResult is the outcome of one replayed envelope, printed as a JSON line
+type replayResult struct {
+    Line int `json:"line"`
+    Event string `json:"event"`
+    Status int `json:"status,omitempty"`
+    Error string `json:"error,omitempty"`
+    Actions []replayAction `json:"actions,omitempty"`
+}
+
+// replayAction is a Slack or Jira call the envelope caused, only known when
+// replaying in-process against the fakes
+type replayAction struct {
+    Kind string `json:"kind"`
+    Channel string `json:"channel,omitempty"`
+    User string `json:"user,omitempty"`
+    IssueKey string `json:"issue_key,omitempty"`
+    Text string `json:"text,omitempty"`
+}
+
+// cleanups stands in for testing.T so the fakes can be used outside tests
+type cleanups []func()
+
+func (c *cleanups) Cleanup(cleanup func()) {
+    *c = append(*c, cleanup)
+
+}
+
+func (c *cleanups) run() {
+    for i := len(*c) - 1; i >= 0; i-- {
+        (*c)[i]()
+    }
+
+}
+
+func init() {
+    commands["replay"] = replayCommand
+
+}
+
+// replayCommand, `slack-jira-integration replay [flags] FILE` signs every
+// Slack event envelope in the JSONL FILE and posts it to -url, without -url
+// the runtime configured from the environment is run in-process against fake
+// Slack and Jira servers and the actions each envelope caused are reported
+func replayCommand(e environment, args []string) int {
+    flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+    target := flags.String("url", "", "events URL of a running instance, e.g. http://localhost:8080/slack/events, in-process when empty")
+    secret := flags.String("secret", e.getEnv("SLACK_SIGNING_SECRET"), "signing secret, defaults to SLACK_SIGNING_SECRET")
+    channelIDs := flags.String("channel-ids", "", "name=ID pairs for the configured SLACK_CHANNELS in-process, the ID defaults to the name")
+    if err := flags.Parse(args); err != nil {
+        return 2
+    }
+
+    if flags.NArg() != 1 || *secret == "" {
+        fmt.Fprintln(os.Stderr, "usage: slack-jira-integration replay [-url URL] [-secret SECRET] [-channel-ids name=ID,...] FILE")
+        return 2
+    }
+
+    file, err := os.Open(flags.Arg(0))
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    defer file.Close()
+
+    if *target == "" {
+        err = replayInProcess(e, file, *secret, *channelIDs, os.Stdout)
+    } else {
+        err = replay(file, *target, *secret, os.Stdout, nil)
+    }
+
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+
+    return 0
+
+}
+
+// readEnvelopes, the lines of events, each a Slack event envelope, blank
+// lines are kept empty so results carry the line numbers of the file
+func readEnvelopes(events io.Reader) ([][]byte, error) {
+    var envelopes [][]byte
+
+    scanner := bufio.NewScanner(events)
+    scanner.Buffer(make([]byte, 64 * 1024), 1 << 20)
+    for scanner.Scan() {
+        envelopes = append(envelopes, append([]byte(nil), bytes.TrimSpace(scanner.Bytes())...))
+    }
+
+    return envelopes, scanner.Err()
+
+}
+
+// eventType, the inner event type of a callback or the envelope type
+func eventType(envelope []byte) (string, error) {
+    event, err := slackevents.ParseEvent(json.RawMessage(envelope), slackevents.OptionNoVerifyToken())
+    if err != nil {
+        return "", err
+    }
+
+    if event.Type == slackevents.CallbackEvent {
+        return event.InnerEvent.Type, nil
+    }
+
+    return event.Type, nil
+
+}
+
+// replay, post every envelope signed with secret to target and write one
+// replayResult per envelope to out, observe reports what each one caused
+func replay(events io.Reader, target string, secret string, out io.Writer, observe func() []replayAction) error {
+    envelopes, err := readEnvelopes(events)
+    if err != nil {
+        return fmt.Errorf("read events err: %w", err)
+    }
+
+    encoder := json.NewEncoder(out)
+    for i, envelope := range envelopes {
+        if len(envelope) == 0 {
+            continue
+        }
+
+        result := replayResult{Line: i + 1}
+        result.Event, err = eventType(envelope)
+        if err != nil {
+            result.Error = fmt.Sprintf("invalid envelope: %s", err)
+            encoder.Encode(result)
+            continue
+        }
+
+        req, err := fakes.NewSignedRequest(target, secret, envelope)
+        if err != nil {
+            return err
+        }
+
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            result.Error = err.Error()
+        } else {
+            resp.Body.Close()
+            result.Status = resp.StatusCode
+        }
+
+        if observe != nil {
+            result.Actions = observe()
+        }
+
+        encoder.Encode(result)
+    }
+
+    return nil
+
+}
+
+// replayInProcess, serve the app configured from e with Slack and Jira
+// swapped for fakes seeded with the channels, messages and users the
+// envelopes refer to, then replay the envelopes against it
+func replayInProcess(e environment, events io.Reader, secret string, channelIDs string, out io.Writer) error {
+    envelopes, err := readEnvelopes(events)
+    if err != nil {
+        return fmt.Errorf("read events err: %w", err)
+    }
+
+    var c cleanups
+    defer c.run()
+
+    slackAPI := fakes.NewSlack(&c)
+    jiraAPI := fakes.NewJira(&c)
+
+    ids := make(map[string]string)
+    for _, pair := range splitList(channelIDs) {
+        if parts := strings.SplitN(pair, "=", 2); len(parts) == 2 {
+            ids[parts[0]] = parts[1]
+        }
+    }
+    for _, name := range e.getList("SLACK_CHANNELS") {
+        id, exists := ids[name]
+        if !exists {
+            id = name
+        }
+        slackAPI.AddChannel(id, name)
+    }
+
+    seedFakes(slackAPI, envelopes)
+
+    // never reach the real Slack, Jira or audit sink
+    overrides := map[string]string{
+        "SLACK_SIGNING_SECRET": secret,
+        "SLACK_BOT_TOKEN": "xoxb-replay",
+        "SLACK_CLIENT_ID": "",
+        "SLACK_API_URL": slackAPI.URL,
+        "AUDIT_SINK": "none",
+    }
+
+    prefixes := []string{"JIRA"}
+    for _, name := range e.getList("JIRA_BACKENDS") {
+        prefixes = append(prefixes, fmt.Sprintf("JIRA_%s", strings.ToUpper(name)))
+    }
+    for _, prefix := range prefixes {
+        overrides[prefix + "_URL"] = jiraAPI.URL + "/"
+        overrides[prefix + "_AUTH_TYPE"] = "pat"
+        overrides[prefix + "_TOKEN"] = "replay"
+        if project := e.getEnv(prefix + "_PROJECT"); project != "" {
+            jiraAPI.AddProject(project)
+        }
+    }
+
+    a, err := environment{overrides: overrides}.newApp()
+    if err != nil {
+        return err
+    }
+
+    server := httptest.NewServer(a.Router)
+    defer server.Close()
+
+    // events are handled after they are acked, observe once they are done
+    observer := newObserver(slackAPI, jiraAPI)
+    observe := func() []replayAction {
+        a.Wait(context.Background())
+        return observer()
+    }
+
+    envelopeBytes := bytes.Join(envelopes, []byte("\n"))
+    return replay(bytes.NewReader(envelopeBytes), server.URL + "/slack/events", secret, out, observe)
+
+}
+
+// seedFakes, add a placeholder message for every reacted to item and a
+// member for every reacting user so the escalation can run
+func seedFakes(slackAPI *fakes.Slack, envelopes [][]byte) {
+    threads := make(map[string]bool)
+    users := make(map[string]bool)
+
+    for _, envelope := range envelopes {
+        event, err := slackevents.ParseEvent(json.RawMessage(envelope), slackevents.OptionNoVerifyToken())
+        if err != nil {
+            continue
+        }
+
+        var user, channel, timestamp string
+        switch ev := event.InnerEvent.Data.(type) {
+        case *slackevents.ReactionAddedEvent:
+            user, channel, timestamp = ev.User, ev.Item.Channel, ev.Item.Timestamp
+        case *slackevents.ReactionRemovedEvent:
+            user, channel, timestamp = ev.User, ev.Item.Channel, ev.Item.Timestamp
+        default:
+            continue
+        }
+
+        if key := channel + "/" + timestamp; !threads[key] {
+            threads[key] = true
+            slackAPI.AddMessage(channel, timestamp, "UREPLAY", fmt.Sprintf("replayed message %s in %s", timestamp, channel))
+        }
+        if !users[user] {
+            users[user] = true
+            slackAPI.AddUser(user, false)
+        }
+    }
+
+}
+
+// newObserver, report the Slack and Jira calls made since it was last called
+func newObserver(slackAPI *fakes.Slack, jiraAPI *fakes.Jira) func() []replayAction {
+    var posted, ephemeral, updated, deleted, transitioned int
+    created := make(map[string]bool)
+
+    return func() []replayAction {
+        actions := []replayAction{}
+
+        for _, issue := range jiraAPI.Issues() {
+            if !created[issue.Key] {
+                created[issue.Key] = true
+                actions = append(actions, replayAction{Kind: "jira.create", IssueKey: issue.Key, Text: issue.Fields.Summary})
+            }
+        }
+
+        keys := jiraAPI.Deleted()
+        for _, key := range keys[deleted:] {
+            actions = append(actions, replayAction{Kind: "jira.delete", IssueKey: key})
+        }
+        deleted = len(keys)
+
+        transitions := jiraAPI.Transitioned()
+        for _, transition := range transitions[transitioned:] {
+            actions = append(actions, replayAction{Kind: "jira.transition", IssueKey: transition.Key, Text: transition.Status})
+        }
+        transitioned = len(transitions)
+
+        messages := slackAPI.Posted()
+        for _, message := range messages[posted:] {
+            actions = append(actions, replayAction{Kind: "slack.post", Channel: message.Channel, Text: message.Text})
+        }
+        posted = len(messages)
+
+        messages = slackAPI.Ephemeral()
+        for _, message := range messages[ephemeral:] {
+            actions = append(actions, replayAction{Kind: "slack.ephemeral", Channel: message.Channel, User: message.User, Text: message.Text})
+        }
+        ephemeral = len(messages)
+
+        messages = slackAPI.Updated()
+        for _, message := range messages[updated:] {
+            actions = append(actions, replayAction{Kind: "slack.update", Channel: message.Channel, Text: message.Text})
+        }
+        updated = len(messages)
+
+        return actions
+
+    }
+
+}
@@ -0,0 +1,105 @@
+//go:build replay
+// +build replay
+
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "strings"
+    "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+    "slack-jira-integration/testing/fakes"
+)
+
+func decodeResults(t *testing.T, out *bytes.Buffer) []replayResult {
+    var results []replayResult
+
+    decoder := json.NewDecoder(out)
+    for decoder.More() {
+        var result replayResult
+        require.NoError(t, decoder.Decode(&result))
+        results = append(results, result)
+    }
+
+    return results
+
+}
+
+func TestReplayPostsSignedEnvelopes(t *testing.T) {
+    var signatures []string
+    server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+        signatures = append(signatures, req.Header.Get("X-Slack-Signature"))
+    }))
+    defer server.Close()
+
+    events := strings.Join([]string{
+        string(fakes.ReactionAdded("T1", "U1", "C1", "1641160800.000100", "ticket")),
+        "",
+        "not json",
+        string(fakes.URLVerification("challenge")),
+    }, "\n")
+
+    out := &bytes.Buffer{}
+    require.NoError(t, replay(strings.NewReader(events), server.URL, "secret", out, nil))
+
+    results := decodeResults(t, out)
+    require.Len(t, results, 3)
+    assert.Equal(t, replayResult{Line: 1, Event: "reaction_added", Status: http.StatusOK}, results[0])
+    assert.Equal(t, 3, results[1].Line)
+    assert.Contains(t, results[1].Error, "invalid envelope")
+    assert.Equal(t, replayResult{Line: 4, Event: "url_verification", Status: http.StatusOK}, results[2])
+    assert.Len(t, signatures, 2)
+
+}
+
+func TestReplayInProcess(t *testing.T) {
+    for key, value := range map[string]string{
+        "SLACK_CHANNELS": "alerts",
+        "SLACK_EMOJI_ALERTS": "ticket",
+        "JIRA_PROJECT": "OPS",
+        "JIRA_SUMMARY": "Escalated from Slack",
+        "JIRA_ISSUE_TYPE": "Task",
+        "UNDO_GRACE_PERIOD": "1m",
+        "UNDO_ACTION": "transition",
+        "UNDO_TRANSITION": "Won't Do",
+    } {
+        t.Setenv(key, value)
+    }
+
+    events := strings.Join([]string{
+        string(fakes.ReactionAdded("T1", "U1", "C0ALERTS", "1641160800.000100", "ticket")),
+        string(fakes.ReactionAdded("T1", "U2", "C0ALERTS", "1641160800.000100", "eyes")),
+        string(fakes.ReactionRemoved("T1", "U1", "C0ALERTS", "1641160800.000100", "ticket")),
+    }, "\n")
+
+    out := &bytes.Buffer{}
+    require.NoError(t, replayInProcess(environment{}, strings.NewReader(events), "secret", "alerts=C0ALERTS", out))
+
+    // the fakes were configured without changing the environment
+    assert.Empty(t, os.Getenv("SLACK_API_URL"))
+
+    results := decodeResults(t, out)
+    require.Len(t, results, 3)
+
+    assert.Equal(t, http.StatusOK, results[0].Status)
+    require.Len(t, results[0].Actions, 2)
+    assert.Equal(t, replayAction{Kind: "jira.create", IssueKey: "OPS-1", Text: "Escalated from Slack"}, results[0].Actions[0])
+    assert.Equal(t, "slack.post", results[0].Actions[1].Kind)
+    assert.Equal(t, "C0ALERTS", results[0].Actions[1].Channel)
+    assert.True(t, strings.HasSuffix(results[0].Actions[1].Text, "/browse/OPS-1"))
+
+    assert.Empty(t, results[1].Actions)
+
+    assert.Equal(t, "reaction_removed", results[2].Event)
+    require.Len(t, results[2].Actions, 2)
+    assert.Equal(t, replayAction{Kind: "jira.transition", IssueKey: "OPS-1", Text: "Won't Do"}, results[2].Actions[0])
+    assert.Equal(t, "slack.update", results[2].Actions[1].Kind)
+
+}
@@ -0,0 +1,314 @@
+package github
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "net/url"
+    "regexp"
+    "strconv"
+    "strings"
+
+    "slack-jira-integration/chat"
+    "slack-jira-integration/httpapi"
+)
+
+const (
+    DefaultAPIURL = "https://api.github.com/"
+    DefaultWebURL = "https://github.com/"
+)
+
+// GitHubEnv creates tickets as GitHub issues in one repository through the
+// REST API, ticket keys are GitHub's own references e.g. "org/repo#12"
+type GitHubEnv struct {
+    Client *http.Client
+    // APIURL is the REST API root, https://HOST/api/v3/ for GitHub Enterprise
+    APIURL string
+    // WebURL is where issues are browsed, issue links are built from it
+    WebURL string
+    Owner string
+    Repo string
+    Title string
+    Labels []string
+}
+
+// NewClient, construct a http.Client authenticating with a personal access
+// or app installation token
+func NewClient(token string) *http.Client {
+    return httpapi.NewClient("github", token, map[string]string{"Accept": "application/vnd.github+json"})
+
+}
+
+// WebURLFor, the web root matching an API root, https://api.github.com/ is
+// served from https://github.com/ and Enterprise's https://HOST/api/v3/ from https://HOST/
+func WebURLFor(apiURL string) string {
+    parsed, err := url.Parse(apiURL)
+    if err != nil || parsed.Host == "api.github.com" {
+        return DefaultWebURL
+    }
+
+    parsed.Path = strings.TrimSuffix(strings.TrimSuffix(parsed.Path, "/"), "/api/v3") + "/"
+    return parsed.String()
+
+}
+
+// NewEnv, construct a GitHubEnv for repository (owner/repo) and check the
+// repository can be reached with client
+func NewEnv(client *http.Client, apiURL string, webURL string, repository string, title string, labels []string) (*GitHubEnv, error) {
+    parts := strings.Split(repository, "/")
+    if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+        return nil, fmt.Errorf("invalid github repository '%s', expected owner/repo", repository)
+    }
+
+    if apiURL == "" {
+        apiURL = DefaultAPIURL
+    }
+    if webURL == "" {
+        webURL = WebURLFor(apiURL)
+    }
+
+    env := &GitHubEnv{
+        Client: client,
+        APIURL: strings.TrimSuffix(apiURL, "/") + "/",
+        WebURL: strings.TrimSuffix(webURL, "/") + "/",
+        Owner: parts[0],
+        Repo: parts[1],
+        Title: title,
+        Labels: labels,
+    }
+
+    if err := env.Ping(context.Background()); err != nil {
+        return nil, err
+    }
+
+    return env, nil
+
+}
+
+// issue is the part of a GitHub issue the integration reads
+type issue struct {
+    Number int `json:"number"`
+    Title string `json:"title"`
+    State string `json:"state"`
+    StateReason string `json:"state_reason"`
+    HTMLURL string `json:"html_url"`
+    Assignee *struct {
+        Login string `json:"login"`
+    } `json:"assignee"`
+}
+
+// do, send a JSON request to path under the API root and decode the response
+// into out, GitHub's error body is returned as the error
+func (g *GitHubEnv) do(ctx context.Context, action string, method string, path string, body interface{}, out interface{}) error {
+    return httpapi.Do(ctx, g.Client, "github", action, method, g.APIURL + path, body, out)
+
+}
+
+// splitKey, the issue number of a key, a key of "#12" or "12" refers to the
+// configured repository, keys of any other repository are refused as the
+// token may reach repositories the integration must not touch
+func (g *GitHubEnv) splitKey(key string) (string, error) {
+    repository, number := "", key
+    if hash := strings.LastIndex(key, "#"); hash >= 0 {
+        repository, number = key[:hash], key[hash + 1:]
+    }
+
+    if repository != "" && !strings.EqualFold(repository, g.Owner + "/" + g.Repo) {
+        return "", fmt.Errorf("github issue key '%s' is not in %s/%s", key, g.Owner, g.Repo)
+    }
+
+    parsed, err := strconv.Atoi(number)
+    if err != nil || parsed <= 0 {
+        return "", fmt.Errorf("invalid github issue key '%s'", key)
+    }
+
+    return strconv.Itoa(parsed), nil
+
+}
+
+// issuePath, the API path of the issue a key refers to
+func (g *GitHubEnv) issuePath(key string) (string, error) {
+    number, err := g.splitKey(key)
+    if err != nil {
+        return "", err
+    }
+
+    return fmt.Sprintf("repos/%s/%s/issues/%s", g.Owner, g.Repo, number), nil
+
+}
+
+// Ping, verify the repository is reachable and the token is still accepted
+func (g *GitHubEnv) Ping(ctx context.Context) error {
+    return g.do(ctx, "get repository", http.MethodGet, fmt.Sprintf("repos/%s/%s", g.Owner, g.Repo), nil, nil)
+
+}
+
+// CreateTicket, open an issue with the configured title and labels and the
+// description as its body, returns its key
+func (g *GitHubEnv) CreateTicket(ctx context.Context, description string) (string, error) {
+    created, err := g.CreateTicketDetails(ctx, description)
+    if err != nil {
+        return "", err
+    }
+
+    return created.Key, nil
+
+}
+
+// CreateTicketDetails, open an issue like CreateTicket, GitHub answers with
+// the whole issue
+func (g *GitHubEnv) CreateTicketDetails(ctx context.Context, description string) (*chat.Ticket, error) {
+    payload := map[string]interface{}{"title": g.Title, "body": description}
+    if len(g.Labels) > 0 {
+        payload["labels"] = g.Labels
+    }
+
+    var created issue
+    err := g.do(ctx, "create issue", http.MethodPost, fmt.Sprintf("repos/%s/%s/issues", g.Owner, g.Repo), payload, &created)
+    if err != nil {
+        return nil, err
+    }
+
+    key := fmt.Sprintf("%s/%s#%d", g.Owner, g.Repo, created.Number)
+    return &chat.Ticket{Key: key, URL: g.TicketURL(key), Summary: created.Title, Status: created.State}, nil
+
+}
+
+// CommentTicket, add a comment to the issue
+func (g *GitHubEnv) CommentTicket(ctx context.Context, key string, comment string) error {
+    path, err := g.issuePath(key)
+    if err != nil {
+        return err
+    }
+
+    return g.do(ctx, "comment issue", http.MethodPost, path + "/comments", map[string]string{"body": comment}, nil)
+
+}
+
+// TicketStatus, "open" or "closed", a closed issue's reason when it has one
+// e.g. "completed" or "not_planned"
+func (g *GitHubEnv) TicketStatus(ctx context.Context, key string) (string, error) {
+    path, err := g.issuePath(key)
+    if err != nil {
+        return "", err
+    }
+
+    var current issue
+    if err := g.do(ctx, "get issue", http.MethodGet, path, nil, &current); err != nil {
+        return "", err
+    }
+
+    if current.State == "closed" && current.StateReason != "" {
+        return current.StateReason, nil
+    }
+
+    return current.State, nil
+
+}
+
+// GetTicket, the title, status (see TicketStatus) and assignee of the issue,
+// GitHub issues have no priority
+func (g *GitHubEnv) GetTicket(ctx context.Context, key string) (*chat.Ticket, error) {
+    path, err := g.issuePath(key)
+    if err != nil {
+        return nil, err
+    }
+
+    var current issue
+    if err := g.do(ctx, "get issue", http.MethodGet, path, nil, &current); err != nil {
+        return nil, err
+    }
+
+    ticket := &chat.Ticket{Key: key, URL: g.TicketURL(key), Summary: current.Title, Status: current.State}
+    if current.State == "closed" && current.StateReason != "" {
+        ticket.Status = current.StateReason
+    }
+    if current.Assignee != nil {
+        ticket.Assignee = current.Assignee.Login
+    }
+
+    return ticket, nil
+
+}
+
+// LinkTicket, GitHub issues have no remote links so a comment with the link
+// is added instead
+func (g *GitHubEnv) LinkTicket(ctx context.Context, key string, url string, title string) error {
+    return g.CommentTicket(ctx, key, fmt.Sprintf("Discussed in [%s](%s)", title, url))
+
+}
+
+// FindTicketKeys, the issues of the configured repository referenced in text
+// as owner/repo#N, a bare #N is too common in chat to be taken as a reference
+func (g *GitHubEnv) FindTicketKeys(text string) []string {
+    pattern := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(g.Owner + "/" + g.Repo) + `#[1-9][0-9]*\b`)
+
+    var keys []string
+    for _, found := range pattern.FindAllString(text, -1) {
+        number, err := g.splitKey(found)
+        if err != nil {
+            continue
+        }
+        keys = append(keys, fmt.Sprintf("%s/%s#%s", g.Owner, g.Repo, number))
+    }
+
+    return keys
+
+}
+
+// TicketURL, the web link of the issue, the repository's issues for a key
+// splitKey refuses
+func (g *GitHubEnv) TicketURL(key string) string {
+    number, err := g.splitKey(key)
+    if err != nil {
+        return fmt.Sprintf("%s%s/%s/issues", g.WebURL, g.Owner, g.Repo)
+    }
+
+    return fmt.Sprintf("%s%s/%s/issues/%s", g.WebURL, g.Owner, g.Repo, number)
+
+}
+
+// TicketProject, the owner/repo new issues are opened in
+func (g *GitHubEnv) TicketProject() string {
+    return g.Owner + "/" + g.Repo
+
+}
+
+// states maps the names a transition may be given to a GitHub state and reason
+var states = map[string][2]string{
+    "open": {"open", ""},
+    "reopen": {"open", ""},
+    "to do": {"open", ""},
+    "closed": {"closed", "completed"},
+    "close": {"closed", "completed"},
+    "done": {"closed", "completed"},
+    "completed": {"closed", "completed"},
+    "resolved": {"closed", "completed"},
+    "won't do": {"closed", "not_planned"},
+    "wont do": {"closed", "not_planned"},
+    "not planned": {"closed", "not_planned"},
+    "not_planned": {"closed", "not_planned"},
+    "cancelled": {"closed", "not_planned"},
+}
+
+// TransitionTicket, open or close the issue, name is matched case-insensitively
+// against open, closed, done, won't do, not planned and similar
+func (g *GitHubEnv) TransitionTicket(ctx context.Context, key string, name string) error {
+    state, known := states[strings.ToLower(strings.TrimSpace(name))]
+    if !known {
+        return fmt.Errorf("no transition '%s' for issue %s", name, key)
+    }
+
+    path, err := g.issuePath(key)
+    if err != nil {
+        return err
+    }
+
+    payload := map[string]string{"state": state[0]}
+    if state[1] != "" {
+        payload["state_reason"] = state[1]
+    }
+
+    return g.do(ctx, "transition issue", http.MethodPatch, path, payload, nil)
+
+}
@@ -0,0 +1,96 @@
+package github
+
+import (
+    "context"
+    "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+    "slack-jira-integration/chat"
+    "slack-jira-integration/testing/fakes"
+)
+
+func TestWebURLFor(t *testing.T) {
+    assert.Equal(t, "https://github.com/", WebURLFor("https://api.github.com/"))
+    assert.Equal(t, "https://github.example.com/", WebURLFor("https://github.example.com/api/v3/"))
+
+}
+
+func TestNewEnv(t *testing.T) {
+    fake := fakes.NewGitHub(t, "acme/ops")
+    fake.Token = "token"
+
+    _, err := NewEnv(NewClient("token"), fake.URL, "", "acme", "Escalated", nil)
+    assert.ErrorContains(t, err, "expected owner/repo")
+
+    _, err = NewEnv(NewClient("token"), fake.URL, "", "acme/missing", "Escalated", nil)
+    assert.ErrorContains(t, err, "404")
+
+    _, err = NewEnv(NewClient("wrong"), fake.URL, "", "acme/ops", "Escalated", nil)
+    assert.ErrorContains(t, err, "Bad credentials")
+
+}
+
+func TestGitHubEnv(t *testing.T) {
+    fake := fakes.NewGitHub(t, "acme/ops", "acme/web")
+    fake.Token = "token"
+
+    env, err := NewEnv(NewClient("token"), fake.URL, "https://github.example.com", "acme/ops", "Escalated from Slack", []string{"slack"})
+    require.NoError(t, err)
+    assert.Equal(t, "acme/ops", env.TicketProject())
+
+    key, err := env.CreateTicket(context.Background(), "the checkout page is down")
+    require.NoError(t, err)
+    assert.Equal(t, "acme/ops#1", key)
+    assert.Equal(t, "https://github.example.com/acme/ops/issues/1", env.TicketURL(key))
+
+    issues := fake.Issues("acme/ops")
+    require.Len(t, issues, 1)
+    assert.Equal(t, "Escalated from Slack", issues[0].Title)
+    assert.Equal(t, "the checkout page is down", issues[0].Body)
+    assert.Equal(t, []string{"slack"}, issues[0].Labels)
+
+    // creating tells enough for a card without looking the issue up
+    created, err := env.CreateTicketDetails(context.Background(), "the cart is empty")
+    require.NoError(t, err)
+    assert.Equal(t, chat.Ticket{Key: "acme/ops#2", URL: "https://github.example.com/acme/ops/issues/2", Summary: "Escalated from Slack", Status: "open"}, *created)
+
+    require.NoError(t, env.CommentTicket(context.Background(), key, "still down"))
+    assert.Equal(t, []string{"still down"}, fake.Issues("acme/ops")[0].Comments)
+
+    status, err := env.TicketStatus(context.Background(), key)
+    require.NoError(t, err)
+    assert.Equal(t, "open", status)
+
+    require.NoError(t, env.TransitionTicket(context.Background(), key, "Won't Do"))
+    status, err = env.TicketStatus(context.Background(), key)
+    require.NoError(t, err)
+    assert.Equal(t, "not_planned", status)
+
+    require.NoError(t, env.TransitionTicket(context.Background(), "#1", "reopen"))
+    status, err = env.TicketStatus(context.Background(), key)
+    require.NoError(t, err)
+    assert.Equal(t, "open", status)
+
+    ticket, err := env.GetTicket(context.Background(), key)
+    require.NoError(t, err)
+    assert.Equal(t, "Escalated from Slack", ticket.Summary)
+    assert.Equal(t, "open", ticket.Status)
+    assert.Equal(t, "https://github.example.com/acme/ops/issues/1", ticket.URL)
+
+    require.NoError(t, env.LinkTicket(context.Background(), key, "https://acme.slack.com/archives/C0ALERTS/p1641160800000100", "#alerts"))
+    assert.Equal(t, "Discussed in [#alerts](https://acme.slack.com/archives/C0ALERTS/p1641160800000100)", fake.Issues("acme/ops")[0].Comments[1])
+
+    assert.Equal(t, []string{"acme/ops#1", "acme/ops#12"}, env.FindTicketKeys("see acme/ops#1 and ACME/ops#12, not acme/web#3 or #4"))
+
+    assert.ErrorContains(t, env.TransitionTicket(context.Background(), key, "In Review"), "no transition")
+    // only issues of the configured repository are touched
+    assert.ErrorContains(t, env.CommentTicket(context.Background(), "acme/web#7", "hi"), "'acme/web#7' is not in acme/ops")
+    assert.ErrorContains(t, env.CommentTicket(context.Background(), "acme/ops/../web#7", "hi"), "is not in acme/ops")
+    _, err = env.GetTicket(context.Background(), "acme/web#7")
+    assert.ErrorContains(t, err, "is not in acme/ops")
+    assert.Equal(t, "https://github.example.com/acme/ops/issues", env.TicketURL("acme/web#7"))
+    assert.ErrorContains(t, env.CommentTicket(context.Background(), "acme/ops#x", "hi"), "invalid github issue key")
+
+}
@@ -0,0 +1,134 @@
+package health
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "sort"
+    "sync"
+    "time"
+)
+
+// Check reports whether one dependency is usable, nil meaning healthy
+type Check func(ctx context.Context) error
+
+// Checker runs the registered dependency checks for /readyz, results are
+// cached for TTL so frequent probes do not hammer the Slack and Jira APIs
+type Checker struct {
+    TTL time.Duration
+    Timeout time.Duration
+
+    mu sync.Mutex
+    names []string
+    checks map[string]Check
+    results map[string]string
+    checkedAt time.Time
+}
+
+type readiness struct {
+    Status string `json:"status"`
+    Checks map[string]string `json:"checks"`
+}
+
+// NewChecker, construct a new Checker caching results for ttl and giving
+// each check at most timeout to complete
+func NewChecker(ttl time.Duration, timeout time.Duration) *Checker {
+    return &Checker{
+        TTL: ttl,
+        Timeout: timeout,
+        checks: make(map[string]Check),
+    }
+
+}
+
+// Add, register a named dependency check
+func (c *Checker) Add(name string, check Check) *Checker {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.names = append(c.names, name)
+    sort.Strings(c.names)
+    c.checks[name] = check
+    c.checkedAt = time.Time{}
+
+    return c
+
+}
+
+// Results, the "ok" or error message of every check, rerun once the cached
+// results are older than TTL
+func (c *Checker) Results(ctx context.Context) (map[string]string, bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if c.results == nil || time.Since(c.checkedAt) > c.TTL {
+        c.results = c.run(ctx)
+        c.checkedAt = time.Now()
+    }
+
+    healthy := true
+    results := make(map[string]string, len(c.results))
+    for name, result := range c.results {
+        results[name] = result
+        healthy = healthy && result == "ok"
+    }
+
+    return results, healthy
+
+}
+
+// run, run every check concurrently so one slow dependency does not delay the others
+func (c *Checker) run(ctx context.Context) map[string]string {
+    ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+    defer cancel()
+
+    var mu sync.Mutex
+    var wg sync.WaitGroup
+    results := make(map[string]string, len(c.names))
+
+    for _, name := range c.names {
+        wg.Add(1)
+        go func(name string, check Check) {
+            defer wg.Done()
+
+            result := "ok"
+            if err := check(ctx); err != nil {
+                result = err.Error()
+            }
+
+            mu.Lock()
+            results[name] = result
+            mu.Unlock()
+
+        }(name, c.checks[name])
+    }
+
+    wg.Wait()
+
+    return results
+
+}
+
+// LivenessHandler, /healthz, the process is serving requests
+func (c *Checker) LivenessHandler(resp http.ResponseWriter, req *http.Request) {
+    resp.Header().Set("Content-Type", "text/plain")
+    resp.Write([]byte("ok"))
+
+}
+
+// ReadinessHandler, /readyz, every dependency check passes
+func (c *Checker) ReadinessHandler(resp http.ResponseWriter, req *http.Request) {
+    results, healthy := c.Results(req.Context())
+
+    body := readiness{Status: "ok", Checks: results}
+    status := http.StatusOK
+    if !healthy {
+        body.Status = "unavailable"
+        status = http.StatusServiceUnavailable
+    }
+
+    resp.Header().Set("Content-Type", "application/json")
+    resp.WriteHeader(status)
+    json.NewEncoder(resp).Encode(body)
+
+}
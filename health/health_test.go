@@ -0,0 +1,64 @@
+package health
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestReadinessHandler(t *testing.T) {
+    calls := 0
+    jiraErr := fmt.Errorf("some jira error")
+
+    checker := NewChecker(time.Minute, time.Second).
+        Add("slack", func(ctx context.Context) error {
+            calls++
+            return nil
+        }).
+        Add("jira:default", func(ctx context.Context) error {
+            return jiraErr
+        })
+
+    rr := httptest.NewRecorder()
+    checker.ReadinessHandler(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+    assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+    var body readiness
+    assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &body))
+    assert.Equal(t, readiness{
+        Status: "unavailable",
+        Checks: map[string]string{"slack": "ok", "jira:default": "some jira error"},
+    }, body)
+
+    // results are cached until the ttl expires
+    jiraErr = nil
+    rr = httptest.NewRecorder()
+    checker.ReadinessHandler(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+    assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+    assert.Equal(t, 1, calls)
+
+    checker.TTL = 0
+    rr = httptest.NewRecorder()
+    checker.ReadinessHandler(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+    assert.Equal(t, http.StatusOK, rr.Code)
+    assert.Equal(t, 2, calls)
+
+}
+
+func TestLivenessHandler(t *testing.T) {
+    rr := httptest.NewRecorder()
+    NewChecker(time.Minute, time.Second).LivenessHandler(rr, httptest.NewRequest("GET", "/healthz", nil))
+
+    assert.Equal(t, http.StatusOK, rr.Code)
+    assert.Equal(t, "ok", rr.Body.String())
+
+}
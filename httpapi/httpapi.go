@@ -0,0 +1,88 @@
+package httpapi
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "io/ioutil"
+    "net/http"
+
+    "slack-jira-integration/logging"
+    "slack-jira-integration/metrics"
+    "slack-jira-integration/tracing"
+)
+
+// tokenTransport authenticates every request with a Bearer token and sets
+// the headers the service expects
+type tokenTransport struct {
+    token string
+    headers map[string]string
+    next http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    req = req.Clone(req.Context())
+    req.Header.Set("Authorization", "Bearer " + t.token)
+    for name, value := range t.headers {
+        req.Header.Set(name, value)
+    }
+
+    return t.next.RoundTrip(req)
+
+}
+
+// NewClient, construct a http.Client for the JSON API of service e.g.
+// "github", every request carries token and headers and is traced and
+// counted under the service's name
+func NewClient(service string, token string, headers map[string]string) *http.Client {
+    transport := &tokenTransport{token: token, headers: headers, next: http.DefaultTransport}
+
+    return &http.Client{
+        Transport: tracing.InstrumentRoundTripper(metrics.InstrumentRoundTripper(service, transport)),
+    }
+
+}
+
+// Do, send body as JSON to url with client and decode the response into out,
+// the service's error body is returned as the error, action names the call
+// in errors and the debug log
+func Do(ctx context.Context, client *http.Client, service string, action string, method string, url string, body interface{}, out interface{}) error {
+    logging.FromContext(ctx).WithField(service + "_method", action).Debug(service + " api call")
+
+    var reader io.Reader
+    if body != nil {
+        bodyBytes, err := json.Marshal(body)
+        if err != nil {
+            return err
+        }
+        reader = bytes.NewReader(bodyBytes)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, method, url, reader)
+    if err != nil {
+        return err
+    }
+    if body != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+
+    resp, err := client.Do(req)
+    if err != nil {
+        return fmt.Errorf("%s err: %w", action, err)
+    }
+    defer resp.Body.Close()
+
+    respBytes, _ := ioutil.ReadAll(resp.Body)
+    if resp.StatusCode >= http.StatusBadRequest {
+        return fmt.Errorf("%s err: %d %s", action, resp.StatusCode, respBytes)
+    }
+
+    if out == nil {
+        return nil
+    }
+
+    return json.Unmarshal(respBytes, out)
+
+}
@@ -0,0 +1,45 @@
+package httpapi
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDo(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+        if req.Header.Get("Authorization") != "Bearer token" {
+            resp.WriteHeader(http.StatusUnauthorized)
+            resp.Write([]byte(`{"message":"Bad credentials"}`))
+            return
+        }
+
+        var body map[string]string
+        json.NewDecoder(req.Body).Decode(&body)
+        json.NewEncoder(resp).Encode(map[string]string{
+            "accept": req.Header.Get("Accept"),
+            "content_type": req.Header.Get("Content-Type"),
+            "name": body["name"],
+        })
+    }))
+    defer server.Close()
+
+    client := NewClient("test", "token", map[string]string{"Accept": "application/vnd.test+json"})
+
+    var out map[string]string
+    require.NoError(t, Do(context.Background(), client, "test", "create", "POST", server.URL, map[string]string{"name": "ops"}, &out))
+    assert.Equal(t, "application/vnd.test+json", out["accept"])
+    assert.Equal(t, "application/json", out["content_type"])
+    assert.Equal(t, "ops", out["name"])
+
+    require.NoError(t, Do(context.Background(), client, "test", "get", "GET", server.URL, nil, nil))
+
+    err := Do(context.Background(), NewClient("test", "wrong", nil), "test", "get", "GET", server.URL, nil, &out)
+    assert.EqualError(t, err, `get err: 401 {"message":"Bad credentials"}`)
+
+}
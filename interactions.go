@@ -0,0 +1,385 @@
+package runtime
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "time"
+
+	"github.com/sirupsen/logrus"
+	slackgo "github.com/slack-go/slack"
+
+    "slack-jira-integration/audit"
+    "slack-jira-integration/logging"
+    "slack-jira-integration/metrics"
+    "slack-jira-integration/slack"
+)
+
+// SlackInteractionsHandler, act on the buttons of ticket cards and on the
+// modals they open, the clicking user is mapped to a ticket backend account
+// by the email address of their Slack profile, Slack is answered before the
+// ticket is acted on as it gives up after three seconds
+func (r *runtime) SlackInteractionsHandler(resp http.ResponseWriter, req *http.Request) {
+    // the signature middleware has already parsed the payload
+    interaction, ok := slack.InteractionFromContext(req.Context())
+    if !ok {
+        interaction = &slackgo.InteractionCallback{}
+        if err := json.Unmarshal([]byte(req.FormValue("payload")), interaction); err != nil {
+            resp.WriteHeader(http.StatusBadRequest)
+            return
+        }
+    }
+
+    response, accepted := r.HandleInteraction(req.Context(), interaction)
+    if !accepted {
+        resp.WriteHeader(http.StatusServiceUnavailable)
+        return
+    }
+
+    // errors are shown under the modal's input, the modal stays open
+    if response != nil {
+        resp.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(resp).Encode(response)
+    }
+
+}
+
+// HandleInteraction, act on interaction in the background and return what
+// to answer Slack with, nil when there is nothing to say, false when
+// draining, the entry point of Socket Mode as well
+func (r *runtime) HandleInteraction(ctx context.Context, interaction *slackgo.InteractionCallback) (*slackgo.ViewSubmissionResponse, bool) {
+    if !r.begin() {
+        return nil, false
+    }
+
+    // work is done once the interaction is answered, it stays in flight
+    // until it returns
+    var work func(ctx context.Context)
+    defer func() {
+        if work == nil {
+            r.end()
+            return
+        }
+
+        go func() {
+            defer r.end()
+            work(detachedContext{ctx})
+        }()
+    }()
+
+    ctx = logging.WithFields(ctx, logrus.Fields{"team_id": interaction.Team.ID, "user": interaction.User.ID})
+    metrics.EventsReceived.WithLabelValues(string(interaction.Type)).Inc()
+
+    slackEnv, err := r.slackEnv(interaction.Team.ID, interaction.Enterprise.ID)
+    if err != nil {
+        logging.FromContext(ctx).WithError(err).Error("no slack env for interaction")
+        return nil, true
+    }
+
+    switch interaction.Type {
+    case slackgo.InteractionTypeBlockActions:
+        work = func(ctx context.Context) {
+            for _, action := range interaction.ActionCallback.BlockActions {
+                r.cardAction(ctx, slackEnv, interaction, action)
+            }
+        }
+
+    case slackgo.InteractionTypeViewSubmission:
+        card, errors := r.modalCard(ctx, slackEnv, interaction.View)
+        if len(errors) > 0 {
+            return slackgo.NewErrorsViewSubmissionResponse(errors), true
+        }
+
+        if card != nil {
+            work = func(ctx context.Context) {
+                r.modalSubmission(ctx, slackEnv, interaction, *card)
+            }
+        }
+    }
+
+    return nil, true
+
+}
+
+// cardBackend, the TicketBackend the card's ticket belongs to and the
+// AccessPolicy of the card's channel, the backend recognising the key is
+// preferred where routes send the channel's tickets to several backends
+func (r *runtime) cardBackend(frontend *slack.SlackEnv, card slack.CardRef) (TicketBackend, *AccessPolicy) {
+    backend, policy := r.channelRoute(frontend, card.Channel)
+    if ownsTicket(backend, card.Key) {
+        return backend, policy
+    }
+
+    for _, candidate := range r.Backends {
+        if ownsTicket(candidate, card.Key) {
+            return candidate, policy
+        }
+    }
+
+    return backend, policy
+
+}
+
+// refreshCard, show the ticket's current state on its card, a card that
+// cannot be refreshed keeps showing the old state
+func refreshCard(ctx context.Context, frontend *slack.SlackEnv, backend TicketBackend, card slack.CardRef) {
+    ticket, err := lookupTicket(ctx, backend, card.Key)
+    if err == nil {
+        err = frontend.UpdateTicketCard(ctx, card.Channel, card.Timestamp, *ticket)
+    }
+
+    if err != nil {
+        logging.FromContext(ctx).WithError(err).Warn("card not refreshed")
+    }
+
+}
+
+// tellUser, log err and show reply to user in channel, an empty reply
+// shows nothing
+func tellUser(ctx context.Context, frontend *slack.SlackEnv, channel string, user string, reply string, err error) {
+    if err != nil {
+        logging.FromContext(ctx).WithError(err).Error("card action failed")
+    }
+
+    if reply == "" {
+        return
+    }
+
+    if err := frontend.PostEphemeral(ctx, channel, user, reply); err != nil {
+        logging.FromContext(ctx).WithError(err).Error("card action reply failed")
+    }
+
+}
+
+// cardAction, act on a click of one of the card's buttons, failures are
+// told to the clicking user ephemerally
+func (r *runtime) cardAction(ctx context.Context, frontend *slack.SlackEnv, interaction *slackgo.InteractionCallback, action *slackgo.BlockAction) {
+    // "Open in Jira" is a link, Slack still reports its clicks
+    if action.BlockID != slack.CardBlockID || action.ActionID == slack.ActionOpen {
+        return
+    }
+
+    card := slack.CardRef{Channel: interaction.Channel.ID, Timestamp: interaction.Container.MessageTs, Key: action.Value}
+    if card.Timestamp == "" {
+        card.Timestamp = interaction.Message.Timestamp
+    }
+
+    ctx = logging.WithFields(ctx, logrus.Fields{"channel": card.Channel, "issue_key": card.Key, "action": action.ActionID})
+
+    reply, err := r.cardActionReply(ctx, frontend, interaction.TriggerID, interaction.Team.ID, interaction.User.ID, card, action.ActionID)
+    tellUser(ctx, frontend, card.Channel, interaction.User.ID, reply, err)
+
+}
+
+// updateCard, change the ticket of the card on behalf of user with update
+// and refresh the card, the change is audited as action, update and
+// updateCard return what to tell user when the change was not made
+func (r *runtime) updateCard(ctx context.Context, frontend *slack.SlackEnv, teamID string, user string, card slack.CardRef, action string, update func(ctx context.Context, backend TicketBackend) (string, error)) (reply string, err error) {
+    backend, policy := r.cardBackend(frontend, card)
+
+    record := audit.Record{
+        Time: time.Now(),
+        Action: action,
+        TeamID: teamID,
+        UserID: user,
+        ChannelID: card.Channel,
+        MessageTS: card.Timestamp,
+        Project: backend.TicketProject(),
+        IssueKey: card.Key,
+        Outcome: audit.OutcomeUpdated,
+    }
+    defer func() {
+        if err != nil {
+            record.Outcome, record.Error = audit.OutcomeFailed, err.Error()
+        }
+        r.audit(ctx, record)
+    }()
+
+    reason, allowed, err := policy.Authorize(ctx, frontend, user)
+    if err != nil {
+        return fmt.Sprintf("Sorry, %s could not be updated.", card.Key), err
+    }
+
+    if !allowed {
+        record.Outcome, record.Error = audit.OutcomeDenied, reason
+        return fmt.Sprintf("Sorry, %s.", reason), nil
+    }
+
+    reply, err = update(ctx, backend)
+    if reply != "" || err != nil {
+        // a change refused without an error is still not made
+        if err == nil {
+            record.Outcome, record.Error = audit.OutcomeFailed, reply
+        }
+        return reply, err
+    }
+
+    logging.FromContext(ctx).Info("ticket updated from card")
+    refreshCard(ctx, frontend, backend, card)
+
+    return "", nil
+
+}
+
+// cardActionReply, assign the ticket to user or open the modal choosing the
+// transition, the priority or writing the comment, returns what to tell
+// user when that could not be done
+func (r *runtime) cardActionReply(ctx context.Context, frontend *slack.SlackEnv, triggerID string, teamID string, user string, card slack.CardRef, actionID string) (string, error) {
+    // the other buttons only open a modal, its submission is audited
+    if actionID == slack.ActionAssignToMe {
+        return r.updateCard(ctx, frontend, teamID, user, card, "assign", func(ctx context.Context, backend TicketBackend) (string, error) {
+            assigner, canAssign := backend.(TicketAssigner)
+            if !canAssign {
+                return fmt.Sprintf("Sorry, %s cannot be assigned from Slack.", card.Key), nil
+            }
+
+            _, email, err := frontend.UserProfile(ctx, user)
+            if err != nil {
+                return "Sorry, your Slack profile could not be looked up.", err
+            }
+
+            if email == "" {
+                return "Sorry, your Slack profile has no email address to find your account by.", nil
+            }
+
+            if err := assigner.AssignTicket(ctx, card.Key, email); err != nil {
+                return fmt.Sprintf("Sorry, %s could not be assigned to %s.", card.Key, email), err
+            }
+
+            return "", nil
+        })
+    }
+
+    backend, policy := r.cardBackend(frontend, card)
+
+    reason, allowed, err := policy.Authorize(ctx, frontend, user)
+    if err != nil {
+        return fmt.Sprintf("Sorry, %s could not be updated.", card.Key), err
+    }
+
+    if !allowed {
+        return fmt.Sprintf("Sorry, %s.", reason), nil
+    }
+
+    switch actionID {
+    case slack.ActionTransition:
+        lister, canList := backend.(TicketTransitionLister)
+        if !canList {
+            return fmt.Sprintf("Sorry, %s cannot be transitioned from Slack.", card.Key), nil
+        }
+
+        names, err := lister.TicketTransitions(ctx, card.Key)
+        if err != nil {
+            return fmt.Sprintf("Sorry, the transitions of %s could not be looked up.", card.Key), err
+        }
+
+        if len(names) == 0 {
+            return fmt.Sprintf("Sorry, %s has no transitions available.", card.Key), nil
+        }
+
+        view := slack.SelectModal(slack.CallbackTransition, "Transition", "Move " + card.Key + " through", card, names)
+        if err := frontend.OpenView(ctx, triggerID, view); err != nil {
+            return fmt.Sprintf("Sorry, %s could not be updated.", card.Key), err
+        }
+
+    case slack.ActionChangePriority:
+        prioritizer, canPrioritize := backend.(TicketPrioritizer)
+        if !canPrioritize {
+            return fmt.Sprintf("Sorry, the priority of %s cannot be changed from Slack.", card.Key), nil
+        }
+
+        names, err := prioritizer.TicketPriorities(ctx)
+        if err != nil {
+            return "Sorry, the priorities could not be looked up.", err
+        }
+
+        view := slack.SelectModal(slack.CallbackPriority, "Change priority", "Priority of " + card.Key, card, names)
+        if err := frontend.OpenView(ctx, triggerID, view); err != nil {
+            return fmt.Sprintf("Sorry, %s could not be updated.", card.Key), err
+        }
+
+    case slack.ActionComment:
+        if err := frontend.OpenView(ctx, triggerID, slack.CommentModal(card)); err != nil {
+            return fmt.Sprintf("Sorry, %s could not be updated.", card.Key), err
+        }
+    }
+
+    return "", nil
+
+}
+
+// modalActions are the audit actions of the modals by callback id
+var modalActions = map[string]string{
+    slack.CallbackTransition: "transition",
+    slack.CallbackPriority: "priority",
+    slack.CallbackComment: "comment",
+}
+
+// modalCard, the card a submitted modal acts on, nil for modals of no card,
+// returns the errors to show in the modal by block id when it cannot be acted on
+func (r *runtime) modalCard(ctx context.Context, frontend *slack.SlackEnv, view slackgo.View) (*slack.CardRef, map[string]string) {
+    if _, known := modalActions[view.CallbackID]; !known {
+        return nil, nil
+    }
+
+    card, err := slack.ModalCard(view)
+    if err != nil {
+        logging.FromContext(ctx).WithError(err).Error("modal submission malformed")
+        return nil, map[string]string{slack.InputBlockID: "Sorry, this form can no longer be submitted."}
+    }
+
+    backend, _ := r.cardBackend(frontend, card)
+    _, canTransition := backend.(TicketTransitioner)
+    _, canPrioritize := backend.(TicketPrioritizer)
+    switch {
+    case view.CallbackID == slack.CallbackTransition && !canTransition:
+        return nil, map[string]string{slack.InputBlockID: fmt.Sprintf("Sorry, %s cannot be transitioned from Slack.", card.Key)}
+
+    case view.CallbackID == slack.CallbackPriority && !canPrioritize:
+        return nil, map[string]string{slack.InputBlockID: fmt.Sprintf("Sorry, the priority of %s cannot be changed from Slack.", card.Key)}
+    }
+
+    return &card, nil
+
+}
+
+// modalSubmission, transition, change the priority of or comment on the
+// ticket of the modal's card and refresh the card, the modal is closed by
+// then so failures are told to the user ephemerally
+func (r *runtime) modalSubmission(ctx context.Context, frontend *slack.SlackEnv, interaction *slackgo.InteractionCallback, card slack.CardRef) {
+    view := interaction.View
+    user := interaction.User.ID
+    ctx = logging.WithFields(ctx, logrus.Fields{"channel": card.Channel, "issue_key": card.Key, "action": view.CallbackID})
+
+    value := slack.ModalValue(view)
+
+    // the user may have lost access since the modal was opened
+    reply, err := r.updateCard(ctx, frontend, interaction.Team.ID, user, card, modalActions[view.CallbackID], func(ctx context.Context, backend TicketBackend) (string, error) {
+        var err error
+        switch view.CallbackID {
+        case slack.CallbackTransition:
+            err = backend.(TicketTransitioner).TransitionTicket(ctx, card.Key, value)
+
+        case slack.CallbackPriority:
+            err = backend.(TicketPrioritizer).PrioritizeTicket(ctx, card.Key, value)
+
+        case slack.CallbackComment:
+            // comments are written by the integration's account, so they name who wrote them
+            author, _, profileErr := frontend.UserProfile(ctx, user)
+            if profileErr != nil || author == "" {
+                author = user
+            }
+            err = backend.CommentTicket(ctx, card.Key, fmt.Sprintf("%s commented in Slack:\n%s", author, value))
+        }
+
+        if err != nil {
+            return fmt.Sprintf("Sorry, %s could not be updated, try again in a moment.", card.Key), err
+        }
+
+        return "", nil
+    })
+
+    tellUser(ctx, frontend, card.Channel, user, reply, err)
+
+}
@@ -0,0 +1,227 @@
+package runtime
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    neturl "net/url"
+    "path/filepath"
+    "strings"
+    "testing"
+
+	slackgo "github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+    "slack-jira-integration/audit"
+    "slack-jira-integration/slack"
+    "slack-jira-integration/testing/fakes"
+)
+
+// newCardRuntime, a link runtime with a card of a new issue posted in a
+// thread of the alerts channel, returns the issue's card
+func newCardRuntime(t *testing.T) (*runtime, *fakes.Slack, *fakes.Jira, slack.CardRef) {
+    r, slackAPI, jiraAPI := newLinkRuntime(t)
+    key := jiraAPI.AddIssue("OPS", "checkout latency")
+    slackAPI.AddMessage("C0ALERTS", "1641160800.000100", "U1", "the checkout page is down")
+
+    ticket, err := r.Backend.(TicketGetter).GetTicket(context.Background(), key)
+    require.NoError(t, err)
+    cardTimestamp, err := r.SlackEnv.PostTicketCard(context.Background(), "C0ALERTS", "1641160800.000100", *ticket)
+    require.NoError(t, err)
+
+    return r, slackAPI, jiraAPI, slack.CardRef{Channel: "C0ALERTS", Timestamp: cardTimestamp, Key: key}
+
+}
+
+// interact, post the interaction form to the handler and wait until it has
+// been acted on
+func interact(t *testing.T, r *runtime, form neturl.Values) *httptest.ResponseRecorder {
+    req := httptest.NewRequest("POST", "/slack/interactions", strings.NewReader(form.Encode()))
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+    rr := httptest.NewRecorder()
+    r.SlackInteractionsHandler(rr, req)
+    assert.Equal(t, http.StatusOK, rr.Code)
+    require.NoError(t, r.Wait(context.Background()))
+
+    return rr
+
+}
+
+// submitted, the modal as Slack sends it back once value was entered
+func submitted(view slackgo.ModalViewRequest, value string, selected bool) slackgo.View {
+    input := slackgo.BlockAction{Value: value}
+    if selected {
+        input = slackgo.BlockAction{SelectedOption: slackgo.OptionBlockObject{Value: value}}
+    }
+
+    return slackgo.View{
+        CallbackID: view.CallbackID,
+        PrivateMetadata: view.PrivateMetadata,
+        State: &slackgo.ViewState{Values: map[string]map[string]slackgo.BlockAction{slack.InputBlockID: {slack.InputActionID: input}}},
+    }
+
+}
+
+// cardField, the text of the field at index of the card's last update
+func cardField(t *testing.T, slackAPI *fakes.Slack, index int) string {
+    updated := slackAPI.Updated()
+    require.NotEmpty(t, updated)
+
+    return updated[len(updated) - 1].Blocks.BlockSet[1].(*slackgo.SectionBlock).Fields[index].Text
+
+}
+
+// newAuditedCardRuntime, newCardRuntime auditing to a file sink
+func newAuditedCardRuntime(t *testing.T) (*runtime, *fakes.Slack, *fakes.Jira, slack.CardRef, audit.Sink) {
+    r, slackAPI, jiraAPI, card := newCardRuntime(t)
+    sink, err := audit.NewFileSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+    require.NoError(t, err)
+    r.WithAudit(sink)
+
+    return r, slackAPI, jiraAPI, card, sink
+
+}
+
+// auditRecords, every record written to sink
+func auditRecords(t *testing.T, sink audit.Sink) []audit.Record {
+    records, err := sink.Search(context.Background(), audit.Query{})
+    require.NoError(t, err)
+
+    return records
+
+}
+
+func TestSlackInteractionsAssignToMe(t *testing.T) {
+    r, slackAPI, jiraAPI, card, sink := newAuditedCardRuntime(t)
+    slackAPI.SetUserProfile("U1", "Ada Lovelace", "ada@example.com")
+    slackAPI.SetUserProfile("U2", "Bob", "bob@example.com")
+    jiraAPI.AddUser("ada-account", "Ada Lovelace", "ada@example.com")
+
+    interact(t, r, fakes.BlockAction(slackAPI.TeamID, "U1", card.Channel, card.Timestamp, slack.CardBlockID, slack.ActionAssignToMe, card.Key))
+    assert.Equal(t, "ada-account", jiraAPI.Issue(card.Key).Fields.Assignee.AccountID)
+    assert.Equal(t, "*Assignee*\nAda Lovelace", cardField(t, slackAPI, 1))
+    assert.Equal(t, card.Timestamp, slackAPI.Updated()[0].Timestamp)
+
+    // Bob has no Jira account
+    interact(t, r, fakes.BlockAction(slackAPI.TeamID, "U2", card.Channel, card.Timestamp, slack.CardBlockID, slack.ActionAssignToMe, card.Key))
+    ephemeral := slackAPI.Ephemeral()
+    require.Len(t, ephemeral, 1)
+    assert.Equal(t, "U2", ephemeral[0].User)
+    assert.Equal(t, "Sorry, " + card.Key + " could not be assigned to bob@example.com.", ephemeral[0].Text)
+    assert.Len(t, slackAPI.Updated(), 1)
+
+    // the link button needs nothing done
+    interact(t, r, fakes.BlockAction(slackAPI.TeamID, "U1", card.Channel, card.Timestamp, slack.CardBlockID, slack.ActionOpen, card.Key))
+    assert.Len(t, slackAPI.Ephemeral(), 1)
+
+    records := auditRecords(t, sink)
+    require.Len(t, records, 2)
+    assert.Equal(t, "assign", records[0].Action)
+    assert.Equal(t, audit.OutcomeUpdated, records[0].Outcome)
+    assert.Equal(t, card.Key, records[0].IssueKey)
+    assert.Equal(t, "U1", records[0].UserID)
+    assert.Equal(t, audit.OutcomeFailed, records[1].Outcome)
+    assert.Contains(t, records[1].Error, "no jira user with email 'bob@example.com'")
+
+}
+
+func TestSlackInteractionsModals(t *testing.T) {
+    r, slackAPI, jiraAPI, card, sink := newAuditedCardRuntime(t)
+    slackAPI.SetUserProfile("U1", "Ada Lovelace", "ada@example.com")
+
+    // transition, the modal lists the transitions Jira offers
+    interact(t, r, fakes.BlockAction(slackAPI.TeamID, "U1", card.Channel, card.Timestamp, slack.CardBlockID, slack.ActionTransition, card.Key))
+    views := slackAPI.Views()
+    require.Len(t, views, 1)
+    assert.Equal(t, slack.CallbackTransition, views[0].CallbackID)
+    assert.Len(t, views[0].Blocks.BlockSet[0].(*slackgo.InputBlock).Element.(*slackgo.SelectBlockElement).Options, 3)
+
+    rr := interact(t, r, fakes.ViewSubmission(slackAPI.TeamID, "U1", submitted(views[0], "Done", true)))
+    assert.Empty(t, rr.Body.String())
+    assert.Equal(t, "Done", jiraAPI.Issue(card.Key).Fields.Status.Name)
+    assert.Equal(t, "*Status*\nDone", cardField(t, slackAPI, 0))
+
+    // priority
+    interact(t, r, fakes.BlockAction(slackAPI.TeamID, "U1", card.Channel, card.Timestamp, slack.CardBlockID, slack.ActionChangePriority, card.Key))
+    views = slackAPI.Views()
+    require.Len(t, views, 2)
+    assert.Equal(t, slack.CallbackPriority, views[1].CallbackID)
+
+    interact(t, r, fakes.ViewSubmission(slackAPI.TeamID, "U1", submitted(views[1], "High", true)))
+    assert.Equal(t, "*Priority*\nHigh", cardField(t, slackAPI, 2))
+
+    // the modal is closed before Jira rejects the priority, the user is told
+    rr = interact(t, r, fakes.ViewSubmission(slackAPI.TeamID, "U1", submitted(views[1], "Urgent", true)))
+    assert.Empty(t, rr.Body.String())
+    ephemeral := slackAPI.Ephemeral()
+    require.Len(t, ephemeral, 1)
+    assert.Equal(t, "Sorry, " + card.Key + " could not be updated, try again in a moment.", ephemeral[0].Text)
+
+    // comment, written by the integration in the name of the user
+    interact(t, r, fakes.BlockAction(slackAPI.TeamID, "U1", card.Channel, card.Timestamp, slack.CardBlockID, slack.ActionComment, card.Key))
+    views = slackAPI.Views()
+    require.Len(t, views, 3)
+    assert.Equal(t, slack.CallbackComment, views[2].CallbackID)
+
+    interact(t, r, fakes.ViewSubmission(slackAPI.TeamID, "U1", submitted(views[2], "rolled back the deploy", false)))
+    comments := jiraAPI.Issue(card.Key).Fields.Comments.Comments
+    require.Len(t, comments, 1)
+    assert.Equal(t, "Ada Lovelace commented in Slack:\nrolled back the deploy", comments[0].Body)
+    assert.Len(t, slackAPI.Ephemeral(), 1)
+
+    // opening the modals changes nothing, their submissions are audited
+    var actions []string
+    for _, record := range auditRecords(t, sink) {
+        actions = append(actions, record.Action + " " + record.Outcome)
+    }
+    assert.Equal(t, []string{"transition updated", "priority updated", "priority failed", "comment updated"}, actions)
+
+}
+
+func TestSlackInteractionsDenied(t *testing.T) {
+    r, slackAPI, jiraAPI, card, sink := newAuditedCardRuntime(t)
+    r.AccessPolicy = &AccessPolicy{DenyUsers: []string{"U3"}}
+
+    interact(t, r, fakes.BlockAction(slackAPI.TeamID, "U3", card.Channel, card.Timestamp, slack.CardBlockID, slack.ActionTransition, card.Key))
+    assert.Empty(t, slackAPI.Views())
+    ephemeral := slackAPI.Ephemeral()
+    require.Len(t, ephemeral, 1)
+    assert.True(t, strings.HasPrefix(ephemeral[0].Text, "Sorry, "))
+
+    // a modal opened before access was taken away
+    view := slack.SelectModal(slack.CallbackTransition, "Transition", "Move", card, []string{"Done"})
+    rr := interact(t, r, fakes.ViewSubmission(slackAPI.TeamID, "U3", submitted(view, "Done", true)))
+    assert.Empty(t, rr.Body.String())
+    assert.Equal(t, "In Progress", jiraAPI.Issue(card.Key).Fields.Status.Name)
+    ephemeral = slackAPI.Ephemeral()
+    require.Len(t, ephemeral, 2)
+    assert.Equal(t, ephemeral[0].Text, ephemeral[1].Text)
+
+    records := auditRecords(t, sink)
+    require.Len(t, records, 1)
+    assert.Equal(t, "transition", records[0].Action)
+    assert.Equal(t, audit.OutcomeDenied, records[0].Outcome)
+
+    // tickets of backends without card actions
+    r.Backend = &plainBackend{r.Backend}
+    interact(t, r, fakes.BlockAction(slackAPI.TeamID, "U1", card.Channel, card.Timestamp, slack.CardBlockID, slack.ActionChangePriority, card.Key))
+    ephemeral = slackAPI.Ephemeral()
+    require.Len(t, ephemeral, 3)
+    assert.Equal(t, "Sorry, the priority of " + card.Key + " cannot be changed from Slack.", ephemeral[2].Text)
+
+    // are refused while the modal is still open
+    rr = interact(t, r, fakes.ViewSubmission(slackAPI.TeamID, "U1", submitted(view, "Done", true)))
+    var response slackgo.ViewSubmissionResponse
+    require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+    assert.Equal(t, slackgo.RAErrors, response.ResponseAction)
+    assert.Equal(t, "Sorry, " + card.Key + " cannot be transitioned from Slack.", response.Errors[slack.InputBlockID])
+
+}
+
+// plainBackend hides every optional interface of the backend it wraps
+type plainBackend struct {
+    TicketBackend
+}
@@ -0,0 +1,276 @@
+package jira
+
+import (
+    "context"
+    "crypto/rsa"
+    "crypto/x509"
+    "encoding/json"
+    "encoding/pem"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "os"
+    "sync"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/dghubble/oauth1"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+    AuthTypeBasic = "basic"
+    AuthTypePAT = "pat"
+    AuthTypeOAuth2 = "oauth2"
+    AuthTypeOAuth1 = "oauth1"
+)
+
+// AuthConfig selects how requests to Jira are authenticated, Type picks the
+// transport and only the fields relevant to that transport need to be set
+//
+//   basic:  Username, Password (Jira Cloud api token as password)
+//   pat:    Token (Jira Data Center/Server personal access token)
+//   oauth2: ClientID, ClientSecret, TokenURL, Scopes and optionally RefreshToken,
+//           without a RefreshToken the client credentials grant is used.
+//           TokenFile persists refreshed tokens across restarts
+//   oauth1: ConsumerKey, PrivateKeyFile, AccessToken, AccessSecret (legacy Jira Server)
+type AuthConfig struct {
+    Type string
+
+    Username string
+    Password string
+
+    Token string
+
+    ClientID string
+    ClientSecret string
+    TokenURL string
+    RefreshToken string
+    Scopes []string
+    TokenFile string
+
+    ConsumerKey string
+    PrivateKeyFile string
+    AccessToken string
+    AccessSecret string
+}
+
+// NewHTTPClient, construct a http.Client which authenticates every request
+// according to the given AuthConfig
+func NewHTTPClient(auth AuthConfig) (*http.Client, error) {
+    switch auth.Type {
+    case AuthTypeBasic, "":
+        tp := jira.BasicAuthTransport{
+            Username: auth.Username,
+            Password: auth.Password,
+        }
+        return tp.Client(), nil
+
+    case AuthTypePAT:
+        if auth.Token == "" {
+            return nil, fmt.Errorf("pat auth requires a token")
+        }
+        tp := bearerAuthTransport{Token: auth.Token}
+        return tp.Client(), nil
+
+    case AuthTypeOAuth2:
+        return newOAuth2Client(auth)
+
+    case AuthTypeOAuth1:
+        return newOAuth1Client(auth)
+
+    }
+
+    return nil, fmt.Errorf("unknown jira auth type '%s'", auth.Type)
+
+}
+
+// bearerAuthTransport, sets the Authorization header to a Bearer token,
+// used for Jira Data Center personal access tokens
+type bearerAuthTransport struct {
+    Token string
+    Transport http.RoundTripper
+}
+
+func (t *bearerAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    // RoundTrip must not modify the request, so clone it before adding the header
+    req2 := req.Clone(req.Context())
+    req2.Header.Set("Authorization", "Bearer " + t.Token)
+
+    return t.transport().RoundTrip(req2)
+
+}
+
+func (t *bearerAuthTransport) Client() *http.Client {
+    return &http.Client{Transport: t}
+
+}
+
+func (t *bearerAuthTransport) transport() http.RoundTripper {
+    if t.Transport != nil {
+        return t.Transport
+    }
+
+    return http.DefaultTransport
+
+}
+
+// newOAuth2Client, builds an oauth2 client, a refresh token (from TokenFile or
+// RefreshToken) uses the refresh grant, otherwise client credentials are used
+func newOAuth2Client(auth AuthConfig) (*http.Client, error) {
+    ctx := context.Background()
+
+    token, err := loadToken(auth.TokenFile)
+    if err != nil {
+        return nil, err
+    }
+
+    useRefresh := auth.RefreshToken != "" || (token != nil && token.RefreshToken != "")
+    if token == nil && auth.RefreshToken != "" {
+        token = &oauth2.Token{RefreshToken: auth.RefreshToken}
+    }
+
+    var source oauth2.TokenSource
+    if useRefresh {
+        config := &oauth2.Config{
+            ClientID: auth.ClientID,
+            ClientSecret: auth.ClientSecret,
+            Endpoint: oauth2.Endpoint{TokenURL: auth.TokenURL, AuthStyle: oauth2.AuthStyleInParams},
+            Scopes: auth.Scopes,
+        }
+        source = config.TokenSource(ctx, token)
+
+    } else {
+        config := &clientcredentials.Config{
+            ClientID: auth.ClientID,
+            ClientSecret: auth.ClientSecret,
+            TokenURL: auth.TokenURL,
+            Scopes: auth.Scopes,
+        }
+        source = config.TokenSource(ctx)
+
+    }
+
+    if auth.TokenFile != "" {
+        source = &persistingTokenSource{Source: source, Path: auth.TokenFile, last: token}
+    }
+
+    return oauth2.NewClient(ctx, oauth2.ReuseTokenSource(token, source)), nil
+
+}
+
+// persistingTokenSource, writes every newly issued token to Path so rotated
+// refresh tokens survive a restart
+type persistingTokenSource struct {
+    Source oauth2.TokenSource
+    Path string
+
+    mu sync.Mutex
+    last *oauth2.Token
+}
+
+func (p *persistingTokenSource) Token() (*oauth2.Token, error) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    token, err := p.Source.Token()
+    if err != nil {
+        return nil, err
+    }
+
+    if p.last == nil || p.last.AccessToken != token.AccessToken || p.last.RefreshToken != token.RefreshToken {
+        if err := saveToken(p.Path, token); err != nil {
+            return nil, err
+        }
+        p.last = token
+    }
+
+    return token, nil
+
+}
+
+// loadToken, reads a previously persisted token, a missing path or file is not an error
+func loadToken(path string) (*oauth2.Token, error) {
+    if path == "" {
+        return nil, nil
+    }
+
+    bodyBytes, err := ioutil.ReadFile(path)
+    if os.IsNotExist(err) {
+        return nil, nil
+    }
+
+    if err != nil {
+        return nil, fmt.Errorf("read token file err: %w", err)
+    }
+
+    var token oauth2.Token
+    if err := json.Unmarshal(bodyBytes, &token); err != nil {
+        return nil, fmt.Errorf("parse token file err: %w", err)
+    }
+
+    return &token, nil
+
+}
+
+func saveToken(path string, token *oauth2.Token) error {
+    bodyBytes, err := json.Marshal(token)
+    if err != nil {
+        return err
+    }
+
+    // write then rename so a crash never leaves a truncated token behind
+    tmpPath := path + ".tmp"
+    if err := ioutil.WriteFile(tmpPath, bodyBytes, 0600); err != nil {
+        return fmt.Errorf("write token file err: %w", err)
+    }
+
+    return os.Rename(tmpPath, path)
+
+}
+
+// newOAuth1Client, builds a RSA-SHA1 signing client for legacy Jira Server
+// application links
+func newOAuth1Client(auth AuthConfig) (*http.Client, error) {
+    pemBytes, err := ioutil.ReadFile(auth.PrivateKeyFile)
+    if err != nil {
+        return nil, fmt.Errorf("read oauth1 private key err: %w", err)
+    }
+
+    privateKey, err := parsePrivateKey(pemBytes)
+    if err != nil {
+        return nil, err
+    }
+
+    config := &oauth1.Config{
+        ConsumerKey: auth.ConsumerKey,
+        Signer: &oauth1.RSASigner{PrivateKey: privateKey},
+    }
+
+    return config.Client(context.Background(), oauth1.NewToken(auth.AccessToken, auth.AccessSecret)), nil
+
+}
+
+func parsePrivateKey(pemBytes []byte) (*rsa.PrivateKey, error) {
+    block, _ := pem.Decode(pemBytes)
+    if block == nil {
+        return nil, fmt.Errorf("oauth1 private key is not PEM encoded")
+    }
+
+    if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+        return key, nil
+    }
+
+    key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+    if err != nil {
+        return nil, fmt.Errorf("parse oauth1 private key err: %w", err)
+    }
+
+    rsaKey, ok := key.(*rsa.PrivateKey)
+    if !ok {
+        return nil, fmt.Errorf("oauth1 private key is not RSA")
+    }
+
+    return rsaKey, nil
+
+}
@@ -0,0 +1,92 @@
+package jira
+
+import (
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func newAuthorizationEchoServer(t *testing.T) *httptest.Server {
+    server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+        resp.Write([]byte(req.Header.Get("Authorization")))
+    }))
+    t.Cleanup(server.Close)
+
+    return server
+}
+
+func getAuthorization(t *testing.T, client *http.Client, url string) string {
+    resp, err := client.Get(url)
+    assert.Nil(t, err)
+    defer resp.Body.Close()
+
+    buf := make([]byte, 512)
+    n, _ := resp.Body.Read(buf)
+
+    return string(buf[:n])
+}
+
+func TestNewHTTPClientBasic(t *testing.T) {
+    server := newAuthorizationEchoServer(t)
+
+    client, err := NewHTTPClient(AuthConfig{Type: AuthTypeBasic, Username: "some-user", Password: "some-password"})
+
+    assert.Nil(t, err)
+    assert.Equal(t, "Basic c29tZS11c2VyOnNvbWUtcGFzc3dvcmQ=", getAuthorization(t, client, server.URL))
+
+}
+
+func TestNewHTTPClientPAT(t *testing.T) {
+    server := newAuthorizationEchoServer(t)
+
+    client, err := NewHTTPClient(AuthConfig{Type: AuthTypePAT, Token: "some-token"})
+
+    assert.Nil(t, err)
+    assert.Equal(t, "Bearer some-token", getAuthorization(t, client, server.URL))
+
+}
+
+func TestNewHTTPClientOAuth2RefreshPersisted(t *testing.T) {
+    server := newAuthorizationEchoServer(t)
+
+    tokenServer := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+        req.ParseForm()
+        assert.Equal(t, "refresh_token", req.PostForm.Get("grant_type"))
+        assert.Equal(t, "some-refresh-token", req.PostForm.Get("refresh_token"))
+
+        resp.Header().Set("Content-Type", "application/json")
+        fmt.Fprint(resp, `{"access_token":"some-access-token","refresh_token":"rotated-refresh-token","token_type":"Bearer","expires_in":3600}`)
+    }))
+    defer tokenServer.Close()
+
+    tokenFile := filepath.Join(t.TempDir(), "token.json")
+
+    client, err := NewHTTPClient(AuthConfig{
+        Type: AuthTypeOAuth2,
+        ClientID: "some-client-id",
+        ClientSecret: "some-client-secret",
+        TokenURL: tokenServer.URL,
+        RefreshToken: "some-refresh-token",
+        TokenFile: tokenFile,
+    })
+
+    assert.Nil(t, err)
+    assert.Equal(t, "Bearer some-access-token", getAuthorization(t, client, server.URL))
+
+    token, err := loadToken(tokenFile)
+
+    assert.Nil(t, err)
+    assert.Equal(t, "rotated-refresh-token", token.RefreshToken)
+
+}
+
+func TestNewHTTPClientUnknownType(t *testing.T) {
+    _, err := NewHTTPClient(AuthConfig{Type: "some-type"})
+
+    assert.NotNil(t, err)
+
+}
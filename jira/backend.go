@@ -0,0 +1,223 @@
+package jira
+
+import (
+    "context"
+    "fmt"
+    "net/url"
+    "regexp"
+    "strings"
+
+    "slack-jira-integration/chat"
+    "slack-jira-integration/logging"
+)
+
+// the methods below let the runtime use a JiraEnv as its generic ticket
+// backend, tickets are Jira issues identified by their issue key
+
+// CreateTicket, create an issue with the given description, returns its key
+func (j *JiraEnv) CreateTicket(ctx context.Context, description string) (string, error) {
+    issue, err := j.CreateJiraIssue(ctx, description)
+    if err != nil {
+        return "", err
+    }
+
+    return issue.Key, nil
+
+}
+
+// CreateTicketDetails, create an issue with the given description, Jira only
+// answers with the key so the issue is looked up for its initial status,
+// assignee and priority, without them when the lookup fails
+func (j *JiraEnv) CreateTicketDetails(ctx context.Context, description string) (*chat.Ticket, error) {
+    issue, err := j.CreateJiraIssue(ctx, description)
+    if err != nil {
+        return nil, err
+    }
+
+    // the issue exists either way, the card only shows less of it
+    ticket, err := j.GetTicket(ctx, issue.Key)
+    if err != nil {
+        logging.FromContext(ctx).WithError(err).WithField("issue_key", issue.Key).Warn("created issue not looked up")
+        return &chat.Ticket{Key: issue.Key, URL: j.TicketURL(issue.Key), Summary: j.JiraSummary}, nil
+    }
+
+    return ticket, nil
+
+}
+
+// CommentTicket, add a comment to the issue
+func (j *JiraEnv) CommentTicket(ctx context.Context, key string, comment string) error {
+    return j.CommentJiraIssue(ctx, key, comment)
+
+}
+
+// TicketStatus, the name of the issue's current status e.g. "In Progress"
+func (j *JiraEnv) TicketStatus(ctx context.Context, key string) (string, error) {
+    issue, err := j.GetJiraIssue(ctx, key)
+    if err != nil {
+        return "", err
+    }
+
+    if issue.Fields == nil || issue.Fields.Status == nil {
+        return "", fmt.Errorf("issue %s has no status", key)
+    }
+
+    return issue.Fields.Status.Name, nil
+
+}
+
+// GetTicket, the summary, status, assignee and priority of the issue
+func (j *JiraEnv) GetTicket(ctx context.Context, key string) (*chat.Ticket, error) {
+    // the integration's account may read issues of projects it does not serve
+    if keys := j.FindTicketKeys(key); len(keys) != 1 || keys[0] != key {
+        return nil, fmt.Errorf("issue %s is not in project %s", key, j.JiraProject)
+    }
+
+    issue, err := j.GetJiraIssue(ctx, key)
+    if err != nil {
+        return nil, err
+    }
+
+    ticket := &chat.Ticket{Key: issue.Key, URL: j.TicketURL(issue.Key)}
+    if issue.Fields == nil {
+        return ticket, nil
+    }
+
+    ticket.Summary = issue.Fields.Summary
+    if issue.Fields.Status != nil {
+        ticket.Status = issue.Fields.Status.Name
+    }
+    if issue.Fields.Assignee != nil {
+        ticket.Assignee = issue.Fields.Assignee.DisplayName
+    }
+    if issue.Fields.Priority != nil {
+        ticket.Priority = issue.Fields.Priority.Name
+    }
+
+    return ticket, nil
+
+}
+
+// LinkTicket, add a remote link to url titled title to the issue
+func (j *JiraEnv) LinkTicket(ctx context.Context, key string, url string, title string) error {
+    return j.AddJiraRemoteLink(ctx, key, url, title)
+
+}
+
+// UnlinkTicket, remove the remote link to url from the issue
+func (j *JiraEnv) UnlinkTicket(ctx context.Context, key string, url string) error {
+    return j.DeleteJiraRemoteLink(ctx, key, url)
+
+}
+
+// FindTicketKeys, the keys of issues in the configured project mentioned in
+// text, keys of other projects are left alone as text such as "UTF-8"
+// looks like one
+func (j *JiraEnv) FindTicketKeys(text string) []string {
+    pattern := regexp.MustCompile(`\b` + regexp.QuoteMeta(j.JiraProject) + `-[1-9][0-9]*\b`)
+    return pattern.FindAllString(text, -1)
+
+}
+
+// TicketKeyFromURL, the key of the issue link opens on the configured site,
+// browse links and board links selecting an issue are understood, only
+// issues of the configured project are as FindTicketKeys, unfurls would
+// otherwise show any project the integration's account can read
+func (j *JiraEnv) TicketKeyFromURL(link string) (string, bool) {
+    site, err := url.Parse(j.JiraUrl)
+    if err != nil {
+        return "", false
+    }
+
+    parsed, err := url.Parse(link)
+    if err != nil || !strings.EqualFold(parsed.Host, site.Host) {
+        return "", false
+    }
+
+    key := parsed.Query().Get("selectedIssue")
+    browse := strings.TrimSuffix(site.Path, "/") + "/browse/"
+    if strings.HasPrefix(parsed.Path, browse) {
+        key = strings.TrimSuffix(strings.TrimPrefix(parsed.Path, browse), "/")
+    }
+
+    pattern := regexp.MustCompile(`^` + regexp.QuoteMeta(j.JiraProject) + `-[1-9][0-9]*$`)
+    if !pattern.MatchString(key) {
+        return "", false
+    }
+
+    return key, true
+
+}
+
+// TicketURL, the browse link of the issue
+func (j *JiraEnv) TicketURL(key string) string {
+    return fmt.Sprintf("%s/browse/%s", strings.TrimSuffix(j.JiraUrl, "/"), key)
+
+}
+
+// TicketProject, the project new issues are created in
+func (j *JiraEnv) TicketProject() string {
+    return j.JiraProject
+
+}
+
+// DeleteTicket, delete the issue
+func (j *JiraEnv) DeleteTicket(ctx context.Context, key string) error {
+    return j.DeleteJiraIssue(ctx, key)
+
+}
+
+// TransitionTicket, move the issue through the transition, or to the status, named name
+func (j *JiraEnv) TransitionTicket(ctx context.Context, key string, name string) error {
+    return j.TransitionJiraIssue(ctx, key, name)
+
+}
+
+// AssignTicket, assign the issue to the Jira user with the given email address
+func (j *JiraEnv) AssignTicket(ctx context.Context, key string, email string) error {
+    user, err := j.FindJiraUserByEmail(ctx, email)
+    if err != nil {
+        return err
+    }
+
+    return j.AssignJiraIssue(ctx, key, user)
+
+}
+
+// TicketTransitions, the names of the transitions currently available for the issue
+func (j *JiraEnv) TicketTransitions(ctx context.Context, key string) ([]string, error) {
+    transitions, err := j.GetJiraTransitions(ctx, key)
+    if err != nil {
+        return nil, err
+    }
+
+    names := make([]string, 0, len(transitions))
+    for _, transition := range transitions {
+        names = append(names, transition.Name)
+    }
+
+    return names, nil
+
+}
+
+// TicketPriorities, the names of the priorities issues can be given
+func (j *JiraEnv) TicketPriorities(ctx context.Context) ([]string, error) {
+    priorities, err := j.GetJiraPriorities(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    names := make([]string, 0, len(priorities))
+    for _, priority := range priorities {
+        names = append(names, priority.Name)
+    }
+
+    return names, nil
+
+}
+
+// PrioritizeTicket, give the issue the priority named name
+func (j *JiraEnv) PrioritizeTicket(ctx context.Context, key string, name string) error {
+    return j.SetJiraIssuePriority(ctx, key, name)
+
+}
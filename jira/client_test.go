@@ -0,0 +1,173 @@
+package jira
+
+import (
+    "context"
+    "net/http"
+    "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+    "slack-jira-integration/testing/fakes"
+)
+
+// the fake Jira exercises the real jiraClient HTTP wiring and error bodies
+func TestClientAgainstFakeJira(t *testing.T) {
+    fake := fakes.NewJira(t, "OPS")
+
+    client, err := NewClient(fake.URL + "/", AuthConfig{Type: AuthTypePAT, Token: "token"})
+    require.NoError(t, err)
+
+    env, err := NewEnv(client, fake.URL + "/", "OPS", "Escalated", "Task")
+    require.NoError(t, err)
+    assert.Equal(t, fake.AccountID, env.JiraUserAccountID)
+
+    key, err := env.CreateTicket(context.Background(), "the checkout page is down")
+    require.NoError(t, err)
+    assert.Equal(t, "OPS-1", key)
+    assert.Equal(t, fake.URL + "/browse/OPS-1", env.TicketURL(key))
+
+    // with or without a trailing slash on the site URL
+    env.JiraUrl = fake.URL
+    assert.Equal(t, fake.URL + "/browse/OPS-1", env.TicketURL(key))
+    env.JiraUrl = fake.URL + "/"
+
+    created, err := env.CreateTicketDetails(context.Background(), "the cart is empty")
+    require.NoError(t, err)
+    assert.Equal(t, "OPS-2", created.Key)
+    assert.Equal(t, "Escalated", created.Summary)
+    assert.Equal(t, fake.URL + "/browse/OPS-2", created.URL)
+    // looked up for the card's status
+    assert.Equal(t, fake.Issue("OPS-2").Fields.Status.Name, created.Status)
+    assert.NotEmpty(t, created.Status)
+
+    require.NoError(t, env.CommentTicket(context.Background(), "OPS-1", "still down"))
+    assert.Equal(t, "still down", fake.Issue("OPS-1").Fields.Comments.Comments[0].Body)
+
+    status, err := env.TicketStatus(context.Background(), "OPS-1")
+    require.NoError(t, err)
+    assert.Equal(t, "To Do", status)
+
+    require.NoError(t, env.TransitionTicket(context.Background(), "OPS-1", "done"))
+    status, err = env.TicketStatus(context.Background(), "OPS-1")
+    require.NoError(t, err)
+    assert.Equal(t, "Done", status)
+
+    _, err = env.TicketStatus(context.Background(), "OPS-9")
+    assert.ErrorContains(t, err, "Issue does not exist")
+
+    existing := fake.AddIssue("OPS", "checkout latency")
+    ticket, err := env.GetTicket(context.Background(), existing)
+    require.NoError(t, err)
+    assert.Equal(t, "checkout latency", ticket.Summary)
+    assert.Equal(t, "In Progress", ticket.Status)
+    assert.Equal(t, fake.URL + "/browse/" + existing, ticket.URL)
+
+    permalink := "https://acme.slack.com/archives/C0ALERTS/p1641160800000100"
+    require.NoError(t, env.LinkTicket(context.Background(), existing, permalink, "#alerts"))
+    require.NoError(t, env.LinkTicket(context.Background(), existing, permalink, "#alerts thread"))
+    links := fake.RemoteLinks(existing)
+    require.Len(t, links, 1)
+    assert.Equal(t, permalink, links[0].Object.URL)
+    assert.Equal(t, "#alerts thread", links[0].Object.Title)
+    assert.Equal(t, SlackIconURL, links[0].Object.Icon.Url16x16)
+    assert.Equal(t, "com.slack", links[0].Application.Type)
+
+    // only links to Slack get its icon
+    require.NoError(t, env.LinkTicket(context.Background(), existing, "https://chat.example.com/acme/pl/abc", "#alerts"))
+    assert.Nil(t, fake.RemoteLinks(existing)[1].Object.Icon)
+    assert.ErrorContains(t, env.LinkTicket(context.Background(), "OPS-9", permalink, "#alerts"), "Issue does not exist")
+
+    // links are removed by the url they were added with
+    require.NoError(t, env.UnlinkTicket(context.Background(), existing, permalink))
+    links = fake.RemoteLinks(existing)
+    require.Len(t, links, 1)
+    assert.Equal(t, "https://chat.example.com/acme/pl/abc", links[0].Object.URL)
+    assert.ErrorContains(t, env.UnlinkTicket(context.Background(), existing, permalink), "no remote link")
+
+    // card actions, users are found by email address
+    fake.AddUser("ada-account", "Ada Lovelace", "ada@example.com")
+    require.NoError(t, env.AssignTicket(context.Background(), existing, "ADA@example.com"))
+    assert.Equal(t, "ada-account", fake.Issue(existing).Fields.Assignee.AccountID)
+    assert.ErrorContains(t, env.AssignTicket(context.Background(), existing, "bob+ops@example.com"), "no jira user with email 'bob+ops@example.com'")
+
+    // a user hiding their address is only trusted as the only match
+    fake.AddHiddenUser("grace-account", "Grace Hopper", "grace@example.com")
+    require.NoError(t, env.AssignTicket(context.Background(), existing, "grace@example.com"))
+    assert.Equal(t, "grace-account", fake.Issue(existing).Fields.Assignee.AccountID)
+    fake.AddHiddenUser("grace-old-account", "Grace Hopper", "grace@example.com")
+    assert.ErrorContains(t, env.AssignTicket(context.Background(), existing, "grace@example.com"), "2 jira users match email 'grace@example.com'")
+
+    transitions, err := env.TicketTransitions(context.Background(), existing)
+    require.NoError(t, err)
+    assert.Equal(t, []string{"Start Progress", "Done", "Won't Do"}, transitions)
+
+    priorities, err := env.TicketPriorities(context.Background())
+    require.NoError(t, err)
+    assert.Equal(t, []string{"High", "Medium", "Low"}, priorities)
+    require.NoError(t, env.PrioritizeTicket(context.Background(), existing, "High"))
+    assert.ErrorContains(t, env.PrioritizeTicket(context.Background(), existing, "Urgent"), "Specify the Priority")
+
+    ticket, err = env.GetTicket(context.Background(), existing)
+    require.NoError(t, err)
+    assert.Equal(t, "Grace Hopper", ticket.Assignee)
+    assert.Equal(t, "High", ticket.Priority)
+
+    // issues of other projects are not looked up
+    _, err = env.GetTicket(context.Background(), "DATA-41")
+    assert.ErrorContains(t, err, "issue DATA-41 is not in project OPS")
+
+    for link, want := range map[string]string{
+        fake.URL + "/browse/OPS-2": "OPS-2",
+        fake.URL + "/browse/OPS-41/": "OPS-41",
+        fake.URL + "/browse/DATA-41": "",
+        fake.URL + "/browse/XOPS-41": "",
+        fake.URL + "/jira/software/projects/OPS/boards/1?selectedIssue=OPS-3": "OPS-3",
+        fake.URL + "/browse/ops-2": "",
+        fake.URL + "/browse/OPS-2/comments": "",
+        "https://other.example.com/browse/OPS-2": "",
+    } {
+        key, ok := env.TicketKeyFromURL(link)
+        assert.Equal(t, want, key, link)
+        assert.Equal(t, want != "", ok, link)
+    }
+
+    assert.Equal(t, []string{"OPS-2", "OPS-12"}, env.FindTicketKeys("see OPS-2 and OPS-12, not XOPS-3, OPS-0 or UTF-8"))
+
+    require.NoError(t, env.DeleteJiraIssue(context.Background(), "OPS-1"))
+    assert.Nil(t, fake.Issue("OPS-1"))
+
+    env.JiraProject = "NOPE"
+    _, err = env.CreateJiraIssue(context.Background(), "the checkout page is down")
+    assert.ErrorContains(t, err, "valid project is required")
+
+    fake.Fail("deleteIssue", http.StatusForbidden)
+    assert.ErrorContains(t, env.DeleteJiraIssue(context.Background(), "OPS-1"), "injected deleteIssue failure")
+
+}
+
+// Server and Data Center users have no account ids, they are searched and
+// assigned by name
+func TestClientAgainstFakeJiraServer(t *testing.T) {
+    fake := fakes.NewJira(t, "OPS")
+    fake.ServerDC = true
+    fake.AddUser("ada", "Ada Lovelace", "ada@example.com")
+
+    client, err := NewClient(fake.URL + "/", AuthConfig{Type: AuthTypePAT, Token: "token"})
+    require.NoError(t, err)
+
+    env, err := NewEnv(client, fake.URL + "/", "OPS", "Escalated", "Task")
+    require.NoError(t, err)
+    assert.True(t, env.ServerDC)
+
+    key, err := env.CreateTicket(context.Background(), "the checkout page is down")
+    require.NoError(t, err)
+
+    require.NoError(t, env.AssignTicket(context.Background(), key, "ada@example.com"))
+    assert.Equal(t, "ada", fake.Issue(key).Fields.Assignee.Name)
+
+    // Cloud's query= is refused by the fake as it is by Server
+    env.ServerDC = false
+    assert.ErrorContains(t, env.AssignTicket(context.Background(), key, "ada@example.com"), "'username' query parameter is required")
+
+}
@@ -5,6 +5,12 @@ import (
     "io/ioutil"
 	"github.com/andygrunwald/go-jira"
     "fmt"
+    "net/url"
+    "strings"
+
+    "slack-jira-integration/logging"
+    "slack-jira-integration/metrics"
+    "slack-jira-integration/tracing"
 )
 
 // JiraEnv is the Dependency Injection(DI) for jira object allowing environment
@@ -16,40 +22,153 @@ type JiraEnv struct {
     JiraSummary string
     JiraIssueType string
     JiraUserAccountID string
+    // ServerDC is set for Jira Server and Data Center, found by the bot's
+    // user having no account id, they search and assign users by name
+    ServerDC bool
 }
 
 // Jiraer is an interface for testing purposes wrapping the concrete jira.client
 // the JiraEnv takes a Jiraer which is either the jiraClient struct below or a 
 // MockJiraClient from jira_test
 type Jiraer interface {
-    getSelf() (*jira.User, *jira.Response, error)
-    createIssue(*jira.Issue) (*jira.Issue, *jira.Response, error)
+    getSelf(context.Context) (*jira.User, *jira.Response, error)
+    createIssue(context.Context, *jira.Issue) (*jira.Issue, *jira.Response, error)
+    deleteIssue(context.Context, string) (*jira.Response, error)
+    getTransitions(context.Context, string) ([]jira.Transition, *jira.Response, error)
+    doTransition(context.Context, string, string) (*jira.Response, error)
+    addComment(context.Context, string, *jira.Comment) (*jira.Comment, *jira.Response, error)
+    getIssue(context.Context, string) (*jira.Issue, *jira.Response, error)
+    addRemoteLink(context.Context, string, *jira.RemoteLink) (*jira.RemoteLink, *jira.Response, error)
+    deleteRemoteLink(context.Context, string, string) (*jira.Response, error)
+    findUsers(context.Context, string, string) ([]jira.User, *jira.Response, error)
+    updateAssignee(context.Context, string, *jira.User) (*jira.Response, error)
+    getPriorities(context.Context) ([]jira.Priority, *jira.Response, error)
+    updateIssue(context.Context, string, map[string]interface{}) (*jira.Response, error)
 }
 
 type jiraClient struct{
-    Context context.Context
     Client *jira.Client
 }
 
-// NewClient, construct a newClient which implements Jiraer 
-func NewClient(jiraUrl string, username string, password string) Jiraer{
-    tp := jira.BasicAuthTransport{
-        Username: username,
-        Password: password,
+// NewClient, construct a newClient which implements Jiraer, authenticating
+// with the transport selected by auth
+func NewClient(jiraUrl string, auth AuthConfig) (Jiraer, error) {
+    httpClient, err := NewHTTPClient(auth)
+    if err != nil {
+        return nil, err
+    }
+    httpClient.Transport = tracing.InstrumentRoundTripper(metrics.InstrumentRoundTripper("jira", httpClient.Transport))
+
+    client, err := jira.NewClient(httpClient, jiraUrl)
+    if err != nil {
+        return nil, err
     }
 
-    client, _ := jira.NewClient(tp.Client(), jiraUrl)
-    return &jiraClient{Context: context.Background(), Client: client}
+    return &jiraClient{Client: client}, nil
+
+}
+
+// logCall, debug log every Jira API call with the correlation fields carried by ctx
+func logCall(ctx context.Context, method string) {
+    logging.FromContext(ctx).WithField("jira_method", method).Debug("jira api call")
+
+}
+
+func (j *jiraClient) createIssue(ctx context.Context, issue *jira.Issue) (*jira.Issue, *jira.Response, error) {
+    logCall(ctx, "issue.create")
+    return j.Client.Issue.CreateWithContext(ctx, issue)
 
 }
 
-func (j *jiraClient) createIssue(issue *jira.Issue) (*jira.Issue, *jira.Response, error) {
-    return j.Client.Issue.CreateWithContext(j.Context, issue)
+func (j *jiraClient) deleteIssue(ctx context.Context, issueKey string) (*jira.Response, error) {
+    logCall(ctx, "issue.delete")
+    return j.Client.Issue.DeleteWithContext(ctx, issueKey)
+
+}
+
+func (j *jiraClient) getTransitions(ctx context.Context, issueKey string) ([]jira.Transition, *jira.Response, error) {
+    logCall(ctx, "issue.transitions")
+    return j.Client.Issue.GetTransitionsWithContext(ctx, issueKey)
+
+}
+
+func (j *jiraClient) doTransition(ctx context.Context, issueKey string, transitionID string) (*jira.Response, error) {
+    logCall(ctx, "issue.transition")
+    return j.Client.Issue.DoTransitionWithContext(ctx, issueKey, transitionID)
+
+}
+
+func (j *jiraClient) addComment(ctx context.Context, issueKey string, comment *jira.Comment) (*jira.Comment, *jira.Response, error) {
+    logCall(ctx, "issue.comment")
+    return j.Client.Issue.AddCommentWithContext(ctx, issueKey, comment)
+
+}
+
+func (j *jiraClient) getIssue(ctx context.Context, issueKey string) (*jira.Issue, *jira.Response, error) {
+    logCall(ctx, "issue.get")
+    return j.Client.Issue.GetWithContext(ctx, issueKey, nil)
+
+}
+
+func (j *jiraClient) addRemoteLink(ctx context.Context, issueKey string, remoteLink *jira.RemoteLink) (*jira.RemoteLink, *jira.Response, error) {
+    logCall(ctx, "issue.remotelink")
+    return j.Client.Issue.AddRemoteLinkWithContext(ctx, issueKey, remoteLink)
+
+}
+
+func (j *jiraClient) deleteRemoteLink(ctx context.Context, issueKey string, globalID string) (*jira.Response, error) {
+    logCall(ctx, "issue.remotelink.delete")
+
+    // go-jira has no call for it, remote links are deleted by their global id
+    req, err := j.Client.NewRequestWithContext(ctx, "DELETE", fmt.Sprintf("rest/api/2/issue/%s/remotelink?globalId=%s", issueKey, url.QueryEscape(globalID)), nil)
+    if err != nil {
+        return nil, err
+    }
+
+    return j.Client.Do(req, nil)
+
+}
+
+func (j *jiraClient) findUsers(ctx context.Context, param string, query string) ([]jira.User, *jira.Response, error) {
+    logCall(ctx, "user.search")
+
+    // go-jira only searches with query= as Jira Cloud does
+    req, err := j.Client.NewRequestWithContext(ctx, "GET", "rest/api/2/user/search?" + url.Values{param: {query}}.Encode(), nil)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    users := []jira.User{}
+    resp, err := j.Client.Do(req, &users)
+    if err != nil {
+        return nil, resp, jira.NewJiraError(resp, err)
+    }
+
+    return users, resp, nil
 
 }
 
-func (j *jiraClient) getSelf() (*jira.User, *jira.Response, error) {
-    return j.Client.User.GetSelf()
+func (j *jiraClient) updateAssignee(ctx context.Context, issueKey string, assignee *jira.User) (*jira.Response, error) {
+    logCall(ctx, "issue.assignee")
+    return j.Client.Issue.UpdateAssigneeWithContext(ctx, issueKey, assignee)
+
+}
+
+func (j *jiraClient) getPriorities(ctx context.Context) ([]jira.Priority, *jira.Response, error) {
+    logCall(ctx, "priority")
+    return j.Client.Priority.GetListWithContext(ctx)
+
+}
+
+func (j *jiraClient) updateIssue(ctx context.Context, issueKey string, data map[string]interface{}) (*jira.Response, error) {
+    logCall(ctx, "issue.update")
+    return j.Client.Issue.UpdateIssueWithContext(ctx, issueKey, data)
+
+}
+
+func (j *jiraClient) getSelf(ctx context.Context) (*jira.User, *jira.Response, error) {
+    logCall(ctx, "myself")
+    return j.Client.User.GetSelfWithContext(ctx)
 
 }
 
@@ -58,29 +177,59 @@ func (j *JiraEnv) setAccountID(accountID string) *JiraEnv {
     return j
 }
 
-func NewEnv(client Jiraer, jiraProject string, jiraSummary string, jiraIssueType string) (*JiraEnv, error) {
+func NewEnv(client Jiraer, jiraUrl string, jiraProject string, jiraSummary string, jiraIssueType string) (*JiraEnv, error) {
     env := &JiraEnv{
         JiraClient: client,
+        JiraUrl: jiraUrl,
         JiraProject: jiraProject,
         JiraSummary: jiraSummary,
         JiraIssueType: jiraIssueType,
     }
 
-    jiraUser, resp, err := env.JiraClient.getSelf()
+    jiraUser, resp, err := env.JiraClient.getSelf(context.Background())
 
     if err != nil {
-        bodyBytes, _ := ioutil.ReadAll(resp.Body)
-        return nil, fmt.Errorf("get self err: %s", bodyBytes)
+        return nil, responseError("get self", resp, err)
 
     }
 
+    env.ServerDC = jiraUser.AccountID == ""
     return env.setAccountID(jiraUser.AccountID), nil
 
 }
 
+// Ping, verify Jira is reachable and the credentials are still accepted, used
+// by readiness checks
+func (j *JiraEnv) Ping(ctx context.Context) error {
+    _, resp, err := j.JiraClient.getSelf(ctx)
+
+    if err != nil {
+        return responseError("get self", resp, err)
+    }
+
+    return nil
+
+}
+
+// responseError, prefer the Jira error body when there is a response
+func responseError(action string, resp *jira.Response, err error) error {
+    if resp == nil || resp.Body == nil {
+        return fmt.Errorf("%s err: %w", action, err)
+    }
+
+    // some go-jira calls have already read the body into err
+    bodyBytes, _ := ioutil.ReadAll(resp.Body)
+    if len(bodyBytes) == 0 {
+        return fmt.Errorf("%s err: %w", action, err)
+    }
+
+    return fmt.Errorf("%s err: %s", action, bodyBytes)
+
+}
+
 // CreateJiraIssue, given the description create a Jira issue with the
 // summary, accountID, issue type, project from the JiraEnv
-func (j *JiraEnv) CreateJiraIssue(description string) (*jira.Issue, error) {
+func (j *JiraEnv) CreateJiraIssue(ctx context.Context, description string) (*jira.Issue, error) {
     fields := &jira.IssueFields{
         Reporter: &jira.User{
             AccountID: j.JiraUserAccountID,
@@ -99,14 +248,224 @@ func (j *JiraEnv) CreateJiraIssue(description string) (*jira.Issue, error) {
         Fields: fields, 
     }
 
-    createdIssue, resp, err := j.JiraClient.createIssue(issue) 
+    createdIssue, resp, err := j.JiraClient.createIssue(ctx, issue) 
 
     if err != nil {
-      bodyBytes, _ := ioutil.ReadAll(resp.Body)
-      return nil, fmt.Errorf("get self err: %s", bodyBytes)
+      return nil, responseError("create issue", resp, err)
 
     }
 
     return createdIssue, nil
 
 }
+
+// DeleteJiraIssue, delete the issue with the given key
+func (j *JiraEnv) DeleteJiraIssue(ctx context.Context, issueKey string) error {
+    resp, err := j.JiraClient.deleteIssue(ctx, issueKey)
+
+    if err != nil {
+        return responseError("delete issue", resp, err)
+    }
+
+    return nil
+
+}
+
+// TransitionJiraIssue, move the issue through the transition named name, or
+// leading to the status named name, matched case-insensitively
+func (j *JiraEnv) TransitionJiraIssue(ctx context.Context, issueKey string, name string) error {
+    transitions, resp, err := j.JiraClient.getTransitions(ctx, issueKey)
+
+    if err != nil {
+        return responseError("get transitions", resp, err)
+    }
+
+    for _, transition := range transitions {
+        if !strings.EqualFold(transition.Name, name) && !strings.EqualFold(transition.To.Name, name) {
+            continue
+        }
+
+        resp, err = j.JiraClient.doTransition(ctx, issueKey, transition.ID)
+        if err != nil {
+            return responseError("transition issue", resp, err)
+        }
+
+        return nil
+    }
+
+    return fmt.Errorf("no transition '%s' for issue %s", name, issueKey)
+
+}
+
+// CommentJiraIssue, add a comment with the given body to the issue
+func (j *JiraEnv) CommentJiraIssue(ctx context.Context, issueKey string, body string) error {
+    _, resp, err := j.JiraClient.addComment(ctx, issueKey, &jira.Comment{Body: body})
+
+    if err != nil {
+        return responseError("comment issue", resp, err)
+    }
+
+    return nil
+
+}
+
+// GetJiraTransitions, the transitions currently available for the issue
+func (j *JiraEnv) GetJiraTransitions(ctx context.Context, issueKey string) ([]jira.Transition, error) {
+    transitions, resp, err := j.JiraClient.getTransitions(ctx, issueKey)
+
+    if err != nil {
+        return nil, responseError("get transitions", resp, err)
+    }
+
+    return transitions, nil
+
+}
+
+// FindJiraUserByEmail, the Jira user with the given email address, an error
+// when there is none or the match is ambiguous
+func (j *JiraEnv) FindJiraUserByEmail(ctx context.Context, email string) (*jira.User, error) {
+    // Cloud searches with query=, Server and Data Center with username= which
+    // matches email addresses as well
+    param := "query"
+    if j.ServerDC {
+        param = "username"
+    }
+
+    users, resp, err := j.JiraClient.findUsers(ctx, param, email)
+
+    if err != nil {
+        return nil, responseError("find user", resp, err)
+    }
+
+    hidden := 0
+    for _, user := range users {
+        if strings.EqualFold(user.EmailAddress, email) {
+            return &user, nil
+        }
+
+        if user.EmailAddress == "" {
+            hidden++
+        }
+    }
+
+    // Jira hides the addresses of users who chose to, the search matched
+    // them by address but a hidden one is only trusted as the only result
+    if len(users) == 1 && hidden == 1 {
+        return &users[0], nil
+    }
+
+    if hidden > 0 {
+        return nil, fmt.Errorf("%d jira users match email '%s' without showing it", hidden, email)
+    }
+
+    return nil, fmt.Errorf("no jira user with email '%s'", email)
+
+}
+
+// AssignJiraIssue, make user the assignee of the issue, Cloud identifies
+// users by account id and Server and Data Center by name
+func (j *JiraEnv) AssignJiraIssue(ctx context.Context, issueKey string, user *jira.User) error {
+    resp, err := j.JiraClient.updateAssignee(ctx, issueKey, &jira.User{AccountID: user.AccountID, Name: user.Name})
+
+    if err != nil {
+        return responseError("assign issue", resp, err)
+    }
+
+    return nil
+
+}
+
+// GetJiraPriorities, the priorities issues can be given
+func (j *JiraEnv) GetJiraPriorities(ctx context.Context) ([]jira.Priority, error) {
+    priorities, resp, err := j.JiraClient.getPriorities(ctx)
+
+    if err != nil {
+        return nil, responseError("get priorities", resp, err)
+    }
+
+    return priorities, nil
+
+}
+
+// SetJiraIssuePriority, give the issue the priority named name
+func (j *JiraEnv) SetJiraIssuePriority(ctx context.Context, issueKey string, name string) error {
+    data := map[string]interface{}{
+        "fields": map[string]interface{}{
+            "priority": map[string]string{"name": name},
+        },
+    }
+
+    resp, err := j.JiraClient.updateIssue(ctx, issueKey, data)
+
+    if err != nil {
+        return responseError("set priority", resp, err)
+    }
+
+    return nil
+
+}
+
+// GetJiraIssue, fetch the issue with the given key
+func (j *JiraEnv) GetJiraIssue(ctx context.Context, issueKey string) (*jira.Issue, error) {
+    issue, resp, err := j.JiraClient.getIssue(ctx, issueKey)
+
+    if err != nil {
+        return nil, responseError("get issue", resp, err)
+    }
+
+    return issue, nil
+
+}
+
+// SlackIconURL is the icon shown next to remote links to Slack
+const SlackIconURL = "https://slack.com/favicon.ico"
+
+// remoteLinkApplication, the application and icon Jira shows a remote link
+// with, links to Slack get Slack's, any other link neither
+func remoteLinkApplication(link string) (*jira.RemoteLinkApplication, *jira.RemoteLinkIcon) {
+    parsed, err := url.Parse(link)
+    if err != nil {
+        return nil, nil
+    }
+
+    host := parsed.Hostname()
+    if host != "slack.com" && !strings.HasSuffix(host, ".slack.com") {
+        return nil, nil
+    }
+
+    return &jira.RemoteLinkApplication{Type: "com.slack", Name: "Slack"}, &jira.RemoteLinkIcon{Url16x16: SlackIconURL, Title: "Slack"}
+
+}
+
+// AddJiraRemoteLink, link the issue to link under the given title, the link is
+// also the remote link's global id so linking it again updates the remote link
+func (j *JiraEnv) AddJiraRemoteLink(ctx context.Context, issueKey string, link string, title string) error {
+    application, icon := remoteLinkApplication(link)
+    remoteLink := &jira.RemoteLink{
+        GlobalID: link,
+        Application: application,
+        Object: &jira.RemoteLinkObject{URL: link, Title: title, Icon: icon},
+    }
+
+    _, resp, err := j.JiraClient.addRemoteLink(ctx, issueKey, remoteLink)
+
+    if err != nil {
+        return responseError("add remote link", resp, err)
+    }
+
+    return nil
+
+}
+
+// DeleteJiraRemoteLink, remove the remote link to link added by
+// AddJiraRemoteLink from the issue
+func (j *JiraEnv) DeleteJiraRemoteLink(ctx context.Context, issueKey string, link string) error {
+    resp, err := j.JiraClient.deleteRemoteLink(ctx, issueKey, link)
+
+    if err != nil {
+        return responseError("delete remote link", resp, err)
+    }
+
+    return nil
+
+}
@@ -5,6 +5,7 @@
 package jira
 
 import (
+	context "context"
 	reflect "reflect"
 
 	jira "github.com/andygrunwald/go-jira"
@@ -34,10 +35,42 @@ func (m *MockJiraer) EXPECT() *MockJiraerMockRecorder {
 	return m.recorder
 }
 
+// addComment mocks base method.
+func (m *MockJiraer) addComment(arg0 context.Context, arg1 string, arg2 *jira.Comment) (*jira.Comment, *jira.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "addComment", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*jira.Comment)
+	ret1, _ := ret[1].(*jira.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// addComment indicates an expected call of addComment.
+func (mr *MockJiraerMockRecorder) addComment(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "addComment", reflect.TypeOf((*MockJiraer)(nil).addComment), arg0, arg1, arg2)
+}
+
+// addRemoteLink mocks base method.
+func (m *MockJiraer) addRemoteLink(arg0 context.Context, arg1 string, arg2 *jira.RemoteLink) (*jira.RemoteLink, *jira.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "addRemoteLink", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*jira.RemoteLink)
+	ret1, _ := ret[1].(*jira.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// addRemoteLink indicates an expected call of addRemoteLink.
+func (mr *MockJiraerMockRecorder) addRemoteLink(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "addRemoteLink", reflect.TypeOf((*MockJiraer)(nil).addRemoteLink), arg0, arg1, arg2)
+}
+
 // createIssue mocks base method.
-func (m *MockJiraer) createIssue(arg0 *jira.Issue) (*jira.Issue, *jira.Response, error) {
+func (m *MockJiraer) createIssue(arg0 context.Context, arg1 *jira.Issue) (*jira.Issue, *jira.Response, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "createIssue", arg0)
+	ret := m.ctrl.Call(m, "createIssue", arg0, arg1)
 	ret0, _ := ret[0].(*jira.Issue)
 	ret1, _ := ret[1].(*jira.Response)
 	ret2, _ := ret[2].(error)
@@ -45,15 +78,108 @@ func (m *MockJiraer) createIssue(arg0 *jira.Issue) (*jira.Issue, *jira.Response,
 }
 
 // createIssue indicates an expected call of createIssue.
-func (mr *MockJiraerMockRecorder) createIssue(arg0 interface{}) *gomock.Call {
+func (mr *MockJiraerMockRecorder) createIssue(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "createIssue", reflect.TypeOf((*MockJiraer)(nil).createIssue), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "createIssue", reflect.TypeOf((*MockJiraer)(nil).createIssue), arg0, arg1)
+}
+
+// deleteIssue mocks base method.
+func (m *MockJiraer) deleteIssue(arg0 context.Context, arg1 string) (*jira.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "deleteIssue", arg0, arg1)
+	ret0, _ := ret[0].(*jira.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// deleteIssue indicates an expected call of deleteIssue.
+func (mr *MockJiraerMockRecorder) deleteIssue(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "deleteIssue", reflect.TypeOf((*MockJiraer)(nil).deleteIssue), arg0, arg1)
+}
+
+// deleteRemoteLink mocks base method.
+func (m *MockJiraer) deleteRemoteLink(arg0 context.Context, arg1, arg2 string) (*jira.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "deleteRemoteLink", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*jira.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// deleteRemoteLink indicates an expected call of deleteRemoteLink.
+func (mr *MockJiraerMockRecorder) deleteRemoteLink(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "deleteRemoteLink", reflect.TypeOf((*MockJiraer)(nil).deleteRemoteLink), arg0, arg1, arg2)
+}
+
+// doTransition mocks base method.
+func (m *MockJiraer) doTransition(arg0 context.Context, arg1, arg2 string) (*jira.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "doTransition", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*jira.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// doTransition indicates an expected call of doTransition.
+func (mr *MockJiraerMockRecorder) doTransition(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "doTransition", reflect.TypeOf((*MockJiraer)(nil).doTransition), arg0, arg1, arg2)
+}
+
+// findUsers mocks base method.
+func (m *MockJiraer) findUsers(arg0 context.Context, arg1, arg2 string) ([]jira.User, *jira.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "findUsers", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]jira.User)
+	ret1, _ := ret[1].(*jira.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// findUsers indicates an expected call of findUsers.
+func (mr *MockJiraerMockRecorder) findUsers(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "findUsers", reflect.TypeOf((*MockJiraer)(nil).findUsers), arg0, arg1, arg2)
+}
+
+// getIssue mocks base method.
+func (m *MockJiraer) getIssue(arg0 context.Context, arg1 string) (*jira.Issue, *jira.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getIssue", arg0, arg1)
+	ret0, _ := ret[0].(*jira.Issue)
+	ret1, _ := ret[1].(*jira.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// getIssue indicates an expected call of getIssue.
+func (mr *MockJiraerMockRecorder) getIssue(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getIssue", reflect.TypeOf((*MockJiraer)(nil).getIssue), arg0, arg1)
+}
+
+// getPriorities mocks base method.
+func (m *MockJiraer) getPriorities(arg0 context.Context) ([]jira.Priority, *jira.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getPriorities", arg0)
+	ret0, _ := ret[0].([]jira.Priority)
+	ret1, _ := ret[1].(*jira.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// getPriorities indicates an expected call of getPriorities.
+func (mr *MockJiraerMockRecorder) getPriorities(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getPriorities", reflect.TypeOf((*MockJiraer)(nil).getPriorities), arg0)
 }
 
 // getSelf mocks base method.
-func (m *MockJiraer) getSelf() (*jira.User, *jira.Response, error) {
+func (m *MockJiraer) getSelf(arg0 context.Context) (*jira.User, *jira.Response, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "getSelf")
+	ret := m.ctrl.Call(m, "getSelf", arg0)
 	ret0, _ := ret[0].(*jira.User)
 	ret1, _ := ret[1].(*jira.Response)
 	ret2, _ := ret[2].(error)
@@ -61,7 +187,53 @@ func (m *MockJiraer) getSelf() (*jira.User, *jira.Response, error) {
 }
 
 // getSelf indicates an expected call of getSelf.
-func (mr *MockJiraerMockRecorder) getSelf() *gomock.Call {
+func (mr *MockJiraerMockRecorder) getSelf(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getSelf", reflect.TypeOf((*MockJiraer)(nil).getSelf), arg0)
+}
+
+// getTransitions mocks base method.
+func (m *MockJiraer) getTransitions(arg0 context.Context, arg1 string) ([]jira.Transition, *jira.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getTransitions", arg0, arg1)
+	ret0, _ := ret[0].([]jira.Transition)
+	ret1, _ := ret[1].(*jira.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// getTransitions indicates an expected call of getTransitions.
+func (mr *MockJiraerMockRecorder) getTransitions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getTransitions", reflect.TypeOf((*MockJiraer)(nil).getTransitions), arg0, arg1)
+}
+
+// updateAssignee mocks base method.
+func (m *MockJiraer) updateAssignee(arg0 context.Context, arg1 string, arg2 *jira.User) (*jira.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "updateAssignee", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*jira.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// updateAssignee indicates an expected call of updateAssignee.
+func (mr *MockJiraerMockRecorder) updateAssignee(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "updateAssignee", reflect.TypeOf((*MockJiraer)(nil).updateAssignee), arg0, arg1, arg2)
+}
+
+// updateIssue mocks base method.
+func (m *MockJiraer) updateIssue(arg0 context.Context, arg1 string, arg2 map[string]interface{}) (*jira.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "updateIssue", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*jira.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// updateIssue indicates an expected call of updateIssue.
+func (mr *MockJiraerMockRecorder) updateIssue(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getSelf", reflect.TypeOf((*MockJiraer)(nil).getSelf))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "updateIssue", reflect.TypeOf((*MockJiraer)(nil).updateIssue), arg0, arg1, arg2)
 }
@@ -1,6 +1,8 @@
 package jira
 
 import (
+	"context"
+	"fmt"
 	"testing"
 
 	"github.com/andygrunwald/go-jira"
@@ -22,14 +24,15 @@ func TestNewEnv(t *testing.T) {
     expectedUser := jira.User{AccountID: "some-account-id"}
     expectedEnv := JiraEnv{
         JiraClient: mockClient, 
+        JiraUrl: "https://some-site.atlassian.net/",
         JiraProject: "Some Project", 
         JiraSummary: "some summary", 
         JiraIssueType: "someIssueType", 
         JiraUserAccountID: "some-account-id",
     }
-    mockClient.EXPECT().getSelf().Times(1).Return(&expectedUser, nil, nil)
+    mockClient.EXPECT().getSelf(gomock.Any()).Times(1).Return(&expectedUser, nil, nil)
 
-    newEnv, err := NewEnv(mockClient,  "Some Project", "some summary", "someIssueType")
+    newEnv, err := NewEnv(mockClient, "https://some-site.atlassian.net/", "Some Project", "some summary", "someIssueType")
 
     assert.True(t, err == nil)
     assert.EqualValues(t, newEnv,&expectedEnv)
@@ -64,11 +67,53 @@ func TestCreateJiraIssue(t *testing.T) {
         Fields: expectedFields, 
     }
 
-    mockClient.EXPECT().createIssue(expectedIssue).Times(1).Return(expectedIssue, nil, nil)
+    mockClient.EXPECT().createIssue(gomock.Any(), expectedIssue).Times(1).Return(expectedIssue, nil, nil)
 
-    issue, err := env.CreateJiraIssue("some description")
+    issue, err := env.CreateJiraIssue(context.Background(), "some description")
 
     assert.True(t, err == nil)
     assert.EqualValues(t, issue, expectedIssue)
 
 }
+
+func TestPing(t *testing.T) {
+    mockClient := newMockJiraer(t)
+    env := JiraEnv{JiraClient: mockClient}
+
+    mockClient.EXPECT().getSelf(gomock.Any()).Times(1).Return(nil, nil, fmt.Errorf("some error"))
+
+    err := env.Ping(context.Background())
+
+    assert.EqualError(t, err, "get self err: some error")
+
+}
+
+func TestDeleteJiraIssue(t *testing.T) {
+    mockClient := newMockJiraer(t)
+    env := JiraEnv{JiraClient: mockClient}
+
+    mockClient.EXPECT().deleteIssue(gomock.Any(), "TEST-1").Times(1).Return(nil, nil)
+
+    assert.Nil(t, env.DeleteJiraIssue(context.Background(), "TEST-1"))
+
+}
+
+func TestTransitionJiraIssue(t *testing.T) {
+    mockClient := newMockJiraer(t)
+    env := JiraEnv{JiraClient: mockClient}
+
+    transitions := []jira.Transition{
+        {ID: "31", Name: "Done", To: jira.Status{Name: "Done"}},
+        {ID: "41", Name: "Decline", To: jira.Status{Name: "Won't Do"}},
+    }
+
+    mockClient.EXPECT().getTransitions(gomock.Any(), "TEST-1").Times(2).Return(transitions, nil, nil)
+    mockClient.EXPECT().doTransition(gomock.Any(), "TEST-1", "41").Times(1).Return(nil, nil)
+
+    // matched by the status the transition leads to
+    assert.Nil(t, env.TransitionJiraIssue(context.Background(), "TEST-1", "won't do"))
+
+    err := env.TransitionJiraIssue(context.Background(), "TEST-1", "Reopen")
+    assert.EqualError(t, err, "no transition 'Reopen' for issue TEST-1")
+
+}
@@ -0,0 +1,433 @@
+package jira
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "html/template"
+    "io/ioutil"
+    "net/http"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// StubAccountID is the reporter of every issue created in the stub
+const StubAccountID = "local-stub"
+
+// stubTransitions are offered for every stub issue
+var stubTransitions = []jira.Transition{
+    {ID: "11", Name: "Start Progress", To: jira.Status{Name: "In Progress"}},
+    {ID: "21", Name: "Done", To: jira.Status{Name: "Done"}},
+    {ID: "31", Name: "Won't Do", To: jira.Status{Name: "Won't Do"}},
+    {ID: "41", Name: "Reopen", To: jira.Status{Name: "To Do"}},
+}
+
+// stubPriorities can be given to stub issues
+var stubPriorities = []jira.Priority{
+    {ID: "1", Name: "Highest"},
+    {ID: "2", Name: "High"},
+    {ID: "3", Name: "Medium"},
+    {ID: "4", Name: "Low"},
+    {ID: "5", Name: "Lowest"},
+}
+
+// stubStore is what the stub persists between restarts
+type stubStore struct {
+    Counters map[string]int `json:"counters"`
+    // LastID, the id of the newest issue, ids are not reused after a delete
+    LastID int `json:"lastId"`
+    Issues map[string]*jira.Issue `json:"issues"`
+    RemoteLinks map[string][]jira.RemoteLink `json:"remoteLinks,omitempty"`
+}
+
+// StubClient implements Jiraer without a Jira site for local development,
+// issues get incrementing keys per project and are kept in a JSON file, it
+// also serves the browse page the posted links point at
+type StubClient struct {
+    mu sync.Mutex
+    path string
+    store stubStore
+}
+
+// NewStubClient, construct a StubClient persisting to the file at path,
+// loading the issues already there, an empty path keeps issues in memory
+func NewStubClient(path string) (*StubClient, error) {
+    stub := &StubClient{
+        path: path,
+        store: stubStore{Counters: make(map[string]int), Issues: make(map[string]*jira.Issue)},
+    }
+
+    if path == "" {
+        return stub, nil
+    }
+
+    bodyBytes, err := ioutil.ReadFile(path)
+    if os.IsNotExist(err) {
+        return stub, nil
+    }
+
+    if err != nil {
+        return nil, fmt.Errorf("read jira stub store err: %w", err)
+    }
+
+    if err := json.Unmarshal(bodyBytes, &stub.store); err != nil {
+        return nil, fmt.Errorf("parse jira stub store err: %w", err)
+    }
+
+    // stores written before ids were counted continue after their newest issue
+    for _, issue := range stub.store.Issues {
+        if id, err := strconv.Atoi(issue.ID); err == nil && id > stub.store.LastID {
+            stub.store.LastID = id
+        }
+    }
+
+    return stub, nil
+
+}
+
+// save, write the store to disk, called with mu held
+func (s *StubClient) save() error {
+    if s.path == "" {
+        return nil
+    }
+
+    bodyBytes, err := json.MarshalIndent(s.store, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    tmpPath := s.path + ".tmp"
+    if err := ioutil.WriteFile(tmpPath, bodyBytes, 0600); err != nil {
+        return fmt.Errorf("write jira stub store err: %w", err)
+    }
+
+    return os.Rename(tmpPath, s.path)
+
+}
+
+// issue, the stored issue with issueKey, called with mu held
+func (s *StubClient) issue(issueKey string) (*jira.Issue, error) {
+    issue, exists := s.store.Issues[issueKey]
+    if !exists {
+        return nil, fmt.Errorf("issue %s does not exist", issueKey)
+    }
+
+    return issue, nil
+
+}
+
+func (s *StubClient) getSelf(ctx context.Context) (*jira.User, *jira.Response, error) {
+    logCall(ctx, "myself")
+    return &jira.User{AccountID: StubAccountID, Name: StubAccountID, DisplayName: "Local Stub"}, nil, nil
+
+}
+
+func (s *StubClient) createIssue(ctx context.Context, issue *jira.Issue) (*jira.Issue, *jira.Response, error) {
+    logCall(ctx, "issue.create")
+
+    if issue.Fields == nil || issue.Fields.Project.Key == "" {
+        return nil, nil, fmt.Errorf("project is required")
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    project := issue.Fields.Project.Key
+    s.store.Counters[project]++
+
+    if s.store.LastID < 10000 {
+        s.store.LastID = 10000
+    } else {
+        s.store.LastID++
+    }
+
+    fields := *issue.Fields
+    fields.Status = &jira.Status{Name: "To Do"}
+    fields.Created = jira.Time(time.Now())
+
+    created := &jira.Issue{
+        ID: strconv.Itoa(s.store.LastID),
+        Key: fmt.Sprintf("%s-%d", project, s.store.Counters[project]),
+        Fields: &fields,
+    }
+    s.store.Issues[created.Key] = created
+
+    if err := s.save(); err != nil {
+        return nil, nil, err
+    }
+
+    return &jira.Issue{ID: created.ID, Key: created.Key}, nil, nil
+
+}
+
+func (s *StubClient) deleteIssue(ctx context.Context, issueKey string) (*jira.Response, error) {
+    logCall(ctx, "issue.delete")
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, err := s.issue(issueKey); err != nil {
+        return nil, err
+    }
+    delete(s.store.Issues, issueKey)
+
+    return nil, s.save()
+
+}
+
+func (s *StubClient) getTransitions(ctx context.Context, issueKey string) ([]jira.Transition, *jira.Response, error) {
+    logCall(ctx, "issue.transitions")
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, err := s.issue(issueKey); err != nil {
+        return nil, nil, err
+    }
+
+    return stubTransitions, nil, nil
+
+}
+
+func (s *StubClient) doTransition(ctx context.Context, issueKey string, transitionID string) (*jira.Response, error) {
+    logCall(ctx, "issue.transition")
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    issue, err := s.issue(issueKey)
+    if err != nil {
+        return nil, err
+    }
+
+    for _, transition := range stubTransitions {
+        if transition.ID == transitionID {
+            status := transition.To
+            issue.Fields.Status = &status
+            return nil, s.save()
+        }
+    }
+
+    return nil, fmt.Errorf("no transition %s for issue %s", transitionID, issueKey)
+
+}
+
+func (s *StubClient) addComment(ctx context.Context, issueKey string, comment *jira.Comment) (*jira.Comment, *jira.Response, error) {
+    logCall(ctx, "issue.comment")
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    issue, err := s.issue(issueKey)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    if issue.Fields.Comments == nil {
+        issue.Fields.Comments = &jira.Comments{}
+    }
+
+    added := &jira.Comment{
+        ID: fmt.Sprintf("%d", len(issue.Fields.Comments.Comments) + 1),
+        Body: comment.Body,
+        Created: time.Now().Format(time.RFC3339),
+    }
+    issue.Fields.Comments.Comments = append(issue.Fields.Comments.Comments, added)
+
+    return added, nil, s.save()
+
+}
+
+func (s *StubClient) getIssue(ctx context.Context, issueKey string) (*jira.Issue, *jira.Response, error) {
+    logCall(ctx, "issue.get")
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    issue, err := s.issue(issueKey)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    fields := *issue.Fields
+    return &jira.Issue{ID: issue.ID, Key: issue.Key, Fields: &fields}, nil, nil
+
+}
+
+func (s *StubClient) addRemoteLink(ctx context.Context, issueKey string, remoteLink *jira.RemoteLink) (*jira.RemoteLink, *jira.Response, error) {
+    logCall(ctx, "issue.remotelink")
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, err := s.issue(issueKey); err != nil {
+        return nil, nil, err
+    }
+
+    if s.store.RemoteLinks == nil {
+        s.store.RemoteLinks = make(map[string][]jira.RemoteLink)
+    }
+
+    // like Jira a link with the same global id replaces the earlier one
+    links := s.store.RemoteLinks[issueKey]
+    added := *remoteLink
+    for i, link := range links {
+        if added.GlobalID != "" && link.GlobalID == added.GlobalID {
+            added.ID = link.ID
+            links[i] = added
+            return &added, nil, s.save()
+        }
+    }
+
+    added.ID = len(links) + 1
+    s.store.RemoteLinks[issueKey] = append(links, added)
+
+    return &added, nil, s.save()
+
+}
+
+func (s *StubClient) deleteRemoteLink(ctx context.Context, issueKey string, globalID string) (*jira.Response, error) {
+    logCall(ctx, "issue.remotelink.delete")
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, err := s.issue(issueKey); err != nil {
+        return nil, err
+    }
+
+    links := s.store.RemoteLinks[issueKey]
+    for i, link := range links {
+        if link.GlobalID == globalID {
+            s.store.RemoteLinks[issueKey] = append(links[:i:i], links[i + 1:]...)
+            return nil, s.save()
+        }
+    }
+
+    return nil, fmt.Errorf("issue %s has no remote link %s", issueKey, globalID)
+
+}
+
+func (s *StubClient) findUsers(ctx context.Context, param string, query string) ([]jira.User, *jira.Response, error) {
+    logCall(ctx, "user.search")
+
+    // every address belongs to a user of the stub, its account id is the address
+    return []jira.User{{AccountID: query, EmailAddress: query, DisplayName: query}}, nil, nil
+
+}
+
+func (s *StubClient) updateAssignee(ctx context.Context, issueKey string, assignee *jira.User) (*jira.Response, error) {
+    logCall(ctx, "issue.assignee")
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    issue, err := s.issue(issueKey)
+    if err != nil {
+        return nil, err
+    }
+
+    issue.Fields.Assignee = &jira.User{AccountID: assignee.AccountID, DisplayName: assignee.AccountID}
+
+    return nil, s.save()
+
+}
+
+func (s *StubClient) getPriorities(ctx context.Context) ([]jira.Priority, *jira.Response, error) {
+    logCall(ctx, "priority")
+    return stubPriorities, nil, nil
+
+}
+
+func (s *StubClient) updateIssue(ctx context.Context, issueKey string, data map[string]interface{}) (*jira.Response, error) {
+    logCall(ctx, "issue.update")
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    issue, err := s.issue(issueKey)
+    if err != nil {
+        return nil, err
+    }
+
+    // only the priority is ever updated
+    fields, _ := data["fields"].(map[string]interface{})
+    priority, _ := fields["priority"].(map[string]string)
+    for _, known := range stubPriorities {
+        if known.Name == priority["name"] {
+            set := known
+            issue.Fields.Priority = &set
+            return nil, s.save()
+        }
+    }
+
+    return nil, fmt.Errorf("no priority '%s'", priority["name"])
+
+}
+
+var stubPage = template.Must(template.New("stub").Parse(`<!DOCTYPE html>
+<html>
+<head><title>{{if .Issue}}{{.Issue.Key}}{{else}}Jira stub{{end}}</title></head>
+<body>
+{{if .Issue}}
+<p><a href="../">All issues</a></p>
+<h1>{{.Issue.Key}}: {{.Issue.Fields.Summary}}</h1>
+<p>{{.Issue.Fields.Type.Name}} in {{.Issue.Fields.Project.Key}}, status <strong>{{.Issue.Fields.Status.Name}}</strong>{{if .Issue.Fields.Assignee}}, assigned to {{.Issue.Fields.Assignee.DisplayName}}{{end}}{{if .Issue.Fields.Priority}}, priority {{.Issue.Fields.Priority.Name}}{{end}}</p>
+<pre>{{.Issue.Fields.Description}}</pre>
+{{range .RemoteLinks}}{{if .Object}}<p>{{if .Object.Icon}}<img src="{{.Object.Icon.Url16x16}}" alt="{{.Object.Icon.Title}}" width="16" height="16"> {{end}}<a href="{{.Object.URL}}">{{.Object.Title}}</a></p>
+{{end}}{{end}}{{if .Issue.Fields.Comments}}{{range .Issue.Fields.Comments.Comments}}<pre>{{.Body}}</pre>
+{{end}}{{end}}{{else}}
+<h1>Jira stub</h1>
+<ul>
+{{range .Issues}}<li><a href="browse/{{.Key}}">{{.Key}}</a> {{.Fields.Summary}} ({{.Fields.Status.Name}})</li>
+{{end}}</ul>
+{{end}}
+</body>
+</html>
+`))
+
+// ServeHTTP, the browse page of an issue at browse/KEY and a list of every
+// issue at the root, mounted under the path of the stub's JiraUrl
+func (s *StubClient) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    path := strings.TrimPrefix(req.URL.Path, "/")
+    resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+    if path == "" {
+        issues := make([]*jira.Issue, 0, len(s.store.Issues))
+        for _, issue := range s.store.Issues {
+            issues = append(issues, issue)
+        }
+        // ids are numbers, 10010 comes after 9999
+        sort.Slice(issues, func(i, j int) bool {
+            first, _ := strconv.Atoi(issues[i].ID)
+            second, _ := strconv.Atoi(issues[j].ID)
+            return first < second
+        })
+
+        stubPage.Execute(resp, map[string]interface{}{"Issues": issues})
+        return
+    }
+
+    if !strings.HasPrefix(path, "browse/") {
+        http.NotFound(resp, req)
+        return
+    }
+
+    issue, err := s.issue(strings.TrimPrefix(path, "browse/"))
+    if err != nil {
+        http.Error(resp, err.Error(), http.StatusNotFound)
+        return
+    }
+
+    stubPage.Execute(resp, map[string]interface{}{"Issue": issue, "RemoteLinks": s.store.RemoteLinks[issue.Key]})
+
+}
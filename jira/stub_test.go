@@ -0,0 +1,73 @@
+package jira
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStubClient(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "jira-stub.json")
+
+    stub, err := NewStubClient(path)
+    require.NoError(t, err)
+
+    env, err := NewEnv(stub, "http://localhost:8080/jira/", "OPS", "Escalated", "Task")
+    require.NoError(t, err)
+    assert.Equal(t, StubAccountID, env.JiraUserAccountID)
+
+    first, err := env.CreateJiraIssue(context.Background(), "the checkout page is down")
+    require.NoError(t, err)
+    assert.Equal(t, "OPS-1", first.Key)
+
+    second, err := env.CreateJiraIssue(context.Background(), "and the cart")
+    require.NoError(t, err)
+    assert.Equal(t, "OPS-2", second.Key)
+
+    require.NoError(t, env.TransitionJiraIssue(context.Background(), "OPS-1", "done"))
+    require.NoError(t, env.AddJiraRemoteLink(context.Background(), "OPS-1", "https://acme.slack.com/archives/C0ALERTS/p1641160800000100", "#alerts"))
+    require.NoError(t, env.AddJiraRemoteLink(context.Background(), "OPS-1", "https://acme.slack.com/archives/C0ALERTS/p1641160800000200", "#alerts"))
+    require.NoError(t, env.DeleteJiraRemoteLink(context.Background(), "OPS-1", "https://acme.slack.com/archives/C0ALERTS/p1641160800000200"))
+    assert.ErrorContains(t, env.DeleteJiraRemoteLink(context.Background(), "OPS-1", "https://acme.slack.com/archives/C0ALERTS/p1641160800000200"), "no remote link")
+    require.NoError(t, env.AssignTicket(context.Background(), "OPS-1", "ada@example.com"))
+    require.NoError(t, env.PrioritizeTicket(context.Background(), "OPS-1", "High"))
+    assert.ErrorContains(t, env.PrioritizeTicket(context.Background(), "OPS-1", "Urgent"), "no priority 'Urgent'")
+    require.NoError(t, env.DeleteJiraIssue(context.Background(), "OPS-2"))
+    assert.ErrorContains(t, env.DeleteJiraIssue(context.Background(), "OPS-2"), "does not exist")
+
+    // issues and counters survive a restart
+    reloaded, err := NewStubClient(path)
+    require.NoError(t, err)
+    env.JiraClient = reloaded
+
+    third, err := env.CreateJiraIssue(context.Background(), "still down")
+    require.NoError(t, err)
+    assert.Equal(t, "OPS-3", third.Key)
+
+    // the id of the deleted OPS-2 is not handed out again
+    assert.Equal(t, "10000", first.ID)
+    assert.Equal(t, "10002", third.ID)
+
+    resp := httptest.NewRecorder()
+    reloaded.ServeHTTP(resp, httptest.NewRequest("GET", "/browse/OPS-1", nil))
+    assert.Equal(t, http.StatusOK, resp.Code)
+    assert.Contains(t, resp.Body.String(), "OPS-1: Escalated")
+    assert.Contains(t, resp.Body.String(), "the checkout page is down")
+    assert.Contains(t, resp.Body.String(), "<strong>Done</strong>, assigned to ada@example.com, priority High")
+    assert.Contains(t, resp.Body.String(), `alt="Slack" width="16" height="16"> <a href="https://acme.slack.com/archives/C0ALERTS/p1641160800000100">#alerts</a>`)
+
+    resp = httptest.NewRecorder()
+    reloaded.ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+    assert.Contains(t, resp.Body.String(), `href="browse/OPS-3"`)
+    assert.NotContains(t, resp.Body.String(), "OPS-2")
+
+    resp = httptest.NewRecorder()
+    reloaded.ServeHTTP(resp, httptest.NewRequest("GET", "/browse/OPS-2", nil))
+    assert.Equal(t, http.StatusNotFound, resp.Code)
+
+}
@@ -0,0 +1,350 @@
+package runtime
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+	"github.com/sirupsen/logrus"
+	slackgo "github.com/slack-go/slack"
+	"go.opentelemetry.io/otel/attribute"
+
+    "slack-jira-integration/audit"
+    "slack-jira-integration/chat"
+    "slack-jira-integration/logging"
+    "slack-jira-integration/metrics"
+    "slack-jira-integration/slack"
+    "slack-jira-integration/tracing"
+)
+
+// linkUsage is the reply to a /jira command that could not be understood
+const linkUsage = "Usage: `/jira link KEY <message link>`, copy the link of the thread with \"Copy link\"."
+
+// lookupTicket, the ticket with key, an error when it does not exist or the
+// backend cannot look tickets up
+func lookupTicket(ctx context.Context, backend TicketBackend, key string) (*chat.Ticket, error) {
+    getter, canGet := backend.(TicketGetter)
+    if !canGet {
+        return nil, fmt.Errorf("ticket backend cannot look up %s", key)
+    }
+
+    return getter.GetTicket(ctx, key)
+
+}
+
+// createTicket, open a ticket with description, what a chat shows of it is
+// only known for backends that answer with it, the others give key and link
+func createTicket(ctx context.Context, backend TicketBackend, description string) (*chat.Ticket, error) {
+    if creator, canDetail := backend.(TicketDetailCreator); canDetail {
+        return creator.CreateTicketDetails(ctx, description)
+    }
+
+    key, err := backend.CreateTicket(ctx, description)
+    if err != nil {
+        return nil, err
+    }
+
+    return &chat.Ticket{Key: key, URL: backend.TicketURL(key)}, nil
+
+}
+
+// postTicket, reply in the thread with a card of ticket where the frontend
+// can show one and text otherwise, returns the reply's id and text
+func postTicket(ctx context.Context, frontend chat.Frontend, channel string, messageID string, ticket chat.Ticket, text string) (string, string, error) {
+    if cards, canCard := frontend.(chat.CardFrontend); canCard {
+        replyID, err := cards.PostTicketCard(ctx, channel, messageID, ticket)
+        return replyID, ticket.URL, err
+    }
+
+    replyID, err := frontend.PostMessageToThread(ctx, channel, messageID, text)
+    return replyID, text, err
+
+}
+
+// mentionedTicket, the first existing ticket a reply in the thread mentions,
+// the root is left out as alerts quote keys of their own and the
+// integration's own messages are skipped, nil when there is none
+func mentionedTicket(ctx context.Context, backend TicketBackend, messages []chat.Message) *chat.Ticket {
+    finder, canFind := backend.(TicketKeyFinder)
+    if !canFind || len(messages) < 2 {
+        return nil
+    }
+
+    looked := make(map[string]bool)
+    for _, message := range messages[1:] {
+        if message.Bot {
+            continue
+        }
+
+        for _, key := range finder.FindTicketKeys(message.Text) {
+            if looked[key] {
+                continue
+            }
+            looked[key] = true
+
+            // a key that cannot be looked up is not linked, a new ticket is created instead
+            found, err := lookupTicket(ctx, backend, key)
+            if err != nil {
+                logging.FromContext(ctx).WithError(err).WithField("issue_key", key).Info("mentioned ticket not found")
+                continue
+            }
+
+            return found
+        }
+    }
+
+    return nil
+
+}
+
+// linkBack, point the ticket at the thread of messageID through a link to
+// the thread titled with its channel, a noop for backends that cannot link
+func linkBack(ctx context.Context, frontend chat.Frontend, backend TicketBackend, channel string, messageID string, key string) error {
+    linker, canLink := backend.(TicketLinker)
+    if !canLink {
+        return nil
+    }
+
+    permalink, err := frontend.Permalink(ctx, channel, messageID)
+    if err != nil {
+        return err
+    }
+
+    return linker.LinkTicket(ctx, key, permalink, "#" + channelLabel(frontend, channel))
+
+}
+
+// unlinkThread, remove the link back to the thread of messageID from the
+// ticket, a noop for backends that cannot remove it
+func unlinkThread(ctx context.Context, frontend chat.Frontend, backend TicketBackend, channel string, messageID string, key string) error {
+    unlinker, canUnlink := backend.(TicketUnlinker)
+    if !canUnlink {
+        return nil
+    }
+
+    permalink, err := frontend.Permalink(ctx, channel, messageID)
+    if err != nil {
+        return err
+    }
+
+    return unlinker.UnlinkTicket(ctx, key, permalink)
+
+}
+
+// linkThread, associate the thread of messageID with an existing ticket
+// instead of creating one, the ticket gets a link back to the thread and
+// the thread a card of the ticket, returns the id of the card's reply
+func (r *runtime) linkThread(ctx context.Context, frontend chat.Frontend, backend TicketBackend, channel string, messageID string, user string, found *chat.Ticket) (replyID string, err error) {
+    ctx = logging.WithFields(ctx, logrus.Fields{"issue_key": found.Key})
+    ctx, span := tracing.Start(ctx, "linkThread", attribute.String("jira.issue_key", found.Key))
+    defer func() {
+        tracing.End(span, err)
+    }()
+
+    if err := linkBack(ctx, frontend, backend, channel, messageID, found.Key); err != nil {
+        return "", err
+    }
+
+    issueUrl := found.URL
+    logging.FromContext(ctx).Info("thread linked to existing issue")
+    metrics.Escalations.WithLabelValues(audit.OutcomeLinked).Inc()
+    r.publish(ctx, chat.ThreadLinked{
+        Frontend: frontend.Name(),
+        Channel: channel,
+        MessageID: messageID,
+        User: user,
+        Project: backend.TicketProject(),
+        TicketKey: found.Key,
+        TicketURL: issueUrl,
+    })
+
+    text := fmt.Sprintf("%s: %s (%s)\n%s", found.Key, found.Summary, found.Status, issueUrl)
+    replyID, text, err = postTicket(ctx, frontend, channel, messageID, *found, text)
+    if err != nil {
+        return replyID, err
+    }
+
+    r.publish(ctx, chat.ReplyPosted{
+        Frontend: frontend.Name(),
+        Channel: channel,
+        MessageID: messageID,
+        ReplyID: replyID,
+        Text: text,
+    })
+
+    return replyID, nil
+
+}
+
+// SlackCommandsHandler, answer the /jira slash command, "/jira link KEY
+// <message link>" links the thread of the message to the existing ticket
+// KEY, Slack sends no thread with a command so the thread is given by its link
+func (r *runtime) SlackCommandsHandler(resp http.ResponseWriter, req *http.Request) {
+    if !r.begin() {
+        resp.WriteHeader(http.StatusServiceUnavailable)
+        return
+    }
+    defer r.end()
+
+    command, err := slackgo.SlashCommandParse(req)
+    if err != nil {
+        resp.WriteHeader(http.StatusBadRequest)
+        return
+    }
+
+    reply := r.HandleSlashCommand(req.Context(), command)
+
+    resp.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(resp).Encode(&slackgo.Msg{ResponseType: slackgo.ResponseTypeEphemeral, Text: reply})
+
+}
+
+// HandleSlashCommand, the reply answering command right away, Slack gives
+// up after three seconds so the command is run in the background and what
+// came of it is sent to its response_url, the entry point of Socket Mode
+// as well
+func (r *runtime) HandleSlashCommand(ctx context.Context, command slackgo.SlashCommand) string {
+    ctx = logging.WithFields(ctx, logrus.Fields{"team_id": command.TeamID, "command": command.Command})
+    metrics.EventsReceived.WithLabelValues("slash_command").Inc()
+
+    args := strings.Fields(command.Text)
+    if len(args) != 3 || args[0] != "link" {
+        return linkUsage
+    }
+
+    channel, messageID, ok := slack.ParsePermalink(args[2])
+    if !ok {
+        return linkUsage
+    }
+
+    if !r.begin() {
+        return "Sorry, try again in a moment."
+    }
+
+    go func() {
+        defer r.end()
+
+        ctx := detachedContext{ctx}
+        reply := r.slashCommand(ctx, command, channel, messageID, args[1])
+        if err := slack.RespondToCommand(ctx, command.ResponseURL, reply); err != nil {
+            logging.FromContext(ctx).WithError(err).Error("command reply failed")
+        }
+    }()
+
+    return fmt.Sprintf("Linking the thread to %s...", args[1])
+
+}
+
+// slashCommand, link the thread of messageID to key and return the reply
+// shown to the user
+func (r *runtime) slashCommand(ctx context.Context, command slackgo.SlashCommand, channel string, messageID string, key string) string {
+    slackEnv, err := r.slackEnv(command.TeamID, command.EnterpriseID)
+    if err != nil {
+        logging.FromContext(ctx).WithError(err).Error("no slack env for command")
+        return "Sorry, this workspace is not set up."
+    }
+
+    reply, err := r.linkCommand(ctx, slackEnv, command.TeamID, command.UserID, channel, messageID, key)
+    if err != nil {
+        logging.FromContext(ctx).WithError(err).Error("link failed")
+    }
+
+    return reply
+
+}
+
+// ownsTicket, whether key is a ticket of the backend's project, keys a
+// backend cannot recognise are never its own
+func ownsTicket(backend TicketBackend, key string) bool {
+    finder, canFind := backend.(TicketKeyFinder)
+    return canFind && contains(finder.FindTicketKeys(key), key)
+
+}
+
+// isChannelMember, whether user is in channel, never for frontends that
+// cannot tell
+func isChannelMember(ctx context.Context, frontend chat.Frontend, channel string, user string) (bool, error) {
+    members, canTell := frontend.(chat.MemberFrontend)
+    if !canTell {
+        return false, nil
+    }
+
+    return members.IsChannelMember(ctx, channel, user)
+
+}
+
+// linkCommand, link the thread of messageID to the ticket key on behalf of
+// user, returns the reply shown to the user
+func (r *runtime) linkCommand(ctx context.Context, frontend chat.Frontend, teamID string, user string, channel string, messageID string, key string) (reply string, err error) {
+    if frontend.ChannelName(channel) == "" {
+        return fmt.Sprintf("Sorry, issues are not escalated from <#%s>.", channel), nil
+    }
+
+    backend, policy := r.channelRoute(frontend, channel)
+    ctx = logging.WithFields(ctx, logrus.Fields{
+        "channel": channel,
+        "message_ts": messageID,
+        "user": user,
+        "jira_project": backend.TicketProject(),
+    })
+
+    record := audit.Record{
+        Time: time.Now(),
+        Action: "link",
+        TeamID: teamID,
+        UserID: user,
+        ChannelID: channel,
+        MessageTS: messageID,
+        Project: backend.TicketProject(),
+        IssueKey: key,
+        Outcome: audit.OutcomeLinked,
+    }
+    defer func() {
+        if err != nil {
+            record.Outcome, record.Error = audit.OutcomeFailed, err.Error()
+        }
+        r.audit(ctx, record)
+    }()
+
+    reason, allowed, err := policy.Authorize(ctx, frontend, user)
+    if err != nil {
+        return "Sorry, linking failed.", err
+    }
+
+    if !allowed {
+        record.Outcome, record.Error = audit.OutcomeDenied, reason
+        return fmt.Sprintf("Sorry, %s.", reason), nil
+    }
+
+    // the link names any channel, only its members may link its threads
+    member, err := isChannelMember(ctx, frontend, channel, user)
+    if err != nil {
+        return "Sorry, linking failed.", err
+    }
+
+    if !member {
+        record.Outcome, record.Error = audit.OutcomeDenied, "not a channel member"
+        return fmt.Sprintf("Sorry, only members of <#%s> can link its threads.", channel), nil
+    }
+
+    // the integration's account may read tickets the channel has no business with
+    if !ownsTicket(backend, key) {
+        record.Outcome, record.Error = audit.OutcomeDenied, "not a ticket of " + backend.TicketProject()
+        return fmt.Sprintf("Sorry, only tickets of %s can be linked from <#%s>.", backend.TicketProject(), channel), nil
+    }
+
+    found, err := lookupTicket(ctx, backend, key)
+    if err != nil {
+        return fmt.Sprintf("Sorry, %s could not be found.", key), err
+    }
+
+    if _, err := r.linkThread(ctx, frontend, backend, channel, messageID, user, found); err != nil {
+        return "Sorry, linking failed.", err
+    }
+
+    return fmt.Sprintf("Linked the thread to %s.", key), nil
+
+}
@@ -0,0 +1,118 @@
+package logging
+
+import (
+    "context"
+    "io"
+    "os"
+    "strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys, log fields whose value is replaced unless redaction is turned
+// off, matched case insensitively against the end of the field name so
+// bot_token, slack_signing_secret, message_text, ... are all covered
+var sensitiveKeys = []string{
+    "token",
+    "password",
+    "secret",
+    "authorization",
+    "text",
+    "body",
+}
+
+type contextKey struct{}
+
+var logger = New(os.Stdout, "info", "json", true)
+
+// New, construct a logger writing to out, level is any logrus level name,
+// format is json or text and redact masks sensitive fields
+func New(out io.Writer, level string, format string, redact bool) *logrus.Logger {
+    l := logrus.New()
+    l.SetOutput(out)
+
+    parsedLevel, err := logrus.ParseLevel(level)
+    if err != nil {
+        parsedLevel = logrus.InfoLevel
+    }
+    l.SetLevel(parsedLevel)
+
+    if format == "text" {
+        l.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+    } else {
+        l.SetFormatter(&logrus.JSONFormatter{})
+    }
+
+    if redact {
+        l.AddHook(&redactHook{})
+    }
+
+    return l
+
+}
+
+// Configure, replace the process wide logger used when a context carries none
+func Configure(level string, format string, redact bool) {
+    logger = New(os.Stdout, level, format, redact)
+
+}
+
+// Logger, the process wide logger
+func Logger() *logrus.Logger {
+    return logger
+
+}
+
+// WithFields, return a context whose logger carries fields in addition to
+// any already on ctx, used to correlate every log line of one Slack event
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+    return context.WithValue(ctx, contextKey{}, FromContext(ctx).WithFields(fields))
+
+}
+
+// FromContext, the logger carried by ctx or the process wide logger
+func FromContext(ctx context.Context) *logrus.Entry {
+    if entry, ok := ctx.Value(contextKey{}).(*logrus.Entry); ok {
+        return entry
+    }
+
+    return logrus.NewEntry(logger)
+
+}
+
+// redactHook, masks sensitive fields before an entry is formatted
+type redactHook struct{}
+
+func (h *redactHook) Levels() []logrus.Level {
+    return logrus.AllLevels
+
+}
+
+func (h *redactHook) Fire(entry *logrus.Entry) error {
+    // entries share their Data map with the parent entry so write a copy
+    data := make(logrus.Fields, len(entry.Data))
+    for key, value := range entry.Data {
+        if isSensitive(key) {
+            value = redacted
+        }
+        data[key] = value
+    }
+    entry.Data = data
+
+    return nil
+
+}
+
+func isSensitive(key string) bool {
+    key = strings.ToLower(key)
+    for _, sensitiveKey := range sensitiveKeys {
+        if strings.HasSuffix(key, sensitiveKey) {
+            return true
+        }
+    }
+
+    return false
+
+}
@@ -0,0 +1,255 @@
+package mattermost
+
+import (
+    "context"
+    "crypto/subtle"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+	"github.com/gorilla/websocket"
+
+    "slack-jira-integration/chat"
+    "slack-jira-integration/logging"
+)
+
+// maxReconnectDelay caps the wait between websocket reconnects
+const maxReconnectDelay = 30 * time.Second
+
+// websocketEvent is the part of a websocket event the integration reads
+type websocketEvent struct {
+    Event string `json:"event"`
+    Data struct {
+        Reaction string `json:"reaction"`
+    } `json:"data"`
+    Broadcast struct {
+        ChannelID string `json:"channel_id"`
+    } `json:"broadcast"`
+}
+
+// reaction is the JSON encoded in the data of reaction events
+type reaction struct {
+    UserID string `json:"user_id"`
+    PostID string `json:"post_id"`
+    EmojiName string `json:"emoji_name"`
+}
+
+// websocketURL, the websocket endpoint of the server
+func (m *MattermostEnv) websocketURL() string {
+    if strings.HasPrefix(m.URL, "https://") {
+        return "wss://" + strings.TrimPrefix(m.URL, "https://") + "api/v4/websocket"
+    }
+
+    return "ws://" + strings.TrimPrefix(m.URL, "http://") + "api/v4/websocket"
+
+}
+
+// Listen, connect to the server's websocket and hand every reaction added
+// or removed in the team to handler until ctx is cancelled, dropped
+// connections are retried with a growing delay
+func (m *MattermostEnv) Listen(ctx context.Context, handler chat.Handler) error {
+    delay := time.Second
+
+    for {
+        connected, err := m.listen(ctx, handler)
+        if ctx.Err() != nil {
+            return nil
+        }
+
+        if connected {
+            delay = time.Second
+        }
+
+        var unauthorized *unauthorizedError
+        if errors.As(err, &unauthorized) {
+            return err
+        }
+
+        logging.FromContext(ctx).WithError(err).WithField("delay", delay.String()).Warn("mattermost websocket closed, reconnecting")
+
+        select {
+        case <-ctx.Done():
+            return nil
+
+        case <-time.After(delay):
+        }
+
+        if delay *= 2; delay > maxReconnectDelay {
+            delay = maxReconnectDelay
+        }
+    }
+
+}
+
+// unauthorizedError, the server refused the token, reconnecting will not help
+type unauthorizedError struct {
+    status int
+}
+
+func (e *unauthorizedError) Error() string {
+    return fmt.Sprintf("mattermost websocket refused with %d, check the token", e.status)
+
+}
+
+// listen, read events from one websocket connection until it fails,
+// connected is whether the connection was established at all
+func (m *MattermostEnv) listen(ctx context.Context, handler chat.Handler) (connected bool, err error) {
+    header := http.Header{"Authorization": []string{"Bearer " + m.Token}}
+
+    conn, resp, err := websocket.DefaultDialer.DialContext(ctx, m.websocketURL(), header)
+    if resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+        return false, &unauthorizedError{status: resp.StatusCode}
+    }
+
+    if err != nil {
+        return false, fmt.Errorf("mattermost websocket dial err: %w", err)
+    }
+    defer conn.Close()
+
+    // a blocked read is interrupted by closing the connection
+    done := make(chan struct{})
+    defer close(done)
+    go func() {
+        select {
+        case <-ctx.Done():
+            conn.Close()
+
+        case <-done:
+        }
+
+    }()
+
+    for {
+        var event websocketEvent
+        if err := conn.ReadJSON(&event); err != nil {
+            return true, err
+        }
+
+        // ctx ends with SIGTERM, the handler returns at once and the runtime
+        // drains the events already read
+        m.handleEvent(context.Background(), handler, &event)
+    }
+
+}
+
+// handleEvent, translate a reaction event into a chat event, the bot's own
+// reactions and every other event are ignored
+func (m *MattermostEnv) handleEvent(ctx context.Context, handler chat.Handler, event *websocketEvent) {
+    if event.Event != "reaction_added" && event.Event != "reaction_removed" {
+        return
+    }
+
+    var r reaction
+    if err := json.Unmarshal([]byte(event.Data.Reaction), &r); err != nil {
+        logging.FromContext(ctx).WithError(err).Warn("mattermost reaction event not understood")
+        return
+    }
+
+    if r.UserID == m.BotUserID {
+        return
+    }
+
+    if event.Event == "reaction_added" {
+        handler.HandleReactionAdded(ctx, m, &chat.ReactionAdded{
+            TeamID: m.TeamID,
+            User: r.UserID,
+            Channel: event.Broadcast.ChannelID,
+            MessageID: r.PostID,
+            Reaction: r.EmojiName,
+        })
+        return
+    }
+
+    handler.HandleReactionRemoved(ctx, m, &chat.ReactionRemoved{
+        TeamID: m.TeamID,
+        User: r.UserID,
+        Channel: event.Broadcast.ChannelID,
+        MessageID: r.PostID,
+        Reaction: r.EmojiName,
+    })
+
+}
+
+// outgoingWebhook is the part of an outgoing webhook request the integration reads
+type outgoingWebhook struct {
+    Token string `json:"token"`
+    ChannelID string `json:"channel_id"`
+    UserID string `json:"user_id"`
+    PostID string `json:"post_id"`
+    TriggerWord string `json:"trigger_word"`
+}
+
+// parseOutgoingWebhook, outgoing webhooks are sent form encoded or as JSON
+// depending on how they were set up
+func parseOutgoingWebhook(req *http.Request) (*outgoingWebhook, error) {
+    var payload outgoingWebhook
+
+    if strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+        if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+            return nil, err
+        }
+
+        return &payload, nil
+    }
+
+    if err := req.ParseForm(); err != nil {
+        return nil, err
+    }
+
+    payload = outgoingWebhook{
+        Token: req.PostForm.Get("token"),
+        ChannelID: req.PostForm.Get("channel_id"),
+        UserID: req.PostForm.Get("user_id"),
+        PostID: req.PostForm.Get("post_id"),
+        TriggerWord: req.PostForm.Get("trigger_word"),
+    }
+
+    return &payload, nil
+
+}
+
+// WebhookHandler, accept outgoing webhooks fired by a trigger word such as
+// ":ticket:", the post carrying it counts as a reaction with that emoji to
+// the root of its thread
+func (m *MattermostEnv) WebhookHandler(handler chat.Handler) http.HandlerFunc {
+    return func(resp http.ResponseWriter, req *http.Request) {
+        payload, err := parseOutgoingWebhook(req)
+        if err != nil {
+            resp.WriteHeader(http.StatusBadRequest)
+            return
+        }
+
+        if m.WebhookToken == "" || subtle.ConstantTimeCompare([]byte(payload.Token), []byte(m.WebhookToken)) != 1 {
+            resp.WriteHeader(http.StatusUnauthorized)
+            return
+        }
+
+        if payload.UserID == m.BotUserID {
+            resp.WriteHeader(http.StatusOK)
+            return
+        }
+
+        rootID, err := m.rootID(req.Context(), payload.PostID)
+        if err != nil {
+            logging.FromContext(req.Context()).WithError(err).Error("mattermost webhook post not found")
+            resp.WriteHeader(http.StatusBadGateway)
+            return
+        }
+
+        handler.HandleReactionAdded(req.Context(), m, &chat.ReactionAdded{
+            TeamID: m.TeamID,
+            User: payload.UserID,
+            Channel: payload.ChannelID,
+            MessageID: rootID,
+            Reaction: strings.Trim(payload.TriggerWord, ":"),
+        })
+
+        resp.Header().Set("Content-Type", "application/json")
+        resp.Write([]byte("{}"))
+
+    }
+
+}
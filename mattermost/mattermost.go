@@ -0,0 +1,282 @@
+package mattermost
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "net/url"
+    "sort"
+    "strings"
+
+    "slack-jira-integration/chat"
+    "slack-jira-integration/httpapi"
+)
+
+// MattermostEnv is a chat.Frontend for one Mattermost team through the REST
+// API v4, the bot's personal access token authenticates every call
+type MattermostEnv struct {
+    Client *http.Client
+    // URL is the server root, it ends with a slash
+    URL string
+    Token string
+    Team string
+    TeamID string
+    BotUserID string
+    ChannelNames []string
+    ChannelNamesByID map[string]string
+    // Emojis are the escalating reactions by channel id
+    Emojis map[string]string
+    // WebhookToken is the token outgoing webhooks are checked against
+    WebhookToken string
+}
+
+// NewClient, construct a http.Client authenticating with a bot or personal access token
+func NewClient(token string) *http.Client {
+    return httpapi.NewClient("mattermost", token, nil)
+
+}
+
+// NewEnv, construct a MattermostEnv for team (its name), finds the bot's own
+// user and the ids of channelNames, emojis are indexed by channel name
+func NewEnv(serverURL string, token string, team string, emojis map[string]string, channelNames []string) (*MattermostEnv, error) {
+    env := &MattermostEnv{
+        Client: NewClient(token),
+        URL: strings.TrimSuffix(serverURL, "/") + "/",
+        Token: token,
+        Team: team,
+        ChannelNames: channelNames,
+        ChannelNamesByID: make(map[string]string),
+        Emojis: make(map[string]string),
+    }
+
+    ctx := context.Background()
+
+    var me user
+    if err := env.do(ctx, "get me", http.MethodGet, "users/me", nil, &me); err != nil {
+        return nil, err
+    }
+    env.BotUserID = me.ID
+
+    var found struct {
+        ID string `json:"id"`
+    }
+    if err := env.do(ctx, "get team", http.MethodGet, "teams/name/" + url.PathEscape(team), nil, &found); err != nil {
+        return nil, err
+    }
+    env.TeamID = found.ID
+
+    for _, channelName := range channelNames {
+        path := fmt.Sprintf("teams/%s/channels/name/%s", env.TeamID, url.PathEscape(channelName))
+        if err := env.do(ctx, "get channel", http.MethodGet, path, nil, &found); err != nil {
+            return nil, fmt.Errorf("could not find channel name '%s': %w", channelName, err)
+        }
+
+        env.ChannelNamesByID[found.ID] = channelName
+        if emoji, exists := emojis[channelName]; exists {
+            env.Emojis[found.ID] = emoji
+        }
+    }
+
+    return env, nil
+
+}
+
+// post is the part of a Mattermost post the integration reads
+type post struct {
+    ID string `json:"id"`
+    ChannelID string `json:"channel_id"`
+    RootID string `json:"root_id"`
+    UserID string `json:"user_id"`
+    Message string `json:"message"`
+    CreateAt int64 `json:"create_at"`
+}
+
+// user is the part of a Mattermost user the integration reads
+type user struct {
+    ID string `json:"id"`
+    Roles string `json:"roles"`
+}
+
+// do, send a JSON request to path under api/v4 and decode the response into
+// out, Mattermost's error body is returned as the error
+func (m *MattermostEnv) do(ctx context.Context, action string, method string, path string, body interface{}, out interface{}) error {
+    return httpapi.Do(ctx, m.Client, "mattermost", action, method, m.URL + "api/v4/" + path, body, out)
+
+}
+
+// Ping, verify the server is reachable and the token is still accepted
+func (m *MattermostEnv) Ping(ctx context.Context) error {
+    return m.do(ctx, "get me", http.MethodGet, "users/me", nil, nil)
+
+}
+
+// Name, "mattermost"
+func (m *MattermostEnv) Name() string {
+    return "mattermost"
+
+}
+
+// getPost, the post with postID
+func (m *MattermostEnv) getPost(ctx context.Context, postID string) (*post, error) {
+    var found post
+    if err := m.do(ctx, "get post", http.MethodGet, "posts/" + postID, nil, &found); err != nil {
+        return nil, err
+    }
+
+    return &found, nil
+
+}
+
+// rootID, the root of the thread postID belongs to
+func (m *MattermostEnv) rootID(ctx context.Context, postID string) (string, error) {
+    found, err := m.getPost(ctx, postID)
+    if err != nil {
+        return "", err
+    }
+
+    if found.RootID != "" {
+        return found.RootID, nil
+    }
+
+    return found.ID, nil
+
+}
+
+// ThreadMessages, every post of the thread postID belongs to, oldest first
+func (m *MattermostEnv) ThreadMessages(ctx context.Context, channel string, postID string) ([]chat.Message, error) {
+    var thread struct {
+        Order []string `json:"order"`
+        Posts map[string]post `json:"posts"`
+    }
+    if err := m.do(ctx, "get thread", http.MethodGet, "posts/" + postID + "/thread", nil, &thread); err != nil {
+        return nil, err
+    }
+
+    posts := make([]post, 0, len(thread.Posts))
+    for _, threadPost := range thread.Posts {
+        posts = append(posts, threadPost)
+    }
+    sort.Slice(posts, func(i, j int) bool {
+        return posts[i].CreateAt < posts[j].CreateAt
+    })
+
+    messages := make([]chat.Message, 0, len(posts))
+    for _, threadPost := range posts {
+        messages = append(messages, chat.Message{
+            ID: threadPost.ID,
+            User: threadPost.UserID,
+            Text: threadPost.Message,
+            Bot: threadPost.UserID == m.BotUserID,
+        })
+    }
+
+    return messages, nil
+
+}
+
+// PostMessageToThread, reply in the thread postID belongs to, returns the
+// id of the reply
+func (m *MattermostEnv) PostMessageToThread(ctx context.Context, channel string, postID string, msgBody string) (string, error) {
+    rootID, err := m.rootID(ctx, postID)
+    if err != nil {
+        return "", fmt.Errorf("post message failed err: %w", err)
+    }
+
+    var created post
+    payload := map[string]string{"channel_id": channel, "root_id": rootID, "message": msgBody}
+    if err := m.do(ctx, "create post", http.MethodPost, "posts", payload, &created); err != nil {
+        return "", fmt.Errorf("post message failed err: %w", err)
+    }
+
+    return created.ID, nil
+
+}
+
+// UpdateMessage, replace the text of the post
+func (m *MattermostEnv) UpdateMessage(ctx context.Context, channel string, postID string, msgBody string) error {
+    payload := map[string]string{"message": msgBody}
+    if err := m.do(ctx, "patch post", http.MethodPut, "posts/" + postID + "/patch", payload, nil); err != nil {
+        return fmt.Errorf("update message failed err: %w", err)
+    }
+
+    return nil
+
+}
+
+// Permalink, the link opening the post in the team
+func (m *MattermostEnv) Permalink(ctx context.Context, channel string, postID string) (string, error) {
+    return fmt.Sprintf("%s%s/pl/%s", m.URL, m.Team, postID), nil
+
+}
+
+// PostEphemeral, send msgBody to user in channel, visible only to them
+func (m *MattermostEnv) PostEphemeral(ctx context.Context, channel string, userID string, msgBody string) error {
+    payload := map[string]interface{}{
+        "user_id": userID,
+        "post": map[string]string{"channel_id": channel, "message": msgBody},
+    }
+    if err := m.do(ctx, "create ephemeral post", http.MethodPost, "posts/ephemeral", payload, nil); err != nil {
+        return fmt.Errorf("post ephemeral failed err: %w", err)
+    }
+
+    return nil
+
+}
+
+// IsGuest, whether the user has the system guest role
+func (m *MattermostEnv) IsGuest(ctx context.Context, userID string) (bool, error) {
+    var found user
+    if err := m.do(ctx, "get user", http.MethodGet, "users/" + userID, nil, &found); err != nil {
+        return false, fmt.Errorf("get user info failed err: %w", err)
+    }
+
+    for _, role := range strings.Fields(found.Roles) {
+        if role == "system_guest" {
+            return true, nil
+        }
+    }
+
+    return false, nil
+
+}
+
+// groupPageSize is how many group members are asked for at a time
+const groupPageSize = 200
+
+// UserGroupMembers, the user ids belonging to the group
+func (m *MattermostEnv) UserGroupMembers(ctx context.Context, group string) ([]string, error) {
+    var members []string
+
+    for page := 0; ; page++ {
+        var found struct {
+            Members []user `json:"members"`
+            TotalMemberCount int `json:"total_member_count"`
+        }
+        path := fmt.Sprintf("groups/%s/members?page=%d&per_page=%d", url.PathEscape(group), page, groupPageSize)
+        if err := m.do(ctx, "get group members", http.MethodGet, path, nil, &found); err != nil {
+            return nil, fmt.Errorf("get user group members failed err: %w", err)
+        }
+
+        for _, member := range found.Members {
+            members = append(members, member.ID)
+        }
+
+        if len(found.Members) < groupPageSize || len(members) >= found.TotalMemberCount {
+            return members, nil
+        }
+    }
+
+}
+
+// ChannelName, the configured name of channelID
+func (m *MattermostEnv) ChannelName(channelID string) string {
+    return m.ChannelNamesByID[channelID]
+
+}
+
+// ChannelEmoji, the configured emoji of channelID
+func (m *MattermostEnv) ChannelEmoji(channelID string) (string, bool) {
+    emoji, exists := m.Emojis[channelID]
+    return emoji, exists
+
+}
@@ -0,0 +1,213 @@
+package mattermost
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+    "slack-jira-integration/chat"
+    "slack-jira-integration/testing/fakes"
+)
+
+// recorder is a chat.Handler keeping every event it is given
+type recorder struct {
+    mu sync.Mutex
+    added []chat.ReactionAdded
+    removed []chat.ReactionRemoved
+}
+
+func (r *recorder) HandleReactionAdded(ctx context.Context, frontend chat.Frontend, ev *chat.ReactionAdded) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.added = append(r.added, *ev)
+
+}
+
+func (r *recorder) HandleReactionRemoved(ctx context.Context, frontend chat.Frontend, ev *chat.ReactionRemoved) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.removed = append(r.removed, *ev)
+
+}
+
+func (r *recorder) counts() (int, int) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    return len(r.added), len(r.removed)
+
+}
+
+func newFake(t *testing.T) (*fakes.Mattermost, *MattermostEnv) {
+    fake := fakes.NewMattermost(t, "acme")
+    fake.Token = "token"
+    fake.AddChannel("alertsid", "alerts")
+
+    env, err := NewEnv(fake.URL, "token", "acme", map[string]string{"alerts": "ticket"}, []string{"alerts"})
+    require.NoError(t, err)
+
+    return fake, env
+
+}
+
+func TestNewEnv(t *testing.T) {
+    fake, env := newFake(t)
+    assert.Equal(t, fake.TeamID, env.TeamID)
+    assert.Equal(t, fake.BotUserID, env.BotUserID)
+    assert.Equal(t, "alerts", env.ChannelName("alertsid"))
+
+    emoji, exists := env.ChannelEmoji("alertsid")
+    assert.True(t, exists)
+    assert.Equal(t, "ticket", emoji)
+
+    _, err := NewEnv(fake.URL, "wrong", "acme", nil, nil)
+    assert.ErrorContains(t, err, "401")
+
+    _, err = NewEnv(fake.URL, "token", "acme", nil, []string{"missing"})
+    assert.ErrorContains(t, err, "could not find channel name 'missing'")
+
+}
+
+func TestMattermostEnv(t *testing.T) {
+    fake, env := newFake(t)
+    ctx := context.Background()
+
+    root := fake.AddPost("alertsid", "U1", "disk full on db-1")
+    reply := fake.AddReply(root, "U2", "still full")
+
+    messages, err := env.ThreadMessages(ctx, "alertsid", reply)
+    require.NoError(t, err)
+    assert.Equal(t, []chat.Message{
+        {ID: root, User: "U1", Text: "disk full on db-1"},
+        {ID: reply, User: "U2", Text: "still full"},
+    }, messages)
+
+    // a reply to a reply lands in the thread of its root
+    posted, err := env.PostMessageToThread(ctx, "alertsid", reply, "OPS-1")
+    require.NoError(t, err)
+    assert.Equal(t, root, fake.Posted()[0].RootID)
+
+    messages, err = env.ThreadMessages(ctx, "alertsid", root)
+    require.NoError(t, err)
+    assert.True(t, messages[2].Bot)
+
+    permalink, err := env.Permalink(ctx, "alertsid", root)
+    require.NoError(t, err)
+    assert.Equal(t, fake.URL + "acme/pl/" + root, permalink)
+
+    require.NoError(t, env.UpdateMessage(ctx, "alertsid", posted, "withdrawn"))
+    assert.Equal(t, "withdrawn", fake.Updated()[0].Message)
+
+    require.NoError(t, env.PostEphemeral(ctx, "alertsid", "U1", "Sorry."))
+    assert.Equal(t, "U1", fake.Ephemeral()[0].UserID)
+
+    fake.AddUser("U1", false)
+    fake.AddUser("UGUEST", true)
+    guest, err := env.IsGuest(ctx, "U1")
+    require.NoError(t, err)
+    assert.False(t, guest)
+    guest, err = env.IsGuest(ctx, "UGUEST")
+    require.NoError(t, err)
+    assert.True(t, guest)
+
+    fake.AddGroup("oncall", "U1", "U2")
+    members, err := env.UserGroupMembers(ctx, "oncall")
+    require.NoError(t, err)
+    assert.Equal(t, []string{"U1", "U2"}, members)
+
+    fake.Fail("createPost", http.StatusInternalServerError)
+    _, err = env.PostMessageToThread(ctx, "alertsid", root, "OPS-2")
+    assert.ErrorContains(t, err, "injected createPost failure")
+
+}
+
+func TestListen(t *testing.T) {
+    fake, env := newFake(t)
+    root := fake.AddPost("alertsid", "U1", "disk full on db-1")
+
+    ctx, cancel := context.WithCancel(context.Background())
+    handler := &recorder{}
+    done := make(chan error, 1)
+    go func() {
+        done <- env.Listen(ctx, handler)
+    }()
+
+    require.Eventually(t, func() bool { return fake.Connected() == 1 }, 5 * time.Second, 10 * time.Millisecond)
+
+    fake.React("U1", root, "ticket")
+    fake.React(fake.BotUserID, root, "white_check_mark")
+    fake.Unreact("U1", root, "ticket")
+
+    require.Eventually(t, func() bool {
+        added, removed := handler.counts()
+        return added == 1 && removed == 1
+    }, 5 * time.Second, 10 * time.Millisecond)
+
+    assert.Equal(t, chat.ReactionAdded{
+        TeamID: fake.TeamID,
+        User: "U1",
+        Channel: "alertsid",
+        MessageID: root,
+        Reaction: "ticket",
+    }, handler.added[0])
+
+    cancel()
+    assert.NoError(t, <-done)
+
+}
+
+func TestListenUnauthorized(t *testing.T) {
+    fake, env := newFake(t)
+    env.Token = "revoked"
+
+    assert.ErrorContains(t, env.Listen(context.Background(), &recorder{}), "check the token")
+    assert.Equal(t, 1, fake.Calls("websocket"))
+
+}
+
+func TestWebhookHandler(t *testing.T) {
+    fake, env := newFake(t)
+    env.WebhookToken = "hook-token"
+    root := fake.AddPost("alertsid", "U1", "disk full on db-1")
+    reply := fake.AddReply(root, "U2", ":ticket:")
+
+    handler := &recorder{}
+    webhook := env.WebhookHandler(handler)
+
+    send := func(token string, user string) int {
+        form := url.Values{
+            "token": {token},
+            "channel_id": {"alertsid"},
+            "user_id": {user},
+            "post_id": {reply},
+            "trigger_word": {":ticket:"},
+        }
+        req := httptest.NewRequest("POST", "/mattermost/webhook", strings.NewReader(form.Encode()))
+        req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+        rr := httptest.NewRecorder()
+        webhook(rr, req)
+        return rr.Code
+    }
+
+    assert.Equal(t, http.StatusUnauthorized, send("wrong", "U2"))
+    assert.Equal(t, http.StatusOK, send("hook-token", fake.BotUserID))
+    assert.Equal(t, http.StatusOK, send("hook-token", "U2"))
+
+    added, _ := handler.counts()
+    require.Equal(t, 1, added)
+    assert.Equal(t, root, handler.added[0].MessageID)
+    assert.Equal(t, "ticket", handler.added[0].Reaction)
+    assert.Equal(t, "U2", handler.added[0].User)
+
+}
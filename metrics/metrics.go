@@ -0,0 +1,133 @@
+package metrics
+
+import (
+    "net/http"
+    "strconv"
+    "time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "slack_jira"
+
+// Registry holds every collector served on /metrics, a private registry
+// keeps tests free of global state from other packages
+var Registry = prometheus.NewRegistry()
+
+var (
+    EventsReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name: "events_received_total",
+        Help: "Slack events received by event type.",
+    }, []string{"type"})
+
+    SignatureFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name: "signature_failures_total",
+        Help: "Slack requests rejected by signature verification by reason.",
+    }, []string{"reason"})
+
+    IssuesCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name: "issues_created_total",
+        Help: "Jira issues created by Slack channel and Jira project.",
+    }, []string{"channel", "project"})
+
+    Escalations = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name: "escalations_total",
+        Help: "Escalations attempted by outcome.",
+    }, []string{"outcome"})
+
+    EscalationsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+        Namespace: namespace,
+        Name: "escalations_in_flight",
+        Help: "Escalations currently being processed.",
+    })
+
+    RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name: "rate_limited_total",
+        Help: "Escalations over a rate limit by scope (user, channel, global) and outcome (rejected, queued).",
+    }, []string{"scope", "outcome"})
+
+    ChatEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name: "chat_events_total",
+        Help: "Events published by the runtime by event type and chat frontend (slack, mattermost).",
+    }, []string{"type", "frontend"})
+
+    APIRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+        Namespace: namespace,
+        Name: "api_request_duration_seconds",
+        Help: "Latency of outbound Slack and Jira API requests.",
+        Buckets: prometheus.DefBuckets,
+    }, []string{"service", "code"})
+
+    APIErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name: "api_errors_total",
+        Help: "Failed outbound Slack and Jira API requests by status code, transport failures use code \"error\".",
+    }, []string{"service", "code"})
+)
+
+func init() {
+    Registry.MustRegister(
+        collectors.NewGoCollector(),
+        collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+        EventsReceived,
+        SignatureFailures,
+        IssuesCreated,
+        Escalations,
+        EscalationsInFlight,
+        RateLimited,
+        ChatEvents,
+        APIRequestDuration,
+        APIErrors,
+    )
+
+}
+
+// Handler, serves the Registry in the Prometheus exposition format
+func Handler() http.Handler {
+    return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+
+}
+
+// InstrumentRoundTripper, wrap next recording latency and errors of every
+// request under the given service label (slack or jira)
+func InstrumentRoundTripper(service string, next http.RoundTripper) http.RoundTripper {
+    if next == nil {
+        next = http.DefaultTransport
+    }
+
+    return &instrumentedRoundTripper{service: service, next: next}
+
+}
+
+type instrumentedRoundTripper struct {
+    service string
+    next http.RoundTripper
+}
+
+func (i *instrumentedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+    start := time.Now()
+
+    resp, err := i.next.RoundTrip(req)
+
+    code := "error"
+    if err == nil {
+        code = strconv.Itoa(resp.StatusCode)
+    }
+
+    APIRequestDuration.WithLabelValues(i.service, code).Observe(time.Since(start).Seconds())
+
+    if err != nil || resp.StatusCode >= http.StatusBadRequest {
+        APIErrors.WithLabelValues(i.service, code).Inc()
+    }
+
+    return resp, err
+
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+    "github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentRoundTripper(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+        if req.URL.Path == "/missing" {
+            resp.WriteHeader(http.StatusNotFound)
+            return
+        }
+        resp.Write([]byte("ok"))
+    }))
+    defer server.Close()
+
+    client := &http.Client{Transport: InstrumentRoundTripper("jira", nil)}
+
+    _, err := client.Get(server.URL + "/ok")
+    assert.Nil(t, err)
+
+    _, err = client.Get(server.URL + "/missing")
+    assert.Nil(t, err)
+
+    assert.Equal(t, float64(1), testutil.ToFloat64(APIErrors.WithLabelValues("jira", "404")))
+    assert.Equal(t, float64(0), testutil.ToFloat64(APIErrors.WithLabelValues("jira", "200")))
+
+    rr := httptest.NewRecorder()
+    Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+    assert.Equal(t, http.StatusOK, rr.Code)
+    assert.True(t, strings.Contains(rr.Body.String(), `slack_jira_api_request_duration_seconds_count{code="200",service="jira"} 1`))
+
+}
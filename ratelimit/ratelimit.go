@@ -0,0 +1,248 @@
+package ratelimit
+
+import (
+    "context"
+    "fmt"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "golang.org/x/time/rate"
+)
+
+const (
+    ScopeUser = "user"
+    ScopeChannel = "channel"
+    ScopeGlobal = "global"
+
+    // OverflowReject refuses escalations over the limit straight away
+    OverflowReject = "reject"
+    // OverflowQueue holds escalations over the limit until a token frees up
+    OverflowQueue = "queue"
+)
+
+// Limit is a token bucket refilled with Burst tokens every Per, a zero Limit
+// is unlimited
+type Limit struct {
+    Burst int
+    Per time.Duration
+}
+
+// ParseLimit, parse "N/duration" e.g. "10/1h", empty meaning unlimited
+func ParseLimit(limit string) (Limit, error) {
+    if limit == "" {
+        return Limit{}, nil
+    }
+
+    parts := strings.SplitN(limit, "/", 2)
+    if len(parts) != 2 {
+        return Limit{}, fmt.Errorf("invalid rate limit '%s', expected N/duration", limit)
+    }
+
+    burst, err := strconv.Atoi(parts[0])
+    if err != nil || burst <= 0 {
+        return Limit{}, fmt.Errorf("invalid rate limit '%s', expected N/duration", limit)
+    }
+
+    per, err := time.ParseDuration(parts[1])
+    if err != nil || per <= 0 {
+        return Limit{}, fmt.Errorf("invalid rate limit '%s', expected N/duration", limit)
+    }
+
+    return Limit{Burst: burst, Per: per}, nil
+
+}
+
+func (l Limit) unlimited() bool {
+    return l.Burst == 0
+
+}
+
+func (l Limit) newLimiter() *rate.Limiter {
+    return rate.NewLimiter(rate.Every(l.Per / time.Duration(l.Burst)), l.Burst)
+
+}
+
+// bucket is a limiter for one user or channel, dropped once it has refilled
+type bucket struct {
+    limiter *rate.Limiter
+    lastUsed time.Time
+}
+
+// Limiter applies per user, per channel and global token buckets to escalations
+type Limiter struct {
+    User Limit
+    Channel Limit
+    Global Limit
+    Overflow string
+    MaxWait time.Duration
+
+    mu sync.Mutex
+    users map[string]*bucket
+    channels map[string]*bucket
+    global *rate.Limiter
+    prunedAt time.Time
+}
+
+// New, construct a Limiter, overflow is OverflowReject or OverflowQueue and
+// maxWait caps how long a queued escalation may wait
+func New(user Limit, channel Limit, global Limit, overflow string, maxWait time.Duration) (*Limiter, error) {
+    if overflow == "" {
+        overflow = OverflowReject
+    }
+
+    if overflow != OverflowReject && overflow != OverflowQueue {
+        return nil, fmt.Errorf("unknown rate limit overflow '%s'", overflow)
+    }
+
+    l := &Limiter{
+        User: user,
+        Channel: channel,
+        Global: global,
+        Overflow: overflow,
+        MaxWait: maxWait,
+        users: make(map[string]*bucket),
+        channels: make(map[string]*bucket),
+    }
+
+    if !global.unlimited() {
+        l.global = global.newLimiter()
+    }
+
+    return l, nil
+
+}
+
+// limiter, the bucket for key, created full on first use
+func (l *Limiter) limiter(buckets map[string]*bucket, limit Limit, key string, now time.Time) *rate.Limiter {
+    b, exists := buckets[key]
+    if !exists {
+        b = &bucket{limiter: limit.newLimiter()}
+        buckets[key] = b
+    }
+    b.lastUsed = now
+
+    return b.limiter
+
+}
+
+// prune, drop buckets idle long enough to have refilled, a new full bucket
+// behaves the same so memory does not grow with every user ever seen
+func (l *Limiter) prune(now time.Time) {
+    if now.Sub(l.prunedAt) < time.Minute {
+        return
+    }
+    l.prunedAt = now
+
+    pruneBuckets(l.users, l.User.Per, now)
+    pruneBuckets(l.channels, l.Channel.Per, now)
+
+}
+
+func pruneBuckets(buckets map[string]*bucket, refill time.Duration, now time.Time) {
+    for key, b := range buckets {
+        if now.Sub(b.lastUsed) > refill {
+            delete(buckets, key)
+        }
+    }
+
+}
+
+// reservation of a token from every bucket an escalation is subject to
+type reservation struct {
+    at time.Time
+    scope string
+    delay time.Duration
+    reservations []*rate.Reservation
+}
+
+// cancel, hand every token back, as of the reservation time since a token
+// that was free then would otherwise count as already spent
+func (r *reservation) cancel() {
+    for _, reservation := range r.reservations {
+        reservation.CancelAt(r.at)
+    }
+
+}
+
+// reserve, take a token from the user, channel and global buckets, scope is
+// the bucket making the escalation wait longest
+func (l *Limiter) reserve(user string, channel string) *reservation {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    now := time.Now()
+    l.prune(now)
+
+    r := &reservation{at: now}
+    take := func(scope string, limiter *rate.Limiter) {
+        reservation := limiter.ReserveN(now, 1)
+        r.reservations = append(r.reservations, reservation)
+
+        if delay := reservation.DelayFrom(now); delay > r.delay {
+            r.scope, r.delay = scope, delay
+        }
+    }
+
+    if !l.User.unlimited() {
+        take(ScopeUser, l.limiter(l.users, l.User, user, now))
+    }
+    if !l.Channel.unlimited() {
+        take(ScopeChannel, l.limiter(l.channels, l.Channel, channel, now))
+    }
+    if l.global != nil {
+        take(ScopeGlobal, l.global)
+    }
+
+    return r
+
+}
+
+// Exceeded is returned by Wait when an escalation is over a limit
+type Exceeded struct {
+    Scope string
+    RetryAfter time.Duration
+}
+
+func (e *Exceeded) Error() string {
+    return fmt.Sprintf("%s rate limit exceeded, retry after %s", e.Scope, e.RetryAfter.Round(time.Second))
+
+}
+
+// Wait, admit an escalation by user in channel, over a limit it returns
+// *Exceeded straight away with OverflowReject, with OverflowQueue it blocks
+// until the escalation is admitted unless that takes longer than MaxWait, queued
+// is called before blocking
+func (l *Limiter) Wait(ctx context.Context, user string, channel string, queued func(scope string, delay time.Duration)) error {
+    if l == nil {
+        return nil
+    }
+
+    r := l.reserve(user, channel)
+    if r.delay == 0 {
+        return nil
+    }
+
+    if l.Overflow == OverflowReject || r.delay > l.MaxWait {
+        r.cancel()
+        return &Exceeded{Scope: r.scope, RetryAfter: r.delay}
+    }
+
+    if queued != nil {
+        queued(r.scope, r.delay)
+    }
+
+    timer := time.NewTimer(r.delay)
+    defer timer.Stop()
+
+    select {
+    case <-timer.C:
+        return nil
+
+    case <-ctx.Done():
+        r.cancel()
+        return ctx.Err()
+    }
+
+}
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestParseLimit(t *testing.T) {
+    limit, err := ParseLimit("10/1h")
+    assert.Nil(t, err)
+    assert.Equal(t, Limit{Burst: 10, Per: time.Hour}, limit)
+
+    limit, err = ParseLimit("")
+    assert.Nil(t, err)
+    assert.True(t, limit.unlimited())
+
+    for _, invalid := range []string{"10", "0/1h", "ten/1h", "10/hour"} {
+        _, err = ParseLimit(invalid)
+        assert.NotNil(t, err, invalid)
+    }
+
+}
+
+func TestWaitReject(t *testing.T) {
+    limiter, err := New(Limit{Burst: 2, Per: time.Hour}, Limit{Burst: 3, Per: time.Hour}, Limit{}, OverflowReject, 0)
+    assert.Nil(t, err)
+
+    ctx := context.Background()
+    assert.Nil(t, limiter.Wait(ctx, "U1", "C1", nil))
+    assert.Nil(t, limiter.Wait(ctx, "U1", "C1", nil))
+
+    err = limiter.Wait(ctx, "U1", "C1", nil)
+    exceeded, ok := err.(*Exceeded)
+    assert.True(t, ok)
+    assert.Equal(t, ScopeUser, exceeded.Scope)
+
+    // the rejected escalation did not use up the channel's last token
+    assert.Nil(t, limiter.Wait(ctx, "U2", "C1", nil))
+
+    err = limiter.Wait(ctx, "U3", "C1", nil)
+    exceeded, ok = err.(*Exceeded)
+    assert.True(t, ok)
+    assert.Equal(t, ScopeChannel, exceeded.Scope)
+
+    assert.Nil(t, limiter.Wait(ctx, "U3", "C2", nil))
+
+}
+
+func TestWaitQueue(t *testing.T) {
+    limiter, err := New(Limit{}, Limit{}, Limit{Burst: 1, Per: 50 * time.Millisecond}, OverflowQueue, time.Second)
+    assert.Nil(t, err)
+
+    ctx := context.Background()
+    assert.Nil(t, limiter.Wait(ctx, "U1", "C1", nil))
+
+    var queuedScope string
+    start := time.Now()
+    assert.Nil(t, limiter.Wait(ctx, "U2", "C2", func(scope string, delay time.Duration) {
+        queuedScope = scope
+    }))
+    assert.Equal(t, ScopeGlobal, queuedScope)
+    assert.True(t, time.Since(start) >= 40 * time.Millisecond)
+
+    // waits longer than MaxWait are rejected
+    limiter.MaxWait = time.Millisecond
+    _, ok := limiter.Wait(ctx, "U3", "C3", nil).(*Exceeded)
+    assert.True(t, ok)
+
+    _, err = New(Limit{}, Limit{}, Limit{}, "drop", 0)
+    assert.NotNil(t, err)
+
+}
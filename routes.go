@@ -0,0 +1,148 @@
+package runtime
+
+import (
+    "fmt"
+    "strings"
+
+    "slack-jira-integration/chat"
+)
+
+// Route sends escalations from Channel (a channel name or id) to the named
+// Jira Backend, an empty Emoji matches the channel's configured emoji while
+// a set Emoji makes that reaction an additional trigger for the channel, a
+// set Policy names the AccessPolicy deciding who may escalate
+type Route struct {
+    Channel string
+    Emoji string
+    Backend string
+    Policy string
+}
+
+// ParseRoutes, parses a comma separated list of channel[:emoji]=backend[@policy]
+// rules e.g. "eng-alerts:fire=eng@oncall,it-help=it,general=@staff", the
+// backend may be left out to keep the default one
+func ParseRoutes(routes string) ([]Route, error) {
+    var parsed []Route
+
+    for _, rule := range strings.Split(routes, ",") {
+        rule = strings.TrimSpace(rule)
+        if rule == "" {
+            continue
+        }
+
+        parts := strings.SplitN(rule, "=", 2)
+        if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+            return nil, fmt.Errorf("invalid route '%s', expected channel[:emoji]=backend", rule)
+        }
+
+        route := Route{Channel: parts[0], Backend: parts[1]}
+        if i := strings.Index(parts[1], "@"); i >= 0 {
+            route.Backend = parts[1][:i]
+            route.Policy = parts[1][i+1:]
+            if route.Policy == "" {
+                return nil, fmt.Errorf("invalid route '%s', empty access policy", rule)
+            }
+        }
+
+        if i := strings.Index(parts[0], ":"); i >= 0 {
+            route.Channel = parts[0][:i]
+            route.Emoji = strings.Trim(parts[0][i+1:], ":")
+        }
+
+        parsed = append(parsed, route)
+
+    }
+
+    return parsed, nil
+
+}
+
+// matchesChannel, channels may be routed by id or by the name they were configured with
+func (route *Route) matchesChannel(frontend chat.Frontend, channelID string) bool {
+    return route.Channel == channelID || route.Channel == frontend.ChannelName(channelID)
+
+}
+
+// WithBackends, register named ticket backends and the routes choosing
+// between them, unrouted escalations keep going to Backend
+func (r *runtime) WithBackends(backends map[string]TicketBackend, routes []Route) (*runtime, error) {
+    for _, route := range routes {
+        if _, exists := backends[route.Backend]; route.Backend != "" && !exists {
+            return nil, fmt.Errorf("route for channel '%s' uses unknown ticket backend '%s'", route.Channel, route.Backend)
+        }
+    }
+
+    r.Backends = backends
+    r.Routes = routes
+
+    return r, nil
+
+}
+
+// routeFor, determine if the given channelID and reaction(emoji) should be
+// processed and by which route, nil when only the channel's emoji matched
+func (r *runtime) routeFor(frontend chat.Frontend, channelID string, reaction string) (*Route, bool) {
+    // reactions named explicitly by a route win over the channel's emoji
+    for i, route := range r.Routes {
+        if route.Emoji != "" && route.Emoji == reaction && route.matchesChannel(frontend, channelID) {
+            return &r.Routes[i], true
+        }
+    }
+
+    if !r.channelEmojiCombinationMatches(frontend, channelID, reaction) {
+        return nil, false
+    }
+
+    for i, route := range r.Routes {
+        if route.Emoji == "" && route.matchesChannel(frontend, channelID) {
+            return &r.Routes[i], true
+        }
+    }
+
+    return nil, true
+
+}
+
+// matchRoute, determine if the given channelID and reaction(emoji) should be
+// processed and if so which TicketBackend the ticket belongs in and under which
+// AccessPolicy
+func (r *runtime) matchRoute(frontend chat.Frontend, channelID string, reaction string) (TicketBackend, *AccessPolicy, bool) {
+    route, matches := r.routeFor(frontend, channelID, reaction)
+    if !matches {
+        return nil, nil, false
+    }
+
+    backend, policy := r.Backend, r.AccessPolicy
+    if route != nil && route.Backend != "" {
+        backend = r.Backends[route.Backend]
+    }
+    if route != nil && route.Policy != "" {
+        policy = r.AccessPolicies[route.Policy]
+    }
+
+    return backend, policy, true
+
+}
+
+// channelRoute, the TicketBackend and AccessPolicy of the route for channelID
+// regardless of the reaction, used where there is no reaction to match e.g.
+// slash commands
+func (r *runtime) channelRoute(frontend chat.Frontend, channelID string) (TicketBackend, *AccessPolicy) {
+    backend, policy := r.Backend, r.AccessPolicy
+    for _, route := range r.Routes {
+        if route.Emoji != "" || !route.matchesChannel(frontend, channelID) {
+            continue
+        }
+
+        if route.Backend != "" {
+            backend = r.Backends[route.Backend]
+        }
+        if route.Policy != "" {
+            policy = r.AccessPolicies[route.Policy]
+        }
+        break
+    }
+
+    return backend, policy
+
+}
@@ -0,0 +1,55 @@
+package runtime
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+
+    "slack-jira-integration/jira"
+)
+
+func TestParseRoutes(t *testing.T) {
+    routes, err := ParseRoutes("eng-alerts:fire:=eng, it-help=it,")
+
+    assert.Nil(t, err)
+    assert.Equal(t, []Route{
+        {Channel: "eng-alerts", Emoji: "fire", Backend: "eng"},
+        {Channel: "it-help", Backend: "it"},
+    }, routes)
+
+    _, err = ParseRoutes("it-help")
+    assert.NotNil(t, err)
+
+}
+
+func TestMatchRoute(t *testing.T) {
+    r := newRuntime(t)
+    r.SlackEnv.SlackChannelNamesByID = map[string]string{"SOMECHANNELID": "some-channel-name"}
+
+    engEnv := &jira.JiraEnv{JiraProject: "ENG"}
+    itEnv := &jira.JiraEnv{JiraProject: "IT"}
+
+    _, err := r.WithBackends(map[string]TicketBackend{"eng": engEnv, "it": itEnv}, []Route{
+        {Channel: "some-channel-name", Emoji: "computer", Backend: "it"},
+        {Channel: "SOMECHANNELID", Backend: "eng"},
+    })
+    assert.Nil(t, err)
+
+    backend, _, matches := r.matchRoute(r.SlackEnv, "SOMECHANNELID", "some-emoji")
+    assert.True(t, matches)
+    assert.True(t, backend == engEnv)
+
+    backend, _, matches = r.matchRoute(r.SlackEnv, "SOMECHANNELID", "computer")
+    assert.True(t, matches)
+    assert.True(t, backend == itEnv)
+
+    _, _, matches = r.matchRoute(r.SlackEnv, "SOMECHANNELID", "other-emoji")
+    assert.False(t, matches)
+
+    _, _, matches = r.matchRoute(r.SlackEnv, "OTHERCHANNELID", "some-emoji")
+    assert.False(t, matches)
+
+    _, err = r.WithBackends(map[string]TicketBackend{"eng": engEnv}, []Route{{Channel: "some-channel-name", Backend: "it"}})
+    assert.NotNil(t, err)
+
+}
@@ -1,100 +1,661 @@
 package runtime
 
 import (
+    "context"
+    "errors"
     "fmt"
 	"io/ioutil"
 	"encoding/json"
 	"net/http"
+    "sync"
+    "time"
 
+    "slack-jira-integration/audit"
+    "slack-jira-integration/chat"
+    "slack-jira-integration/logging"
+    "slack-jira-integration/metrics"
+    "slack-jira-integration/ratelimit"
+    "slack-jira-integration/tracing"
     "slack-jira-integration/slack"
-    "slack-jira-integration/jira"
 
+	"github.com/sirupsen/logrus"
 	"github.com/slack-go/slack/slackevents"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type runtime struct {
-    JiraEnv *jira.JiraEnv 
+    Backend TicketBackend
+    Backends map[string]TicketBackend
+    Routes []Route
     SlackEnv *slack.SlackEnv
+    SlackWorkspaces *slack.Workspaces
+    Audit audit.Sink
+    AccessPolicy *AccessPolicy
+    AccessPolicies map[string]*AccessPolicy
+    RateLimiter *ratelimit.Limiter
+    Votes *Votes
+    Undo *Undo
+    Unfurls *Unfurls
+    Listeners []func(context.Context, chat.Event)
+
+    mu sync.Mutex
+    draining bool
+    inflight int
+    idle chan struct{}
+    delivered map[string]time.Time
+}
+
+// eventRetryWindow, how long an event_id is remembered, Slack gives up
+// resending an event after its third retry about 5 minutes later
+const eventRetryWindow = 10 * time.Minute
+
+// detachedContext carries the values of the request that started work which
+// outlives it, e.g. the logging fields, but not its cancellation
+type detachedContext struct {
+    context.Context
 }
 
-// New, create a new runtime, given a SlackEnv and JiraEnv
-func New(slackEnv *slack.SlackEnv, jiraEnv *jira.JiraEnv) *runtime {
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{} { return nil }
+func (detachedContext) Err() error { return nil }
+
+// New, create a new runtime, given a SlackEnv and the default TicketBackend
+func New(slackEnv *slack.SlackEnv, backend TicketBackend) *runtime {
     return &runtime{        
         SlackEnv: slackEnv,
-        JiraEnv: jiraEnv,
+        Backend: backend,
+    }
+
+}
+
+// WithWorkspaces, serve every workspace installed through the OAuth flow,
+// SlackEnv (if any) is still used for events from teams with no installation
+func (r *runtime) WithWorkspaces(workspaces *slack.Workspaces) *runtime {
+    r.SlackWorkspaces = workspaces
+    return r
+
+}
+
+// WithAudit, record every escalation to sink
+func (r *runtime) WithAudit(sink audit.Sink) *runtime {
+    r.Audit = sink
+    return r
+
+}
+
+// WithRateLimiter, limit how fast issues can be created per user, per
+// channel and overall
+func (r *runtime) WithRateLimiter(limiter *ratelimit.Limiter) *runtime {
+    r.RateLimiter = limiter
+    return r
+
+}
+
+// WithListener, call listener with every chat.Event published while escalating
+func (r *runtime) WithListener(listener func(context.Context, chat.Event)) *runtime {
+    r.Listeners = append(r.Listeners, listener)
+    return r
+
+}
+
+// publish, hand event to every listener
+func (r *runtime) publish(ctx context.Context, event chat.Event) {
+    for _, listener := range r.Listeners {
+        listener(ctx, event)
+    }
+
+}
+
+// audit, append the outcome of an escalation to the audit sink, a failed
+// write is logged but never fails the escalation itself
+func (r *runtime) audit(ctx context.Context, record audit.Record) {
+    if r.Audit == nil {
+        return
+    }
+
+    if err := r.Audit.Write(ctx, record); err != nil {
+        logging.FromContext(ctx).WithError(err).Error("audit record not written")
+    }
+
+}
+
+// slackEnv, select the SlackEnv for the team (or Enterprise Grid org) an event came from
+func (r *runtime) slackEnv(teamID string, enterpriseID string) (*slack.SlackEnv, error) {
+    if r.SlackWorkspaces != nil {
+        env, err := r.SlackWorkspaces.Get(teamID, enterpriseID)
+        if err == nil || r.SlackEnv == nil {
+            return env, err
+        }
+    }
+
+    if r.SlackEnv == nil {
+        return nil, fmt.Errorf("no slack env for team '%s'", teamID)
+    }
+
+    return r.SlackEnv, nil
+
+}
+
+// begin, register an in-flight event, false once the runtime is draining
+func (r *runtime) begin() bool {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if r.draining {
+        return false
+    }
+
+    if r.inflight == 0 {
+        r.idle = make(chan struct{})
+    }
+    r.inflight++
+
+    return true
+
+}
+
+// end, the event registered by begin has been handled
+func (r *runtime) end() {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.inflight--
+    if r.inflight == 0 {
+        close(r.idle)
     }
 
 }
 
+// Wait, wait until no event is in flight or ctx is done, events are still
+// accepted meanwhile
+func (r *runtime) Wait(ctx context.Context) error {
+    r.mu.Lock()
+    idle, busy := r.idle, r.inflight > 0
+    r.mu.Unlock()
+
+    if !busy {
+        return nil
+    }
+
+    select {
+    case <-idle:
+        return nil
+
+    case <-ctx.Done():
+        return fmt.Errorf("wait for events err: %w", ctx.Err())
+    }
+
+}
+
+// Drain, stop accepting events and wait until every in-flight escalation
+// has finished or ctx is done
+func (r *runtime) Drain(ctx context.Context) error {
+    r.mu.Lock()
+    r.draining = true
+    r.mu.Unlock()
+
+    if err := r.Wait(ctx); err != nil {
+        return fmt.Errorf("drain escalations err: %w", err)
+    }
+
+    return nil
+
+}
+
+// redelivered, whether the event was already accepted, Slack resends events
+// it did not see acked in time and each would escalate again
+func (r *runtime) redelivered(eventsAPIEvent slackevents.EventsAPIEvent) bool {
+    callbackEvent, ok := eventsAPIEvent.Data.(*slackevents.EventsAPICallbackEvent)
+    if !ok || callbackEvent.EventID == "" {
+        return false
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    now := time.Now()
+    if delivered, exists := r.delivered[callbackEvent.EventID]; exists && now.Sub(delivered) < eventRetryWindow {
+        return true
+    }
+
+    if r.delivered == nil {
+        r.delivered = make(map[string]time.Time)
+    }
+    for eventID, delivered := range r.delivered {
+        if now.Sub(delivered) >= eventRetryWindow {
+            delete(r.delivered, eventID)
+        }
+    }
+    r.delivered[callbackEvent.EventID] = now
+
+    return false
+
+}
+
+// dispatch, handle the event in the background so it is acked at once, the
+// rate limiter alone may hold an escalation longer than Slack's 3 second ack
+// window. Redelivered events are dropped, false once the runtime is draining
+func (r *runtime) dispatch(ctx context.Context, eventsAPIEvent slackevents.EventsAPIEvent) bool {
+    if !r.begin() {
+        return false
+    }
+
+    if r.redelivered(eventsAPIEvent) {
+        logging.FromContext(withEventFields(ctx, eventsAPIEvent)).Info("event redelivered, dropped")
+        r.end()
+        return true
+    }
+
+    go func() {
+        defer r.end()
+        r.handleEventsAPIEvent(detachedContext{ctx}, eventsAPIEvent)
+    }()
+
+    return true
+
+}
+
 // SlackEventsHandler, main server handler accepts requests from Slack client
-// and routes Slack event type to right function
+// and routes Slack event type to right function, the request is answered
+// before the event is handled
 func (r *runtime) SlackEventsHandler(resp http.ResponseWriter, req *http.Request) {
 	body, err := ioutil.ReadAll(req.Body)
-    fmt.Println(string(body))
 	if err != nil {
 		resp.WriteHeader(http.StatusBadRequest)
 		return
 
 	}
 
-	eventsAPIEvent, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
-	if err != nil {
-		resp.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+    // the signature middleware has already parsed the event
+    eventsAPIEvent, ok := slack.EventFromContext(req.Context())
+    if !ok {
+        eventsAPIEvent, err = slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
+        if err != nil {
+            resp.WriteHeader(http.StatusBadRequest)
+            return
+        }
+    }
+
+    // a draining pod answers 503 so Slack retries the event elsewhere
+    if !r.dispatch(req.Context(), eventsAPIEvent) {
+        resp.WriteHeader(http.StatusServiceUnavailable)
+        return
+    }
+
+	resp.Write(body)
+
+}
+
+// HandleEventsAPIEvent, routes Slack event type to right function in the
+// background, the entry point of Socket Mode
+func (r *runtime) HandleEventsAPIEvent(ctx context.Context, eventsAPIEvent slackevents.EventsAPIEvent) {
+    if !r.dispatch(ctx, eventsAPIEvent) {
+        logging.FromContext(ctx).Warn("draining, event dropped")
+    }
+
+}
+
+func (r *runtime) handleEventsAPIEvent(ctx context.Context, eventsAPIEvent slackevents.EventsAPIEvent) {
+    ctx = withEventFields(ctx, eventsAPIEvent)
+    log := logging.FromContext(ctx)
+
+    ctx, span := tracing.Start(ctx, "slack.event",
+        attribute.String("slack.team_id", eventsAPIEvent.TeamID),
+        attribute.String("slack.event_type", eventsAPIEvent.InnerEvent.Type))
+    defer span.End()
 
 	if eventsAPIEvent.Type == slackevents.CallbackEvent {
 		innerEvent := eventsAPIEvent.InnerEvent
+        log.WithField("event_type", innerEvent.Type).Debug("event received")
+        metrics.EventsReceived.WithLabelValues(innerEvent.Type).Inc()
+
         switch ev := innerEvent.Data.(type) {
         case *slackevents.ReactionAddedEvent:
-            r.reactionAddedEvent(ev)
-        default:
-            fmt.Println(fmt.Sprintf("ev: %+v", ev))
+            slackEnv, err := r.slackEnv(eventsAPIEvent.TeamID, eventsAPIEvent.EnterpriseID)
+            if err != nil {
+                log.WithError(err).Error("no slack env for event")
+                break
+            }
+
+            r.handleReactionAdded(ctx, slackEnv, slack.ReactionAdded(eventsAPIEvent.TeamID, ev))
+
+        case *slackevents.ReactionRemovedEvent:
+            slackEnv, err := r.slackEnv(eventsAPIEvent.TeamID, eventsAPIEvent.EnterpriseID)
+            if err != nil {
+                log.WithError(err).Error("no slack env for event")
+                break
+            }
+
+            r.reactionRemovedEvent(ctx, slackEnv, slack.ReactionRemoved(eventsAPIEvent.TeamID, ev))
+
+        case *slackevents.LinkSharedEvent:
+            slackEnv, err := r.slackEnv(eventsAPIEvent.TeamID, eventsAPIEvent.EnterpriseID)
+            if err != nil {
+                log.WithError(err).Error("no slack env for event")
+                break
+            }
+
+            if err := r.linkSharedEvent(ctx, slackEnv, ev); err != nil {
+                log.WithError(err).Error("unfurl failed")
+            }
+
+        case *slackevents.MessageEvent:
+            slackEnv, err := r.slackEnv(eventsAPIEvent.TeamID, eventsAPIEvent.EnterpriseID)
+            if err != nil {
+                log.WithError(err).Error("no slack env for event")
+                break
+            }
+
+            if err := r.messageEvent(ctx, slackEnv, ev); err != nil {
+                log.WithError(err).Error("unfurl failed")
+            }
         }
 	}
 
-	resp.Write(body)
+}
+
+// HandleReactionAdded, escalate the thread a reaction was added to in the
+// background, the entry point of frontends other than Slack
+func (r *runtime) HandleReactionAdded(ctx context.Context, frontend chat.Frontend, ev *chat.ReactionAdded) {
+    if !r.begin() {
+        logging.FromContext(ctx).Warn("draining, event dropped")
+        return
+    }
+
+    ctx = logging.WithFields(ctx, logrus.Fields{"team_id": ev.TeamID, "frontend": frontend.Name()})
+    metrics.EventsReceived.WithLabelValues("reaction_added").Inc()
+
+    go func() {
+        defer r.end()
+        r.handleReactionAdded(detachedContext{ctx}, frontend, ev)
+    }()
+
+}
+
+// HandleReactionRemoved, withdraw the vote or escalation of a removed
+// reaction in the background, the entry point of frontends other than Slack
+func (r *runtime) HandleReactionRemoved(ctx context.Context, frontend chat.Frontend, ev *chat.ReactionRemoved) {
+    if !r.begin() {
+        logging.FromContext(ctx).Warn("draining, event dropped")
+        return
+    }
+
+    ctx = logging.WithFields(ctx, logrus.Fields{"team_id": ev.TeamID, "frontend": frontend.Name()})
+    metrics.EventsReceived.WithLabelValues("reaction_removed").Inc()
+
+    go func() {
+        defer r.end()
+        r.reactionRemovedEvent(detachedContext{ctx}, frontend, ev)
+    }()
+
+}
+
+func (r *runtime) handleReactionAdded(ctx context.Context, frontend chat.Frontend, ev *chat.ReactionAdded) {
+    metrics.EscalationsInFlight.Inc()
+    defer metrics.EscalationsInFlight.Dec()
+
+    if err := r.reactionAddedEvent(ctx, frontend, ev); err != nil {
+        logging.FromContext(ctx).WithError(err).Error("escalation failed")
+        metrics.Escalations.WithLabelValues("failed").Inc()
+    }
+
+}
+
+// withEventFields, correlate every log line written while handling the event
+// by its Slack event_id and team
+func withEventFields(ctx context.Context, eventsAPIEvent slackevents.EventsAPIEvent) context.Context {
+    fields := logrus.Fields{"team_id": eventsAPIEvent.TeamID}
+
+    if callbackEvent, ok := eventsAPIEvent.Data.(*slackevents.EventsAPICallbackEvent); ok {
+        fields["event_id"] = callbackEvent.EventID
+    }
+
+    return logging.WithFields(ctx, fields)
 
 }
 
 // channelEmojiCombinationMatches, logic to determine if given channelID and reaction(emoji)
 // should be processed
-func (r *runtime) channelEmojiCombinationMatches(channelID string, reaction string) bool {
-    emoji, exists := r.SlackEnv.SlackEmojis[channelID]
+func (r *runtime) channelEmojiCombinationMatches(frontend chat.Frontend, channelID string, reaction string) bool {
+    emoji, exists := frontend.ChannelEmoji(channelID)
     return exists && reaction == emoji
 
 }
 
-// reactionAddedEvent, handle a ReactionAddedEvent(emoji added) to top level thread
-func (r *runtime) reactionAddedEvent(ev *slackevents.ReactionAddedEvent) error {
+// reactionAddedEvent, handle a ReactionAdded(emoji added) to top level thread
+func (r *runtime) reactionAddedEvent(ctx context.Context, frontend chat.Frontend, ev *chat.ReactionAdded) (err error) {
     // noop if channel and reaction do not exist or match desired channel/emoji combination
-    if !r.channelEmojiCombinationMatches(ev.Item.Channel, ev.Reaction) {
+    backend, policy, matches := r.matchRoute(frontend, ev.Channel, ev.Reaction)
+    if !matches {
+        return nil
+    }
+
+    ctx, span := tracing.Start(ctx, "reactionAddedEvent",
+        attribute.String("slack.channel", ev.Channel),
+        attribute.String("slack.message_ts", ev.MessageID),
+        attribute.String("slack.reaction", ev.Reaction),
+        attribute.String("jira.project", backend.TicketProject()))
+    span.SetAttributes(attribute.String("chat.frontend", frontend.Name()))
+    record := audit.Record{
+        Time: time.Now(),
+        Action: "escalate",
+        TeamID: ev.TeamID,
+        UserID: ev.User,
+        ChannelID: ev.Channel,
+        MessageTS: ev.MessageID,
+        Emoji: ev.Reaction,
+        Project: backend.TicketProject(),
+    }
+    defer func() {
+        tracing.End(span, err)
+
+        if record.Outcome == "" {
+            record.Outcome = audit.OutcomeCreated
+        }
+        if err != nil {
+            record.Outcome, record.Error = audit.OutcomeFailed, err.Error()
+        }
+
+        // with no issue created a later vote may try again
+        if record.IssueKey == "" && (err != nil || record.Outcome == audit.OutcomeRateLimited) {
+            r.Votes.Reopen(ev.Channel, ev.MessageID, ev.Reaction)
+        }
+        r.audit(ctx, record)
+    }()
+
+    ctx = logging.WithFields(ctx, logrus.Fields{
+        "channel": ev.Channel,
+        "message_ts": ev.MessageID,
+        "user": ev.User,
+        "reaction": ev.Reaction,
+        "jira_project": backend.TicketProject(),
+    })
+
+    // check the reacting user before anything is read from the thread
+    stepCtx, stepSpan := tracing.Start(ctx, "authorize")
+    reason, allowed, err := policy.Authorize(stepCtx, frontend, ev.User)
+    tracing.End(stepSpan, err)
+
+    if err != nil {
+        return err
+    }
+
+    if !allowed {
+        logging.FromContext(ctx).WithField("reason", reason).Info("escalation denied")
+        metrics.Escalations.WithLabelValues(audit.OutcomeDenied).Inc()
+        record.Outcome, record.Error = audit.OutcomeDenied, reason
+
+        r.publish(ctx, chat.EscalationDenied{
+            Frontend: frontend.Name(),
+            Channel: ev.Channel,
+            MessageID: ev.MessageID,
+            User: ev.User,
+            Reason: reason,
+        })
+
+        return frontend.PostEphemeral(ctx, ev.Channel, ev.User, fmt.Sprintf("Sorry, %s.", reason))
+    }
+
+    // noisy channels only escalate once enough distinct users have reacted
+    if reached, total, needed := r.Votes.Add(frontend, ev.Channel, ev.MessageID, ev.Reaction, ev.User); !reached {
+        logging.FromContext(ctx).WithFields(logrus.Fields{"votes": total, "threshold": needed}).Info("escalation vote counted")
+        record.Outcome = audit.OutcomeVoted
         return nil
     }
 
+    // over a limit the escalation is either refused or held until a token frees up
+    err = r.RateLimiter.Wait(ctx, ev.User, ev.Channel, func(scope string, delay time.Duration) {
+        logging.FromContext(ctx).WithFields(logrus.Fields{"scope": scope, "delay": delay.String()}).Info("escalation queued by rate limit")
+        metrics.RateLimited.WithLabelValues(scope, "queued").Inc()
+    })
+
+    var exceeded *ratelimit.Exceeded
+    if errors.As(err, &exceeded) {
+        logging.FromContext(ctx).WithField("scope", exceeded.Scope).Info("escalation rate limited")
+        metrics.RateLimited.WithLabelValues(exceeded.Scope, "rejected").Inc()
+        metrics.Escalations.WithLabelValues(audit.OutcomeRateLimited).Inc()
+        record.Outcome, record.Error = audit.OutcomeRateLimited, exceeded.Error()
+
+        return frontend.PostEphemeral(ctx, ev.Channel, ev.User, fmt.Sprintf(
+            "Sorry, too many Jira issues have been created (%s limit), try again in %s.",
+            exceeded.Scope, exceeded.RetryAfter.Round(time.Second)))
+    }
+
+    if err != nil {
+        return err
+    }
+
     // get all messages in the current conversation
-    messages, err := r.SlackEnv.GetConversationMessages(ev.Item.Channel, ev.Item.Timestamp)
+    stepCtx, stepSpan = tracing.Start(ctx, "getConversationReplies")
+    messages, err := frontend.ThreadMessages(stepCtx, ev.Channel, ev.MessageID)
+    tracing.End(stepSpan, err)
 
     if err != nil {
         return err
     }
 
-    // create a Jira issue with the text of the first message in the thread
-    createdIssue, err := r.JiraEnv.CreateJiraIssue(messages[0].Msg.Text)
+    // a thread already discussing a ticket is linked to it instead
+    if found := mentionedTicket(ctx, backend, messages); found != nil {
+        record.IssueKey, record.Outcome = found.Key, audit.OutcomeLinked
+        span.SetAttributes(attribute.String("jira.issue_key", found.Key))
+
+        replyID, err := r.linkThread(ctx, frontend, backend, ev.Channel, ev.MessageID, ev.User, found)
+
+        // the reactor may take the link back the same way
+        r.Undo.remember(ev.Channel, ev.MessageID, ev.Reaction, &escalation{
+            user: ev.User,
+            issueKey: found.Key,
+            backend: backend,
+            replyTimestamp: replyID,
+            link: found.URL,
+            linked: true,
+        })
+
+        return err
+    }
+
+    // create a ticket with the text of the first message in the thread
+    stepCtx, stepSpan = tracing.Start(ctx, "createIssue")
+    created, err := createTicket(stepCtx, backend, messages[0].Text)
+    tracing.End(stepSpan, err)
 
     if err != nil {
         return err
     }
 
-    jiraUrlToIssue := fmt.Sprintf("%sbrowse/%s", r.JiraEnv.JiraUrl, createdIssue.Key)
+    issueKey := created.Key
+    record.IssueKey = issueKey
+    span.SetAttributes(attribute.String("jira.issue_key", issueKey))
+    logging.FromContext(ctx).WithField("issue_key", issueKey).Info("jira issue created")
+    metrics.IssuesCreated.WithLabelValues(channelLabel(frontend, ev.Channel), backend.TicketProject()).Inc()
 
-    // post back to slack thread with link to jira issue created
-    err = r.SlackEnv.PostMessageToThread(
-        ev.Item.Channel,
-        ev.Item.Timestamp,
-        jiraUrlToIssue)
+    issueUrl := backend.TicketURL(issueKey)
+    r.publish(ctx, chat.ThreadEscalated{
+        Frontend: frontend.Name(),
+        Channel: ev.Channel,
+        MessageID: ev.MessageID,
+        User: ev.User,
+        Reaction: ev.Reaction,
+        Project: backend.TicketProject(),
+        TicketKey: issueKey,
+        TicketURL: issueUrl,
+    })
+
+    // the ticket links back to the conversation, without the link it is still useful
+    stepCtx, stepSpan = tracing.Start(ctx, "linkBack")
+    linkErr := linkBack(stepCtx, frontend, backend, ev.Channel, ev.MessageID, issueKey)
+    tracing.End(stepSpan, linkErr)
+
+    if linkErr != nil {
+        logging.FromContext(ctx).WithError(linkErr).WithField("issue_key", issueKey).Warn("link back to thread failed")
+    }
+
+    // post back to the thread with link to the ticket created, a card shows
+    // what creating it told about the ticket, the rest fills in once acted on
+    stepCtx, stepSpan = tracing.Start(ctx, "postMessage")
+    replyTimestamp, replyText, err := postTicket(stepCtx, frontend, ev.Channel, ev.MessageID, *created, issueUrl)
+    tracing.End(stepSpan, err)
+
+    // the reactor may take it back by removing the reaction within the grace period
+    r.Undo.remember(ev.Channel, ev.MessageID, ev.Reaction, &escalation{
+        user: ev.User,
+        issueKey: issueKey,
+        backend: backend,
+        replyTimestamp: replyTimestamp,
+        link: issueUrl,
+    })
+
+    if err != nil {
+        return err
+    }
+
+    metrics.Escalations.WithLabelValues("created").Inc()
+    r.publish(ctx, chat.ReplyPosted{
+        Frontend: frontend.Name(),
+        Channel: ev.Channel,
+        MessageID: ev.MessageID,
+        ReplyID: replyTimestamp,
+        Text: replyText,
+    })
+
+    return nil
+
+}
+
+// reactionRemovedEvent, handle a ReactionRemoved(emoji removed) by
+// withdrawing the user's vote
+func (r *runtime) reactionRemovedEvent(ctx context.Context, frontend chat.Frontend, ev *chat.ReactionRemoved) {
+    if _, _, matches := r.matchRoute(frontend, ev.Channel, ev.Reaction); !matches {
+        return
+    }
+
+    if escalation, exists := r.Undo.take(ev.Channel, ev.MessageID, ev.Reaction, ev.User); exists {
+        // the issue still exists when the undo failed, escalating the
+        // message again would create a second one
+        if err := r.undoEscalation(ctx, frontend, ev, escalation); err != nil {
+            logging.FromContext(ctx).WithError(err).Error("undo escalation failed")
+        } else {
+            r.Votes.Reopen(ev.Channel, ev.MessageID, ev.Reaction)
+        }
+    }
+
+    r.Votes.Remove(ev.Channel, ev.MessageID, ev.Reaction, ev.User)
+    logging.FromContext(ctx).WithFields(logrus.Fields{
+        "channel": ev.Channel,
+        "message_ts": ev.MessageID,
+        "user": ev.User,
+        "reaction": ev.Reaction,
+    }).Debug("escalation vote withdrawn")
+
+}
+
+// channelLabel, prefer the configured channel name as metric label
+func channelLabel(frontend chat.Frontend, channelID string) string {
+    if channelName := frontend.ChannelName(channelID); channelName != "" {
+        return channelName
+    }
 
-    return err
+    return channelID
 
 }
 
@@ -1,14 +1,21 @@
 package runtime 
 
 import (
+    "context"
     "net/http"
     "net/http/httptest"
+    "path/filepath"
     "testing"
     "strings"
+    "time"
 
     "github.com/golang/mock/gomock"
     "github.com/stretchr/testify/assert"
+    "github.com/slack-go/slack/slackevents"
 
+    "slack-jira-integration/audit"
+    "slack-jira-integration/chat"
+    "slack-jira-integration/ratelimit"
     "slack-jira-integration/slack"
     "slack-jira-integration/jira"
 )
@@ -39,7 +46,7 @@ func newRuntime(t *testing.T) *runtime {
 
     return &runtime{
         SlackEnv: slackEnv,
-        JiraEnv: jiraEnv, 
+        Backend: jiraEnv,
 
     }
 
@@ -97,4 +104,132 @@ func TestSlackEventsHandler(t *testing.T) {
     // Check the response body is what we expect.
     assert.Equal(t, rr.Body.String(), reactionAddedEventPayload)
 
+    // the event is handled after the response
+    assert.Nil(t, r.Wait(context.Background()))
+
+}
+
+func TestDrain(t *testing.T) {
+    r := newRuntime(t)
+
+    // an escalation still running keeps the drain waiting
+    assert.True(t, r.begin())
+
+    ctx, cancel := context.WithTimeout(context.Background(), 10 * time.Millisecond)
+    defer cancel()
+    assert.NotNil(t, r.Drain(ctx))
+
+    // new events are refused while draining
+    rr := httptest.NewRecorder()
+    r.SlackEventsHandler(rr, httptest.NewRequest("POST", "/slack/events", strings.NewReader(reactionAddedEventPayload)))
+    assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+    r.end()
+    assert.Nil(t, r.Drain(context.Background()))
+
+}
+
+func TestRedelivered(t *testing.T) {
+    r := newRuntime(t)
+    event := func(eventID string) slackevents.EventsAPIEvent {
+        return slackevents.EventsAPIEvent{Data: &slackevents.EventsAPICallbackEvent{EventID: eventID}}
+    }
+
+    assert.False(t, r.redelivered(event("Ev0SOMEEVENT")))
+    assert.False(t, r.redelivered(event("Ev0OTHEREVENT")))
+
+    // Slack resent the event as the ack was late
+    assert.True(t, r.redelivered(event("Ev0SOMEEVENT")))
+
+    // events without an id are never dropped
+    assert.False(t, r.redelivered(slackevents.EventsAPIEvent{}))
+    assert.False(t, r.redelivered(slackevents.EventsAPIEvent{}))
+
+    // ids are forgotten once Slack stopped retrying
+    r.delivered["Ev0SOMEEVENT"] = time.Now().Add(-eventRetryWindow)
+    assert.False(t, r.redelivered(event("Ev0SOMEEVENT")))
+
+}
+
+func TestReactionAddedEventAudit(t *testing.T) {
+    sink, err := audit.NewFileSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+    assert.Nil(t, err)
+
+    r := newTracedRuntime(t).WithAudit(sink)
+
+    ev := &chat.ReactionAdded{
+        User: "UCJLPB2AG",
+        Reaction: "some-emoji",
+        Channel: "SOMECHANNELID",
+        MessageID: "1641160687.000200",
+    }
+
+    // a reaction that matches no route is not an escalation and is not audited
+    assert.Nil(t, r.reactionAddedEvent(context.Background(), r.SlackEnv, &chat.ReactionAdded{Reaction: "other-emoji"}))
+    assert.Nil(t, r.reactionAddedEvent(context.Background(), r.SlackEnv, ev))
+
+    records, err := sink.Search(context.Background(), audit.Query{})
+    assert.Nil(t, err)
+    assert.Equal(t, 1, len(records))
+    assert.Equal(t, "UCJLPB2AG", records[0].UserID)
+    assert.Equal(t, "SOMECHANNELID", records[0].ChannelID)
+    assert.Equal(t, "some-emoji", records[0].Emoji)
+    assert.Equal(t, "TEST-1", records[0].IssueKey)
+    assert.Equal(t, audit.OutcomeCreated, records[0].Outcome)
+
+}
+
+func TestReactionAddedEventRateLimited(t *testing.T) {
+    sink, err := audit.NewFileSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+    assert.Nil(t, err)
+
+    limiter, err := ratelimit.New(ratelimit.Limit{Burst: 1, Per: time.Hour}, ratelimit.Limit{}, ratelimit.Limit{}, ratelimit.OverflowReject, 0)
+    assert.Nil(t, err)
+
+    r := newTracedRuntime(t).WithAudit(sink).WithRateLimiter(limiter)
+
+    ev := &chat.ReactionAdded{
+        User: "UCJLPB2AG",
+        Reaction: "some-emoji",
+        Channel: "SOMECHANNELID",
+        MessageID: "1641160687.000200",
+    }
+
+    assert.Nil(t, r.reactionAddedEvent(context.Background(), r.SlackEnv, ev))
+    assert.Nil(t, r.reactionAddedEvent(context.Background(), r.SlackEnv, ev))
+
+    records, err := sink.Search(context.Background(), audit.Query{})
+    assert.Nil(t, err)
+    assert.Equal(t, 2, len(records))
+    assert.Equal(t, audit.OutcomeCreated, records[0].Outcome)
+    assert.Equal(t, audit.OutcomeRateLimited, records[1].Outcome)
+
+}
+
+func TestReactionAddedEventPublishesEvents(t *testing.T) {
+    var events []chat.Event
+    r := newTracedRuntime(t).WithListener(func(ctx context.Context, event chat.Event) {
+        events = append(events, event)
+    })
+
+    ev := &chat.ReactionAdded{
+        User: "UCJLPB2AG",
+        Reaction: "some-emoji",
+        Channel: "SOMECHANNELID",
+        MessageID: "1641160687.000200",
+    }
+    assert.Nil(t, r.reactionAddedEvent(context.Background(), r.SlackEnv, ev))
+
+    assert.Equal(t, 2, len(events))
+    escalated, ok := events[0].(chat.ThreadEscalated)
+    assert.True(t, ok)
+    assert.Equal(t, "slack", escalated.Frontend)
+    assert.Equal(t, "TEST-1", escalated.TicketKey)
+    assert.Equal(t, "UCJLPB2AG", escalated.User)
+
+    posted, ok := events[1].(chat.ReplyPosted)
+    assert.True(t, ok)
+    assert.Equal(t, "1641160687.000200", posted.MessageID)
+    assert.Equal(t, escalated.TicketURL, posted.Text)
+
 }
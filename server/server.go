@@ -0,0 +1,95 @@
+package server
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "time"
+)
+
+// Config configures one HTTP listener, TLS is served when both TLSCertFile
+// and TLSKeyFile are set
+type Config struct {
+    Addr string
+    ReadTimeout time.Duration
+    WriteTimeout time.Duration
+    IdleTimeout time.Duration
+    MaxBodyBytes int64
+    TLSCertFile string
+    TLSKeyFile string
+}
+
+// DefaultConfig, the settings used for anything left unset
+func DefaultConfig() Config {
+    return Config{
+        Addr: ":8000",
+        ReadTimeout: 10 * time.Second,
+        WriteTimeout: 10 * time.Second,
+        IdleTimeout: 60 * time.Second,
+        MaxBodyBytes: 1 << 20,
+    }
+
+}
+
+// New, construct a http.Server for handler with the configured timeouts,
+// every request body is capped at MaxBodyBytes
+func New(config Config, handler http.Handler) *http.Server {
+    return &http.Server{
+        Addr: config.Addr,
+        Handler: LimitBody(config.MaxBodyBytes, handler),
+        ReadTimeout: config.ReadTimeout,
+        ReadHeaderTimeout: config.ReadTimeout,
+        WriteTimeout: config.WriteTimeout,
+        IdleTimeout: config.IdleTimeout,
+    }
+
+}
+
+// LimitBody, cap request bodies at maxBytes, reads past the limit fail so
+// oversized payloads are rejected by the handler instead of buffered
+func LimitBody(maxBytes int64, next http.Handler) http.Handler {
+    if maxBytes <= 0 {
+        return next
+    }
+
+    return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+        req.Body = http.MaxBytesReader(resp, req.Body, maxBytes)
+        next.ServeHTTP(resp, req)
+    })
+
+}
+
+// Run, serve until ctx is cancelled then stop accepting connections and wait
+// up to shutdownTimeout for in-flight requests, a listener failure is returned
+// immediately
+func Run(ctx context.Context, srv *http.Server, config Config, shutdownTimeout time.Duration) error {
+    errc := make(chan error, 1)
+    go func() {
+        if config.TLSCertFile != "" && config.TLSKeyFile != "" {
+            errc <- srv.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile)
+        } else {
+            errc <- srv.ListenAndServe()
+        }
+    }()
+
+    select {
+    case err := <-errc:
+        return err
+
+    case <-ctx.Done():
+    }
+
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+    defer cancel()
+
+    if err := srv.Shutdown(shutdownCtx); err != nil {
+        return err
+    }
+
+    if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+        return err
+    }
+
+    return nil
+
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+    "context"
+    "io/ioutil"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestLimitBody(t *testing.T) {
+    handler := LimitBody(4, http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+        if _, err := ioutil.ReadAll(req.Body); err != nil {
+            resp.WriteHeader(http.StatusRequestEntityTooLarge)
+            return
+        }
+    }))
+
+    rr := httptest.NewRecorder()
+    handler.ServeHTTP(rr, httptest.NewRequest("POST", "/", strings.NewReader("ok")))
+    assert.Equal(t, http.StatusOK, rr.Code)
+
+    rr = httptest.NewRecorder()
+    handler.ServeHTTP(rr, httptest.NewRequest("POST", "/", strings.NewReader("too large")))
+    assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+
+}
+
+func TestRunShutsDownOnCancel(t *testing.T) {
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    assert.Nil(t, err)
+    addr := listener.Addr().String()
+    listener.Close()
+
+    config := DefaultConfig()
+    config.Addr = addr
+
+    ctx, cancel := context.WithCancel(context.Background())
+    errc := make(chan error, 1)
+    go func() {
+        errc <- Run(ctx, New(config, http.NotFoundHandler()), config, time.Second)
+    }()
+
+    // wait until the listener accepts connections
+    assert.Eventually(t, func() bool {
+        conn, err := net.Dial("tcp", addr)
+        if err != nil {
+            return false
+        }
+        conn.Close()
+        return true
+    }, time.Second, 10 * time.Millisecond)
+
+    cancel()
+    assert.Nil(t, <-errc)
+
+}
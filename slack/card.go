@@ -0,0 +1,233 @@
+package slack
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "strings"
+
+	"github.com/slack-go/slack"
+
+    "slack-jira-integration/chat"
+)
+
+// the action ids of the card's buttons, each button carries the ticket key
+// as its value
+const (
+    ActionAssignToMe = "jira_assign_to_me"
+    ActionChangePriority = "jira_change_priority"
+    ActionTransition = "jira_transition"
+    ActionComment = "jira_comment"
+    ActionOpen = "jira_open"
+
+    // CardBlockID is the block id of the card's buttons
+    CardBlockID = "jira_card_actions"
+)
+
+// the callback ids of the modals the buttons open and the ids of their single
+// input, the modal's private metadata is the CardRef of the card
+const (
+    CallbackTransition = "jira_transition_modal"
+    CallbackPriority = "jira_priority_modal"
+    CallbackComment = "jira_comment_modal"
+
+    InputBlockID = "jira_input"
+    InputActionID = "jira_input_value"
+)
+
+// CardRef identifies the card a modal was opened from and its ticket
+type CardRef struct {
+    Channel string `json:"channel"`
+    Timestamp string `json:"ts"`
+    Key string `json:"key"`
+}
+
+// orNone, value or a placeholder for an empty card field
+func orNone(value string, none string) string {
+    if value == "" {
+        return none
+    }
+
+    return value
+
+}
+
+// escape, text safe to put into mrkdwn, & first so the entities of < and >
+// are not escaped again, a summary like "<!channel>" would otherwise ping
+// the channel
+func escape(text string) string {
+    return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+
+}
+
+// TicketCard, the Block Kit card of ticket, its key linking to the ticket,
+// summary, status, assignee and priority and the buttons acting on it
+func TicketCard(ticket chat.Ticket) []slack.Block {
+    title := slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*<%s|%s>* %s", ticket.URL, ticket.Key, escape(ticket.Summary)), false, false)
+
+    fields := []*slack.TextBlockObject{
+        slack.NewTextBlockObject(slack.MarkdownType, "*Status*\n" + orNone(escape(ticket.Status), "Unknown"), false, false),
+        slack.NewTextBlockObject(slack.MarkdownType, "*Assignee*\n" + orNone(escape(ticket.Assignee), "Unassigned"), false, false),
+        slack.NewTextBlockObject(slack.MarkdownType, "*Priority*\n" + orNone(escape(ticket.Priority), "None"), false, false),
+    }
+
+    button := func(actionID string, label string) *slack.ButtonBlockElement {
+        return slack.NewButtonBlockElement(actionID, ticket.Key, slack.NewTextBlockObject(slack.PlainTextType, label, false, false))
+    }
+
+    open := button(ActionOpen, "Open in Jira")
+    open.URL = ticket.URL
+
+    return []slack.Block{
+        slack.NewSectionBlock(title, nil, nil),
+        slack.NewSectionBlock(nil, fields, nil),
+        slack.NewActionBlock(CardBlockID,
+            button(ActionAssignToMe, "Assign to me"),
+            button(ActionChangePriority, "Change priority"),
+            button(ActionTransition, "Transition"),
+            button(ActionComment, "Comment"),
+            open),
+    }
+
+}
+
+// TicketUnfurl, the compact card of ticket shown in place of a link to it,
+// without buttons since anyone in the channel sees it
+func TicketUnfurl(ticket chat.Ticket) slack.Attachment {
+    title := slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*<%s|%s>* %s", ticket.URL, ticket.Key, escape(ticket.Summary)), false, false)
+    details := slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s · %s · %s priority",
+        orNone(escape(ticket.Status), "Unknown status"), orNone(escape(ticket.Assignee), "Unassigned"), orNone(escape(ticket.Priority), "No")), false, false)
+
+    return slack.Attachment{
+        Blocks: slack.Blocks{BlockSet: []slack.Block{
+            slack.NewSectionBlock(title, nil, nil),
+            slack.NewContextBlock("", details),
+        }},
+    }
+
+}
+
+// plainText, a plain text object as modals want for titles and labels
+func plainText(text string) *slack.TextBlockObject {
+    return slack.NewTextBlockObject(slack.PlainTextType, text, false, false)
+
+}
+
+// modal, a modal with the callback id, the card it acts on and a single input
+func modal(callbackID string, title string, submit string, card CardRef, input *slack.InputBlock) slack.ModalViewRequest {
+    metadata, _ := json.Marshal(card)
+
+    return slack.ModalViewRequest{
+        Type: slack.VTModal,
+        CallbackID: callbackID,
+        Title: plainText(title),
+        Submit: plainText(submit),
+        Close: plainText("Cancel"),
+        PrivateMetadata: string(metadata),
+        Blocks: slack.Blocks{BlockSet: []slack.Block{input}},
+    }
+
+}
+
+// SelectModal, a modal choosing one of options for the card's ticket,
+// Slack allows at most 100 options so any further ones are left out
+func SelectModal(callbackID string, title string, label string, card CardRef, options []string) slack.ModalViewRequest {
+    if len(options) > 100 {
+        options = options[:100]
+    }
+
+    choices := make([]*slack.OptionBlockObject, 0, len(options))
+    for _, option := range options {
+        choices = append(choices, slack.NewOptionBlockObject(option, plainText(option), nil))
+    }
+
+    selection := slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, plainText("Choose"), InputActionID, choices...)
+    return modal(callbackID, title, "Save", card, slack.NewInputBlock(InputBlockID, plainText(label), selection))
+
+}
+
+// CommentModal, a modal writing a comment on the card's ticket
+func CommentModal(card CardRef) slack.ModalViewRequest {
+    text := slack.NewPlainTextInputBlockElement(plainText("What should the comment say?"), InputActionID)
+    text.Multiline = true
+
+    return modal(CallbackComment, "Comment", "Comment", card, slack.NewInputBlock(InputBlockID, plainText("Comment on " + card.Key), text))
+
+}
+
+// ModalCard, the card a modal submitted in view was opened from
+func ModalCard(view slack.View) (CardRef, error) {
+    var card CardRef
+    if err := json.Unmarshal([]byte(view.PrivateMetadata), &card); err != nil {
+        return card, fmt.Errorf("parse modal metadata err: %w", err)
+    }
+
+    return card, nil
+
+}
+
+// ModalValue, what was chosen or written in the input of the submitted view
+func ModalValue(view slack.View) string {
+    if view.State == nil {
+        return ""
+    }
+
+    value := view.State.Values[InputBlockID][InputActionID]
+    if value.SelectedOption.Value != "" {
+        return value.SelectedOption.Value
+    }
+
+    return value.Value
+
+}
+
+// PostTicketCard, reply in the thread started at timestamp with the card of
+// ticket, the ticket's link is the notification text
+func (s *SlackEnv) PostTicketCard(ctx context.Context, channel string, timestamp string, ticket chat.Ticket) (string, error) {
+    _, replyTimestamp, err := s.SlackClient.postBlocks(ctx, channel, timestamp, ticket.URL, TicketCard(ticket))
+    if err != nil {
+        return "", fmt.Errorf("post card failed err: %w", err)
+    }
+
+    return replyTimestamp, nil
+
+}
+
+// PostTicketUnfurls, reply in the thread started at timestamp with the
+// compact cards of tickets, their links are the notification text
+func (s *SlackEnv) PostTicketUnfurls(ctx context.Context, channel string, timestamp string, tickets []chat.Ticket) error {
+    links := make([]string, 0, len(tickets))
+    blocks := []slack.Block{}
+    for _, ticket := range tickets {
+        links = append(links, ticket.URL)
+        blocks = append(blocks, TicketUnfurl(ticket).Blocks.BlockSet...)
+    }
+
+    if _, _, err := s.SlackClient.postBlocks(ctx, channel, timestamp, strings.Join(links, " "), blocks); err != nil {
+        return fmt.Errorf("post unfurls failed err: %w", err)
+    }
+
+    return nil
+
+}
+
+// ReplaceTicketCard, replace the card at channel/timestamp with msgBody, the
+// card's blocks are cleared as Slack would show them instead of the text
+func (s *SlackEnv) ReplaceTicketCard(ctx context.Context, channel string, timestamp string, msgBody string) error {
+    if _, _, _, err := s.SlackClient.updateBlocks(ctx, channel, timestamp, msgBody, []slack.Block{}); err != nil {
+        return fmt.Errorf("replace card failed err: %w", err)
+    }
+
+    return nil
+
+}
+
+// UpdateTicketCard, replace the card at channel/timestamp with the current one of ticket
+func (s *SlackEnv) UpdateTicketCard(ctx context.Context, channel string, timestamp string, ticket chat.Ticket) error {
+    if _, _, _, err := s.SlackClient.updateBlocks(ctx, channel, timestamp, ticket.URL, TicketCard(ticket)); err != nil {
+        return fmt.Errorf("update card failed err: %w", err)
+    }
+
+    return nil
+
+}
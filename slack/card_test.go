@@ -0,0 +1,131 @@
+package slack
+
+import (
+    "context"
+    "testing"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+    "slack-jira-integration/chat"
+    "slack-jira-integration/testing/fakes"
+)
+
+func TestTicketCard(t *testing.T) {
+    ticket := chat.Ticket{
+        Key: "OPS-1",
+        URL: "https://jira.example.com/browse/OPS-1",
+        Summary: "checkout latency",
+        Status: "In Progress",
+        Priority: "High",
+    }
+
+    blocks := TicketCard(ticket)
+    require.Len(t, blocks, 3)
+
+    title := blocks[0].(*slack.SectionBlock)
+    assert.Equal(t, "*<https://jira.example.com/browse/OPS-1|OPS-1>* checkout latency", title.Text.Text)
+
+    fields := blocks[1].(*slack.SectionBlock).Fields
+    assert.Equal(t, "*Status*\nIn Progress", fields[0].Text)
+    assert.Equal(t, "*Assignee*\nUnassigned", fields[1].Text)
+    assert.Equal(t, "*Priority*\nHigh", fields[2].Text)
+
+    actions := blocks[2].(*slack.ActionBlock)
+    assert.Equal(t, CardBlockID, actions.BlockID)
+    require.Len(t, actions.Elements.ElementSet, 5)
+    for _, element := range actions.Elements.ElementSet {
+        assert.Equal(t, "OPS-1", element.(*slack.ButtonBlockElement).Value)
+    }
+    assert.Equal(t, ActionOpen, actions.Elements.ElementSet[4].(*slack.ButtonBlockElement).ActionID)
+    assert.Equal(t, ticket.URL, actions.Elements.ElementSet[4].(*slack.ButtonBlockElement).URL)
+
+}
+
+func TestTicketCardEscapesText(t *testing.T) {
+    ticket := chat.Ticket{Key: "OPS-1", URL: "https://jira.example.com/browse/OPS-1", Summary: "<!channel> cart & checkout > 5s", Assignee: "<@U1>", Status: "<!here>", Priority: "P1 & up"}
+
+    title := TicketCard(ticket)[0].(*slack.SectionBlock)
+    assert.Equal(t, "*<https://jira.example.com/browse/OPS-1|OPS-1>* &lt;!channel&gt; cart &amp; checkout &gt; 5s", title.Text.Text)
+    fields := TicketCard(ticket)[1].(*slack.SectionBlock).Fields
+    assert.Equal(t, "*Status*\n&lt;!here&gt;", fields[0].Text)
+    assert.Equal(t, "*Assignee*\n&lt;@U1&gt;", fields[1].Text)
+    assert.Equal(t, "*Priority*\nP1 &amp; up", fields[2].Text)
+
+    unfurl := TicketUnfurl(ticket)
+    assert.Equal(t, title.Text.Text, unfurl.Blocks.BlockSet[0].(*slack.SectionBlock).Text.Text)
+    details := unfurl.Blocks.BlockSet[1].(*slack.ContextBlock).ContextElements.Elements[0].(*slack.TextBlockObject)
+    assert.Equal(t, "&lt;!here&gt; · &lt;@U1&gt; · P1 &amp; up priority", details.Text)
+
+}
+
+func TestTicketUnfurl(t *testing.T) {
+    unfurl := TicketUnfurl(chat.Ticket{Key: "OPS-1", URL: "https://jira.example.com/browse/OPS-1", Summary: "checkout latency", Status: "Done", Priority: "High"})
+    require.Len(t, unfurl.Blocks.BlockSet, 2)
+
+    assert.Equal(t, "*<https://jira.example.com/browse/OPS-1|OPS-1>* checkout latency", unfurl.Blocks.BlockSet[0].(*slack.SectionBlock).Text.Text)
+    details := unfurl.Blocks.BlockSet[1].(*slack.ContextBlock).ContextElements.Elements[0].(*slack.TextBlockObject)
+    assert.Equal(t, "Done · Unassigned · High priority", details.Text)
+
+}
+
+func TestTicketCardAgainstFakeSlack(t *testing.T) {
+    fake := fakes.NewSlack(t)
+    fake.AddMessage("C2", "1641160800.000100", "U1", "first")
+    env := &SlackEnv{SlackClient: NewClient("xoxb-test", slack.OptionAPIURL(fake.URL))}
+
+    ticket := chat.Ticket{Key: "OPS-1", URL: "https://jira.example.com/browse/OPS-1", Summary: "checkout latency", Status: "To Do"}
+    replyTimestamp, err := env.PostTicketCard(context.Background(), "C2", "1641160800.000100", ticket)
+    require.NoError(t, err)
+
+    posted := fake.Posted()
+    require.Len(t, posted, 1)
+    assert.Equal(t, ticket.URL, posted[0].Text)
+    assert.Len(t, posted[0].Blocks.BlockSet, 3)
+
+    ticket.Status, ticket.Assignee = "In Progress", "Ada Lovelace"
+    require.NoError(t, env.UpdateTicketCard(context.Background(), "C2", replyTimestamp, ticket))
+    updated := fake.Updated()
+    require.Len(t, updated, 1)
+    fields := updated[0].Blocks.BlockSet[1].(*slack.SectionBlock).Fields
+    assert.Equal(t, "*Assignee*\nAda Lovelace", fields[1].Text)
+
+    // replacing the card clears its blocks so the text is shown
+    require.NoError(t, env.ReplaceTicketCard(context.Background(), "C2", replyTimestamp, "withdrawn"))
+    updated = fake.Updated()
+    assert.Equal(t, "withdrawn", updated[1].Text)
+    assert.Empty(t, updated[1].Blocks.BlockSet)
+
+}
+
+func TestModals(t *testing.T) {
+    card := CardRef{Channel: "C2", Timestamp: "1641160800.000200", Key: "OPS-1"}
+
+    view := SelectModal(CallbackPriority, "Change priority", "Priority of OPS-1", card, []string{"High", "Low"})
+    assert.Equal(t, CallbackPriority, view.CallbackID)
+    input := view.Blocks.BlockSet[0].(*slack.InputBlock)
+    assert.Equal(t, InputBlockID, input.BlockID)
+    assert.Len(t, input.Element.(*slack.SelectBlockElement).Options, 2)
+
+    // Slack sends the metadata and the chosen option back on submission
+    submitted := slack.View{PrivateMetadata: view.PrivateMetadata, State: &slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+        InputBlockID: {InputActionID: {SelectedOption: slack.OptionBlockObject{Value: "Low"}}},
+    }}}
+    parsed, err := ModalCard(submitted)
+    require.NoError(t, err)
+    assert.Equal(t, card, parsed)
+    assert.Equal(t, "Low", ModalValue(submitted))
+
+    comment := CommentModal(card)
+    assert.Equal(t, CallbackComment, comment.CallbackID)
+    assert.True(t, comment.Blocks.BlockSet[0].(*slack.InputBlock).Element.(*slack.PlainTextInputBlockElement).Multiline)
+    assert.Equal(t, "still down", ModalValue(slack.View{State: &slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+        InputBlockID: {InputActionID: {Value: "still down"}},
+    }}}))
+
+    _, err = ModalCard(slack.View{PrivateMetadata: "not json"})
+    assert.Error(t, err)
+    assert.Empty(t, ModalValue(slack.View{}))
+
+}
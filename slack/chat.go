@@ -0,0 +1,107 @@
+package slack
+
+import (
+    "context"
+    "net/url"
+    "strings"
+
+	"github.com/slack-go/slack/slackevents"
+
+    "slack-jira-integration/chat"
+)
+
+// the methods below make a SlackEnv a chat.Frontend, Slack identifies a
+// message by its timestamp
+
+// Name, "slack"
+func (s *SlackEnv) Name() string {
+    return "slack"
+
+}
+
+// ThreadMessages, every message of the thread started at timestamp
+func (s *SlackEnv) ThreadMessages(ctx context.Context, channel string, timestamp string) ([]chat.Message, error) {
+    messages, err := s.GetConversationMessages(ctx, channel, timestamp)
+    if err != nil {
+        return nil, err
+    }
+
+    threadMessages := make([]chat.Message, 0, len(messages))
+    for _, message := range messages {
+        threadMessages = append(threadMessages, chat.Message{
+            ID: message.Timestamp,
+            User: message.User,
+            Text: message.Text,
+            Bot: message.BotID != "",
+        })
+    }
+
+    return threadMessages, nil
+
+}
+
+// Permalink, the link opening the message at timestamp
+func (s *SlackEnv) Permalink(ctx context.Context, channel string, timestamp string) (string, error) {
+    return s.GetPermalink(ctx, channel, timestamp)
+
+}
+
+// ParsePermalink, the channel and thread timestamp of a message link such as
+// https://acme.slack.com/archives/C0ALERTS/p1641160800000100, a link to a
+// reply names its thread in the thread_ts parameter
+func ParsePermalink(link string) (string, string, bool) {
+    parsed, err := url.Parse(strings.Trim(link, "<>"))
+    if err != nil {
+        return "", "", false
+    }
+
+    parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+    if len(parts) != 3 || parts[0] != "archives" || !strings.HasPrefix(parts[2], "p") || len(parts[2]) <= 7 {
+        return "", "", false
+    }
+
+    if threadTimestamp := parsed.Query().Get("thread_ts"); threadTimestamp != "" {
+        return parts[1], threadTimestamp, true
+    }
+
+    digits := parts[2][1:]
+    return parts[1], digits[:len(digits) - 6] + "." + digits[len(digits) - 6:], true
+
+}
+
+// ChannelName, the configured name of channelID
+func (s *SlackEnv) ChannelName(channelID string) string {
+    return s.SlackChannelNamesByID[channelID]
+
+}
+
+// ChannelEmoji, the configured emoji of channelID
+func (s *SlackEnv) ChannelEmoji(channelID string) (string, bool) {
+    emoji, exists := s.SlackEmojis[channelID]
+    return emoji, exists
+
+}
+
+// ReactionAdded, the chat event of a reaction_added event from teamID
+func ReactionAdded(teamID string, ev *slackevents.ReactionAddedEvent) *chat.ReactionAdded {
+    return &chat.ReactionAdded{
+        TeamID: teamID,
+        User: ev.User,
+        Channel: ev.Item.Channel,
+        MessageID: ev.Item.Timestamp,
+        Reaction: ev.Reaction,
+    }
+
+}
+
+// ReactionRemoved, the chat event of a reaction_removed event from teamID
+func ReactionRemoved(teamID string, ev *slackevents.ReactionRemovedEvent) *chat.ReactionRemoved {
+    return &chat.ReactionRemoved{
+        TeamID: teamID,
+        User: ev.User,
+        Channel: ev.Item.Channel,
+        MessageID: ev.Item.Timestamp,
+        Reaction: ev.Reaction,
+    }
+
+}
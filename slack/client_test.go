@@ -0,0 +1,51 @@
+package slack
+
+import (
+    "context"
+    "testing"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+    "slack-jira-integration/testing/fakes"
+)
+
+// the fake Slack API exercises the real slackClient HTTP wiring
+func TestClientAgainstFakeSlack(t *testing.T) {
+    fake := fakes.NewSlack(t)
+    fake.PageSize = 1
+    fake.AddChannel("C1", "general")
+    fake.AddChannel("C2", "alerts")
+    fake.AddMessage("C2", "1641160800.000100", "U1", "first")
+    fake.AddReply("C2", "1641160800.000100", "U2", "second")
+    fake.AddReply("C2", "1641160800.000100", "U3", "third")
+
+    env, err := NewEnv(NewClient("xoxb-test", slack.OptionAPIURL(fake.URL)), "secret", map[string]string{"alerts": "ticket"}, []string{"alerts"})
+    require.NoError(t, err)
+    assert.Equal(t, map[string]string{"C2": "ticket"}, env.SlackEmojis)
+
+    messages, err := env.GetConversationMessages(context.Background(), "C2", "1641160800.000100")
+    require.NoError(t, err)
+    require.Len(t, messages, 3)
+    assert.Equal(t, "third", messages[2].Text)
+    assert.Equal(t, 3, fake.Calls("conversations.replies"))
+
+    replyTimestamp, err := env.PostMessageToThread(context.Background(), "C2", "1641160800.000100", "https://jira/browse/OPS-1")
+    require.NoError(t, err)
+    assert.Equal(t, replyTimestamp, fake.Posted()[0].Timestamp)
+
+    permalink, err := env.Permalink(context.Background(), "C2", "1641160800.000100")
+    require.NoError(t, err)
+    assert.Equal(t, "https://fake.slack.com/archives/C2/p1641160800000100", permalink)
+
+    threadMessages, err := env.ThreadMessages(context.Background(), "C2", "1641160800.000100")
+    require.NoError(t, err)
+    assert.False(t, threadMessages[0].Bot)
+    assert.True(t, threadMessages[3].Bot)
+
+    fake.Fail("chat.postMessage", "channel_not_found")
+    _, err = env.PostMessageToThread(context.Background(), "C2", "1641160800.000100", "https://jira/browse/OPS-2")
+    assert.ErrorContains(t, err, "channel_not_found")
+
+}
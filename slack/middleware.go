@@ -1,44 +1,201 @@
 package slack
 
 import (
-    "fmt"
-    "net/http"
-    "io/ioutil"
     "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "io/ioutil"
+    "mime"
+    "net/http"
+    "net/url"
+    "strconv"
+    "time"
 
 	"encoding/json"
 
 	"github.com/slack-go/slack"
 
 	"github.com/slack-go/slack/slackevents"
+
+    "slack-jira-integration/logging"
+    "slack-jira-integration/metrics"
+)
+
+const (
+    headerSignature = "X-Slack-Signature"
+    headerTimestamp = "X-Slack-Request-Timestamp"
+
+    // DefaultMaxTimestampSkew, the replay window Slack recommends
+    DefaultMaxTimestampSkew = 5 * time.Minute
+    // DefaultMaxBodyBytes, far above any payload Slack sends
+    DefaultMaxBodyBytes = 1 << 20
+)
+
+type contextKey int
+
+const (
+    eventKey contextKey = iota
+    interactionKey
 )
 
+// VerifierConfig configures ValidateSlackRequest, every secret in
+// SigningSecrets is accepted so a secret can be rotated without downtime
+type VerifierConfig struct {
+    SigningSecrets []string
+    MaxBodyBytes int64
+    MaxTimestampSkew time.Duration
+    now func() time.Time
+}
+
+// NewVerifierConfig, construct a VerifierConfig with the default body limit and
+// timestamp skew, empty secrets are dropped
+func NewVerifierConfig(signingSecrets ...string) VerifierConfig {
+    config := VerifierConfig{
+        MaxBodyBytes: DefaultMaxBodyBytes,
+        MaxTimestampSkew: DefaultMaxTimestampSkew,
+    }
+
+    for _, secret := range signingSecrets {
+        if secret != "" {
+            config.SigningSecrets = append(config.SigningSecrets, secret)
+        }
+    }
+
+    return config
+
+}
+
+// EventFromContext, the Events API event parsed by ValidateSlackRequest
+func EventFromContext(ctx context.Context) (slackevents.EventsAPIEvent, bool) {
+    eventsAPIEvent, ok := ctx.Value(eventKey).(slackevents.EventsAPIEvent)
+    return eventsAPIEvent, ok
+
+}
+
+// InteractionFromContext, the interaction payload parsed by ValidateSlackRequest
+// from a form-encoded request, slash commands carry no payload and are read
+// from the form with slack.SlashCommandParse
+func InteractionFromContext(ctx context.Context) (*slack.InteractionCallback, bool) {
+    interaction, ok := ctx.Value(interactionKey).(*slack.InteractionCallback)
+    return interaction, ok
+
+}
+
+// verify, check the v0 signature against every secret without returning
+// early so the time taken does not reveal which secret matched
+func (c VerifierConfig) verify(header http.Header, body []byte) (string, bool) {
+    signature := header.Get(headerSignature)
+    timestamp := header.Get(headerTimestamp)
+    if signature == "" || timestamp == "" {
+        return "missing_headers", false
+    }
+
+    seconds, err := strconv.ParseInt(timestamp, 10, 64)
+    if err != nil {
+        return "missing_headers", false
+    }
+
+    now := time.Now
+    if c.now != nil {
+        now = c.now
+    }
+
+    skew := now().Sub(time.Unix(seconds, 0))
+    if skew < 0 {
+        skew = -skew
+    }
+    if skew > c.MaxTimestampSkew {
+        return "stale_timestamp", false
+    }
+
+    matched := false
+    for _, secret := range c.SigningSecrets {
+        mac := hmac.New(sha256.New, []byte(secret))
+        mac.Write([]byte("v0:" + timestamp + ":"))
+        mac.Write(body)
+        expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+        if hmac.Equal([]byte(expected), []byte(signature)) {
+            matched = true
+        }
+    }
+
+    if !matched {
+        return "invalid_signature", false
+    }
+
+    return "", true
+
+}
+
+// ValidateSlackRequest, reject requests not signed by Slack then parse the
+// payload once, JSON Events API bodies are answered here for url_verification
+// and otherwise passed on via EventFromContext, form-encoded commands and
+// interactions are passed on with req.Form populated
+func ValidateSlackRequest(config VerifierConfig) func(http.Handler) http.Handler {
+    if config.MaxBodyBytes <= 0 {
+        config.MaxBodyBytes = DefaultMaxBodyBytes
+    }
+    if config.MaxTimestampSkew <= 0 {
+        config.MaxTimestampSkew = DefaultMaxTimestampSkew
+    }
 
-func ValidateSlackRequest(slackSigningSecret string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
-			bodyBytes, _ := ioutil.ReadAll(req.Body)
+            log := logging.FromContext(req.Context())
+
+            bodyBytes, err := ioutil.ReadAll(http.MaxBytesReader(resp, req.Body, config.MaxBodyBytes))
 			req.Body.Close() //  must close
+            if err != nil {
+                log.WithError(err).Warn("slack request body unreadable")
+                metrics.SignatureFailures.WithLabelValues("body_too_large").Inc()
+                resp.WriteHeader(http.StatusRequestEntityTooLarge)
+                return
+            }
 			req.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
 
-			sv, err := slack.NewSecretsVerifier(req.Header, slackSigningSecret)
-			if err != nil {
-                fmt.Println(fmt.Sprintf("secrets err: %+v", err))
-				resp.WriteHeader(http.StatusBadRequest)
-				return
-			}
-			if _, err := sv.Write(bodyBytes); err != nil {
-				resp.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-			if err := sv.Ensure(); err != nil {
-                fmt.Println(fmt.Sprintf("auth err: %+v", err))
-				resp.WriteHeader(http.StatusUnauthorized)
-				return
-			}
+            if reason, ok := config.verify(req.Header, bodyBytes); !ok {
+                log.WithField("reason", reason).Warn("slack request signature invalid")
+                metrics.SignatureFailures.WithLabelValues(reason).Inc()
+                if reason == "missing_headers" {
+                    resp.WriteHeader(http.StatusBadRequest)
+                } else {
+                    resp.WriteHeader(http.StatusUnauthorized)
+                }
+                return
+            }
+
+            mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+            if mediaType == "application/x-www-form-urlencoded" {
+                form, err := url.ParseQuery(string(bodyBytes))
+                if err != nil {
+                    log.WithError(err).Warn("slack form payload malformed")
+                    resp.WriteHeader(http.StatusBadRequest)
+                    return
+                }
+                req.Form, req.PostForm = form, form
+
+                ctx := req.Context()
+                if payload := form.Get("payload"); payload != "" {
+                    var interaction slack.InteractionCallback
+                    if err := json.Unmarshal([]byte(payload), &interaction); err != nil {
+                        log.WithError(err).Warn("slack interaction payload malformed")
+                        resp.WriteHeader(http.StatusBadRequest)
+                        return
+                    }
+                    ctx = context.WithValue(ctx, interactionKey, &interaction)
+                }
+
+                next.ServeHTTP(resp, req.WithContext(ctx))
+                return
+            }
+
 			eventsAPIEvent, err := slackevents.ParseEvent(json.RawMessage(bodyBytes), slackevents.OptionNoVerifyToken())
 			if err != nil {
-				resp.WriteHeader(http.StatusInternalServerError)
+                log.WithError(err).Warn("slack event payload malformed")
+				resp.WriteHeader(http.StatusBadRequest)
 				return
 			}
 
@@ -46,7 +203,7 @@ func ValidateSlackRequest(slackSigningSecret string) func(http.Handler) http.Han
 				var r *slackevents.ChallengeResponse
 				err := json.Unmarshal([]byte(bodyBytes), &r)
 				if err != nil {
-					resp.WriteHeader(http.StatusInternalServerError)
+					resp.WriteHeader(http.StatusBadRequest)
 					return
 				}
 				resp.Header().Set("Content-Type", "text")
@@ -54,7 +211,7 @@ func ValidateSlackRequest(slackSigningSecret string) func(http.Handler) http.Han
 				return
 			}
 
-			next.ServeHTTP(resp, req)
+			next.ServeHTTP(resp, req.WithContext(context.WithValue(req.Context(), eventKey, eventsAPIEvent)))
 
 		})
 	}
@@ -0,0 +1,124 @@
+package slack
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+const (
+    callbackPayload = `{"type":"event_callback","team_id":"T1","event_id":"Ev1","event":{"type":"reaction_added","user":"U1","reaction":"mega","item":{"type":"message","channel":"C1","ts":"1.1"}}}`
+    challengePayload = `{"type":"url_verification","token":"some-token","challenge":"some-challenge"}`
+)
+
+var now = time.Unix(1641160720, 0)
+
+func signedRequest(secret string, timestamp time.Time, contentType string, body string) *http.Request {
+    ts := fmt.Sprint(timestamp.Unix())
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte("v0:" + ts + ":" + body))
+
+    req := httptest.NewRequest("POST", "/slack/events", strings.NewReader(body))
+    req.Header.Set("Content-Type", contentType)
+    req.Header.Set(headerTimestamp, ts)
+    req.Header.Set(headerSignature, "v0=" + hex.EncodeToString(mac.Sum(nil)))
+    return req
+
+}
+
+func newVerifier(next http.HandlerFunc) http.Handler {
+    config := NewVerifierConfig("current-secret", "", "previous-secret")
+    config.MaxBodyBytes = 1024
+    config.now = func() time.Time { return now }
+
+    return ValidateSlackRequest(config)(next)
+
+}
+
+func TestValidateSlackRequest(t *testing.T) {
+    var handled *http.Request
+    handler := newVerifier(func(resp http.ResponseWriter, req *http.Request) {
+        handled = req
+    })
+
+    tests := []struct {
+        name string
+        req *http.Request
+        code int
+        handled bool
+    }{
+        {"current secret", signedRequest("current-secret", now, "application/json", callbackPayload), http.StatusOK, true},
+        {"rotated secret", signedRequest("previous-secret", now, "application/json", callbackPayload), http.StatusOK, true},
+        {"unknown secret", signedRequest("some-secret", now, "application/json", callbackPayload), http.StatusUnauthorized, false},
+        {"stale timestamp", signedRequest("current-secret", now.Add(-6 * time.Minute), "application/json", callbackPayload), http.StatusUnauthorized, false},
+        {"missing headers", httptest.NewRequest("POST", "/slack/events", strings.NewReader(callbackPayload)), http.StatusBadRequest, false},
+        {"malformed json", signedRequest("current-secret", now, "application/json", "{"), http.StatusBadRequest, false},
+        {"body too large", signedRequest("current-secret", now, "application/json", strings.Repeat("a", 2048)), http.StatusRequestEntityTooLarge, false},
+        {"url verification", signedRequest("current-secret", now, "application/json", challengePayload), http.StatusOK, false},
+    }
+
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            handled = nil
+            rr := httptest.NewRecorder()
+            handler.ServeHTTP(rr, test.req)
+
+            assert.Equal(t, test.code, rr.Code)
+            assert.Equal(t, test.handled, handled != nil)
+        })
+    }
+
+}
+
+func TestValidateSlackRequestPassesParsedEvent(t *testing.T) {
+    handler := newVerifier(func(resp http.ResponseWriter, req *http.Request) {
+        eventsAPIEvent, ok := EventFromContext(req.Context())
+        assert.True(t, ok)
+        assert.Equal(t, "reaction_added", eventsAPIEvent.InnerEvent.Type)
+
+        // the body is still readable by the handler
+        body, err := ioutil.ReadAll(req.Body)
+        assert.Nil(t, err)
+        assert.Equal(t, callbackPayload, string(body))
+    })
+
+    rr := httptest.NewRecorder()
+    handler.ServeHTTP(rr, signedRequest("current-secret", now, "application/json", callbackPayload))
+    assert.Equal(t, http.StatusOK, rr.Code)
+
+}
+
+func TestValidateSlackRequestFormEncoded(t *testing.T) {
+    command := url.Values{"command": {"/jira"}, "text": {"link ABC-1"}, "channel_id": {"C1"}}.Encode()
+    interaction := url.Values{"payload": {`{"type":"block_actions","user":{"id":"U1"}}`}}.Encode()
+
+    handler := newVerifier(func(resp http.ResponseWriter, req *http.Request) {
+        if callback, ok := InteractionFromContext(req.Context()); ok {
+            assert.Equal(t, "U1", callback.User.ID)
+            return
+        }
+
+        assert.Equal(t, "link ABC-1", req.FormValue("text"))
+    })
+
+    for _, body := range []string{command, interaction} {
+        rr := httptest.NewRecorder()
+        handler.ServeHTTP(rr, signedRequest("current-secret", now, "application/x-www-form-urlencoded", body))
+        assert.Equal(t, http.StatusOK, rr.Code)
+    }
+
+    rr := httptest.NewRecorder()
+    handler.ServeHTTP(rr, signedRequest("current-secret", now, "application/x-www-form-urlencoded", url.Values{"payload": {"{"}}.Encode()))
+    assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+}
@@ -0,0 +1,228 @@
+package slack
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "net/url"
+    "os"
+    "strings"
+    "sync"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+    slackAuthorizeURL = "https://slack.com/oauth/v2/authorize"
+    oauthStateCookie = "slack_oauth_state"
+)
+
+// Installation is the result of installing the app into a workspace, or into
+// a whole Enterprise Grid org when IsEnterpriseInstall is set
+type Installation struct {
+    TeamID string `json:"team_id"`
+    TeamName string `json:"team_name"`
+    EnterpriseID string `json:"enterprise_id"`
+    IsEnterpriseInstall bool `json:"is_enterprise_install"`
+    BotToken string `json:"bot_token"`
+    BotUserID string `json:"bot_user_id"`
+}
+
+// key, org wide installs are stored under the enterprise id, everything else
+// under the team id
+func (i *Installation) key() string {
+    if i.IsEnterpriseInstall {
+        return i.EnterpriseID
+    }
+
+    return i.TeamID
+}
+
+// TokenStore persists Installations keyed by team_id (or enterprise_id for
+// org wide installs), Get returns nil, nil when nothing is installed
+type TokenStore interface {
+    Get(id string) (*Installation, error)
+    Put(installation *Installation) error
+}
+
+type memoryTokenStore struct {
+    mu sync.RWMutex
+    installations map[string]*Installation
+}
+
+// NewMemoryTokenStore, construct a TokenStore which only lives as long as the process
+func NewMemoryTokenStore() TokenStore {
+    return &memoryTokenStore{installations: make(map[string]*Installation)}
+
+}
+
+func (m *memoryTokenStore) Get(id string) (*Installation, error) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+
+    return m.installations[id], nil
+
+}
+
+func (m *memoryTokenStore) Put(installation *Installation) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    m.installations[installation.key()] = installation
+    return nil
+
+}
+
+type fileTokenStore struct {
+    memoryTokenStore
+    path string
+}
+
+// NewFileTokenStore, construct a TokenStore persisted as JSON at path, the
+// file is loaded on construction and rewritten on every Put
+func NewFileTokenStore(path string) (TokenStore, error) {
+    store := &fileTokenStore{
+        memoryTokenStore: memoryTokenStore{installations: make(map[string]*Installation)},
+        path: path,
+    }
+
+    bodyBytes, err := ioutil.ReadFile(path)
+    if os.IsNotExist(err) {
+        return store, nil
+    }
+
+    if err != nil {
+        return nil, fmt.Errorf("read token store err: %w", err)
+    }
+
+    if err := json.Unmarshal(bodyBytes, &store.installations); err != nil {
+        return nil, fmt.Errorf("parse token store err: %w", err)
+    }
+
+    return store, nil
+
+}
+
+func (f *fileTokenStore) Put(installation *Installation) error {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+
+    f.installations[installation.key()] = installation
+
+    bodyBytes, err := json.Marshal(f.installations)
+    if err != nil {
+        return err
+    }
+
+    tmpPath := f.path + ".tmp"
+    if err := ioutil.WriteFile(tmpPath, bodyBytes, 0600); err != nil {
+        return fmt.Errorf("write token store err: %w", err)
+    }
+
+    return os.Rename(tmpPath, f.path)
+
+}
+
+// OAuthEnv holds the Slack app credentials needed for the OAuth v2 install
+// flow, completed installs are written to Store
+type OAuthEnv struct {
+    ClientID string
+    ClientSecret string
+    RedirectURL string
+    Scopes []string
+    Store TokenStore
+
+    // exchangeCode, swapped in tests, defaults to slack.GetOAuthV2ResponseContext
+    exchangeCode func(ctx context.Context, clientID, clientSecret, code, redirectURI string) (*slack.OAuthV2Response, error)
+}
+
+// NewOAuthEnv, construct a new OAuthEnv
+func NewOAuthEnv(clientID string, clientSecret string, redirectURL string, scopes []string, store TokenStore) *OAuthEnv {
+    return &OAuthEnv{
+        ClientID: clientID,
+        ClientSecret: clientSecret,
+        RedirectURL: redirectURL,
+        Scopes: scopes,
+        Store: store,
+        exchangeCode: func(ctx context.Context, clientID, clientSecret, code, redirectURI string) (*slack.OAuthV2Response, error) {
+            return slack.GetOAuthV2ResponseContext(ctx, http.DefaultClient, clientID, clientSecret, code, redirectURI)
+        },
+    }
+
+}
+
+// InstallHandler, redirects the browser to Slack's authorize page, the state
+// parameter is also set as a cookie and checked in OAuthCallbackHandler
+func (o *OAuthEnv) InstallHandler(resp http.ResponseWriter, req *http.Request) {
+    stateBytes := make([]byte, 16)
+    if _, err := rand.Read(stateBytes); err != nil {
+        resp.WriteHeader(http.StatusInternalServerError)
+        return
+    }
+    state := hex.EncodeToString(stateBytes)
+
+    http.SetCookie(resp, &http.Cookie{
+        Name: oauthStateCookie,
+        Value: state,
+        Path: "/slack/oauth",
+        MaxAge: 600,
+        HttpOnly: true,
+        Secure: true,
+        SameSite: http.SameSiteLaxMode,
+    })
+
+    params := url.Values{
+        "client_id": {o.ClientID},
+        "scope": {strings.Join(o.Scopes, ",")},
+        "redirect_uri": {o.RedirectURL},
+        "state": {state},
+    }
+
+    http.Redirect(resp, req, slackAuthorizeURL + "?" + params.Encode(), http.StatusFound)
+
+}
+
+// OAuthCallbackHandler, exchanges the code Slack redirects back with for a
+// bot token and stores the resulting Installation
+func (o *OAuthEnv) OAuthCallbackHandler(resp http.ResponseWriter, req *http.Request) {
+    query := req.URL.Query()
+
+    if errParam := query.Get("error"); errParam != "" {
+        http.Error(resp, fmt.Sprintf("installation cancelled: %s", errParam), http.StatusBadRequest)
+        return
+    }
+
+    cookie, err := req.Cookie(oauthStateCookie)
+    if err != nil || cookie.Value == "" || cookie.Value != query.Get("state") {
+        http.Error(resp, "invalid oauth state", http.StatusBadRequest)
+        return
+    }
+
+    oauthResp, err := o.exchangeCode(req.Context(), o.ClientID, o.ClientSecret, query.Get("code"), o.RedirectURL)
+    if err != nil {
+        http.Error(resp, fmt.Sprintf("oauth exchange failed err: %s", err), http.StatusBadGateway)
+        return
+    }
+
+    installation := &Installation{
+        TeamID: oauthResp.Team.ID,
+        TeamName: oauthResp.Team.Name,
+        EnterpriseID: oauthResp.Enterprise.ID,
+        IsEnterpriseInstall: oauthResp.Team.ID == "" && oauthResp.Enterprise.ID != "",
+        BotToken: oauthResp.AccessToken,
+        BotUserID: oauthResp.BotUserID,
+    }
+
+    if err := o.Store.Put(installation); err != nil {
+        http.Error(resp, fmt.Sprintf("store installation err: %s", err), http.StatusInternalServerError)
+        return
+    }
+
+    resp.Header().Set("Content-Type", "text/plain")
+    resp.Write([]byte("Slack Jira integration installed, you can close this window"))
+
+}
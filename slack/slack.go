@@ -3,7 +3,14 @@ package slack
 import (
     "fmt"
     "context"
+    "net/http"
+    "sync"
+    "time"
 	"github.com/slack-go/slack"
+
+    "slack-jira-integration/logging"
+    "slack-jira-integration/metrics"
+    "slack-jira-integration/tracing"
 )
 
 // SlackEnv is the Dependency Injection(DI) for slack object allowing environment
@@ -13,46 +20,157 @@ type SlackEnv struct {
 	SlackSigningSecret string
     SlackChannelNames []string
     SlackChannelIds []string
+    SlackChannelNamesByID map[string]string
     SlackEmojis map[string]string
+    // UserGroupCacheTTL, how long user group members are reused before
+    // usergroups.users.list is asked again, zero is DefaultUserGroupCacheTTL
+    UserGroupCacheTTL time.Duration
+
+    mu sync.Mutex
+    userGroups map[string]cachedUserGroup
+}
+
+// DefaultUserGroupCacheTTL, usergroups.users.list is rate limited to about
+// 20 calls a minute so access checks reuse the members this long
+const DefaultUserGroupCacheTTL = 5 * time.Minute
+
+// cachedUserGroup, the members of a user group looked up at some point
+type cachedUserGroup struct {
+    members []string
+    expires time.Time
 }
 
 // Slacker is an interface for testing purposes wrapping the concrete slack.client
 // the SlackEnv takes a Slacker which is either the slackClient struct below or a 
 // MockSlackClient from slack_test
 type Slacker interface {
-    getConversationReplies(*slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error)
-    getConversations(*slack.GetConversationsParameters) ([]slack.Channel, string, error)
-    postMessage(string, string, string) (string, string, error)
+    getConversationReplies(context.Context, *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error)
+    getConversations(context.Context, *slack.GetConversationsParameters) ([]slack.Channel, string, error)
+    postMessage(context.Context, string, string, string) (string, string, error)
+    authTest(context.Context) (*slack.AuthTestResponse, error)
+    getUserInfo(context.Context, string) (*slack.User, error)
+    getUserGroupMembers(context.Context, string) ([]string, error)
+    postEphemeral(context.Context, string, string, string) (string, error)
+    updateMessage(context.Context, string, string, string) (string, string, string, error)
+    getPermalink(context.Context, *slack.PermalinkParameters) (string, error)
+    postBlocks(context.Context, string, string, string, []slack.Block) (string, string, error)
+    updateBlocks(context.Context, string, string, string, []slack.Block) (string, string, string, error)
+    openView(context.Context, string, slack.ModalViewRequest) (*slack.ViewResponse, error)
+    unfurl(context.Context, string, string, map[string]slack.Attachment) (string, string, string, error)
+    getConversationInfo(context.Context, string) (*slack.Channel, error)
+    getConversationMembers(context.Context, *slack.GetUsersInConversationParameters) ([]string, string, error)
 
 }
 
 type slackClient struct {
     Client *slack.Client
-    Context context.Context
 
 }
 
-// NewClient, construct a newClient which implements Slacker
-func NewClient(slackBotToken string) Slacker {
-    context := context.Background()
+// NewClient, construct a newClient which implements Slacker, options are
+// passed through to slack.New e.g. slack.OptionAPIURL to target a fake server
+func NewClient(slackBotToken string, options ...slack.Option) Slacker {
+    httpClient := &http.Client{Transport: tracing.InstrumentRoundTripper(metrics.InstrumentRoundTripper("slack", nil))}
+    options = append([]slack.Option{slack.OptionHTTPClient(httpClient)}, options...)
+
     return &slackClient{
-        Context: context,
-        Client: slack.New(slackBotToken),
+        Client: slack.New(slackBotToken, options...),
     }
 
 }
 
-func (s *slackClient) getConversations(params *slack.GetConversationsParameters) ([]slack.Channel, string, error) {
-    return s.Client.GetConversationsContext(s.Context, params)
+// logCall, debug log every Slack API call with the correlation fields carried by ctx
+func logCall(ctx context.Context, method string) {
+    logging.FromContext(ctx).WithField("slack_method", method).Debug("slack api call")
+
+}
+
+func (s *slackClient) getConversations(ctx context.Context, params *slack.GetConversationsParameters) ([]slack.Channel, string, error) {
+    logCall(ctx, "conversations.list")
+    return s.Client.GetConversationsContext(ctx, params)
+}
+
+func (s *slackClient) getConversationReplies(ctx context.Context, params *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error) {
+    logCall(ctx, "conversations.replies")
+    return s.Client.GetConversationRepliesContext(ctx, params)
+
+}
+
+func (s *slackClient) postMessage(ctx context.Context, channel string, timestamp string, msgBody string) (string, string, error)  {
+    logCall(ctx, "chat.postMessage")
+    return s.Client.PostMessageContext(ctx, channel, slack.MsgOptionTS(timestamp), slack.MsgOptionText(msgBody, true))
+
+}
+
+func (s *slackClient) authTest(ctx context.Context) (*slack.AuthTestResponse, error) {
+    logCall(ctx, "auth.test")
+    return s.Client.AuthTestContext(ctx)
+
+}
+
+func (s *slackClient) getUserInfo(ctx context.Context, user string) (*slack.User, error) {
+    logCall(ctx, "users.info")
+    return s.Client.GetUserInfoContext(ctx, user)
+
+}
+
+func (s *slackClient) getUserGroupMembers(ctx context.Context, userGroup string) ([]string, error) {
+    logCall(ctx, "usergroups.users.list")
+    return s.Client.GetUserGroupMembersContext(ctx, userGroup)
+
+}
+
+func (s *slackClient) postEphemeral(ctx context.Context, channel string, user string, msgBody string) (string, error) {
+    logCall(ctx, "chat.postEphemeral")
+    return s.Client.PostEphemeralContext(ctx, channel, user, slack.MsgOptionText(msgBody, false))
+
+}
+
+func (s *slackClient) updateMessage(ctx context.Context, channel string, timestamp string, msgBody string) (string, string, string, error) {
+    logCall(ctx, "chat.update")
+    return s.Client.UpdateMessageContext(ctx, channel, timestamp, slack.MsgOptionText(msgBody, false))
+
+}
+
+func (s *slackClient) postBlocks(ctx context.Context, channel string, timestamp string, text string, blocks []slack.Block) (string, string, error) {
+    logCall(ctx, "chat.postMessage")
+    return s.Client.PostMessageContext(ctx, channel, slack.MsgOptionTS(timestamp), slack.MsgOptionText(text, false), slack.MsgOptionBlocks(blocks...))
+
+}
+
+func (s *slackClient) updateBlocks(ctx context.Context, channel string, timestamp string, text string, blocks []slack.Block) (string, string, string, error) {
+    logCall(ctx, "chat.update")
+    return s.Client.UpdateMessageContext(ctx, channel, timestamp, slack.MsgOptionText(text, false), slack.MsgOptionBlocks(blocks...))
+
+}
+
+func (s *slackClient) openView(ctx context.Context, triggerID string, view slack.ModalViewRequest) (*slack.ViewResponse, error) {
+    logCall(ctx, "views.open")
+    return s.Client.OpenViewContext(ctx, triggerID, view)
+
+}
+
+func (s *slackClient) unfurl(ctx context.Context, channel string, timestamp string, unfurls map[string]slack.Attachment) (string, string, string, error) {
+    logCall(ctx, "chat.unfurl")
+    return s.Client.UnfurlMessageContext(ctx, channel, timestamp, unfurls)
+
+}
+
+func (s *slackClient) getConversationInfo(ctx context.Context, channel string) (*slack.Channel, error) {
+    logCall(ctx, "conversations.info")
+    return s.Client.GetConversationInfoContext(ctx, channel, false)
+
 }
 
-func (s *slackClient) getConversationReplies(params *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error) {
-    return s.Client.GetConversationRepliesContext(s.Context, params)
+func (s *slackClient) getConversationMembers(ctx context.Context, params *slack.GetUsersInConversationParameters) ([]string, string, error) {
+    logCall(ctx, "conversations.members")
+    return s.Client.GetUsersInConversationContext(ctx, params)
 
 }
 
-func (s *slackClient) postMessage(channel string, timestamp string, msgBody string) (string, string, error)  {
-    return s.Client.PostMessage(channel, slack.MsgOptionTS(timestamp), slack.MsgOptionText(msgBody, true))
+func (s *slackClient) getPermalink(ctx context.Context, params *slack.PermalinkParameters) (string, error) {
+    logCall(ctx, "chat.getPermalink")
+    return s.Client.GetPermalinkContext(ctx, params)
 
 }
 
@@ -74,8 +192,9 @@ func NewEnv(client Slacker, slackSigningSecret string, slackEmojis map[string]st
 // finds the corresponding ChannelID for the given channel name via getChannelID
 func (s *SlackEnv) transformSlackEmojisToIndexedByChannelID(slackEmojis map[string]string) (*SlackEnv, error) {
     slackChannelNamesToIds := make(map[string]string)
+    slackChannelNamesByID := make(map[string]string)
     for _, channelName := range s.SlackChannelNames {
-        channelID, err := s.getChannelID(channelName)
+        channelID, err := s.getChannelID(context.Background(), channelName)
 
         if err != nil {
             return nil, err
@@ -88,6 +207,7 @@ func (s *SlackEnv) transformSlackEmojisToIndexedByChannelID(slackEmojis map[stri
         }
 
         slackChannelNamesToIds[channelName] = channelID
+        slackChannelNamesByID[channelID] = channelName
 
     }
 
@@ -101,20 +221,21 @@ func (s *SlackEnv) transformSlackEmojisToIndexedByChannelID(slackEmojis map[stri
     }
 
     s.SlackEmojis = slackEmojisByChannelID
+    s.SlackChannelNamesByID = slackChannelNamesByID
 
     return s, nil
 
 }
 
 // GetConversationMessages, returns all messages in a thread given the originating channel/timestamp
-func (s *SlackEnv) GetConversationMessages(channel string, timestamp string) ([]slack.Message, error) {
+func (s *SlackEnv) GetConversationMessages(ctx context.Context, channel string, timestamp string) ([]slack.Message, error) {
     params := slack.GetConversationRepliesParameters {
         ChannelID: channel,
         Timestamp: timestamp,
     }
 
 
-    messages, hasMore, nextCursor, err := s.SlackClient.getConversationReplies(&params) 
+    messages, hasMore, nextCursor, err := s.SlackClient.getConversationReplies(ctx, &params) 
 
     if err != nil {
         return nil, err
@@ -131,7 +252,7 @@ func (s *SlackEnv) GetConversationMessages(channel string, timestamp string) ([]
             Cursor: nextCursor,
         }
 
-        messages, hasMore, nextCursor, err = s.SlackClient.getConversationReplies(&params)
+        messages, hasMore, nextCursor, err = s.SlackClient.getConversationReplies(ctx, &params)
 
         if err != nil {
            return conversationMessages, err
@@ -146,30 +267,198 @@ func (s *SlackEnv) GetConversationMessages(channel string, timestamp string) ([]
 
 }
 
-// PostMessageToThread, reply to a thread(channel/timestamp) with the given msgBody
-func (s *SlackEnv) PostMessageToThread(channel string, timestamp string, msgBody string ) error {
-    _, resp, err := s.SlackClient.postMessage(channel, timestamp, msgBody) 
+// PostMessageToThread, reply to a thread(channel/timestamp) with the given
+// msgBody, returns the timestamp of the reply so it can be edited later
+func (s *SlackEnv) PostMessageToThread(ctx context.Context, channel string, timestamp string, msgBody string ) (string, error) {
+    _, replyTimestamp, err := s.SlackClient.postMessage(ctx, channel, timestamp, msgBody) 
+
+    if err != nil {
+        return "", fmt.Errorf("post message failed err: %w", err)
+    }
+
+    return replyTimestamp, nil
+
+}
+
+// UpdateMessage, replace the text of the message at channel/timestamp
+func (s *SlackEnv) UpdateMessage(ctx context.Context, channel string, timestamp string, msgBody string) error {
+    if _, _, _, err := s.SlackClient.updateMessage(ctx, channel, timestamp, msgBody); err != nil {
+        return fmt.Errorf("update message failed err: %w", err)
+    }
+
+    return nil
+
+}
+
+// PostEphemeral, send msgBody to user in channel, visible only to them
+func (s *SlackEnv) PostEphemeral(ctx context.Context, channel string, user string, msgBody string) error {
+    if _, err := s.SlackClient.postEphemeral(ctx, channel, user, msgBody); err != nil {
+        return fmt.Errorf("post ephemeral failed err: %w", err)
+    }
+
+    return nil
+
+}
+
+// RespondToCommand, answer a slash command through its response_url once
+// it has been run, the answer is visible only to the user who ran it
+func RespondToCommand(ctx context.Context, responseURL string, msgBody string) error {
+    message := &slack.WebhookMessage{ResponseType: slack.ResponseTypeEphemeral, Text: msgBody}
+    if err := slack.PostWebhookContext(ctx, responseURL, message); err != nil {
+        return fmt.Errorf("respond to command failed err: %w", err)
+    }
+
+    return nil
+
+}
+
+// IsGuest, whether user is a multi or single channel guest of the workspace
+func (s *SlackEnv) IsGuest(ctx context.Context, user string) (bool, error) {
+    info, err := s.SlackClient.getUserInfo(ctx, user)
+    if err != nil {
+        return false, fmt.Errorf("get user info failed err: %w", err)
+    }
+
+    return info.IsRestricted || info.IsUltraRestricted, nil
+
+}
+
+// UserProfile, the real name and email address of user, the email needs the
+// users:read.email scope
+func (s *SlackEnv) UserProfile(ctx context.Context, user string) (string, string, error) {
+    info, err := s.SlackClient.getUserInfo(ctx, user)
+    if err != nil {
+        return "", "", fmt.Errorf("get user info failed err: %w", err)
+    }
+
+    return info.RealName, info.Profile.Email, nil
+
+}
+
+// OpenView, open the modal view in answer to the interaction of triggerID
+func (s *SlackEnv) OpenView(ctx context.Context, triggerID string, view slack.ModalViewRequest) error {
+    if _, err := s.SlackClient.openView(ctx, triggerID, view); err != nil {
+        return fmt.Errorf("open view failed err: %w", err)
+    }
+
+    return nil
+
+}
+
+// UserGroupMembers, the user IDs belonging to the user group, cached for
+// UserGroupCacheTTL, failures are not cached
+func (s *SlackEnv) UserGroupMembers(ctx context.Context, userGroup string) ([]string, error) {
+    s.mu.Lock()
+    cached, exists := s.userGroups[userGroup]
+    s.mu.Unlock()
+
+    if exists && time.Now().Before(cached.expires) {
+        return cached.members, nil
+    }
+
+    members, err := s.SlackClient.getUserGroupMembers(ctx, userGroup)
+    if err != nil {
+        return nil, fmt.Errorf("get user group members failed err: %w", err)
+    }
+
+    ttl := s.UserGroupCacheTTL
+    if ttl <= 0 {
+        ttl = DefaultUserGroupCacheTTL
+    }
+
+    s.mu.Lock()
+    if s.userGroups == nil {
+        s.userGroups = make(map[string]cachedUserGroup)
+    }
+    s.userGroups[userGroup] = cachedUserGroup{members: members, expires: time.Now().Add(ttl)}
+    s.mu.Unlock()
+
+    return members, nil
+
+}
 
+// GetPermalink, the link opening the message at channel/timestamp
+func (s *SlackEnv) GetPermalink(ctx context.Context, channel string, timestamp string) (string, error) {
+    permalink, err := s.SlackClient.getPermalink(ctx, &slack.PermalinkParameters{Channel: channel, Ts: timestamp})
     if err != nil {
-        return fmt.Errorf("post message failed err: %s", resp)
+        return "", fmt.Errorf("get permalink failed err: %w", err)
+    }
+
+    return permalink, nil
+
+}
+
+// UnfurlLinks, show the attachments in place of the previews of the links
+// they are keyed by in the message at channel/timestamp
+func (s *SlackEnv) UnfurlLinks(ctx context.Context, channel string, timestamp string, unfurls map[string]slack.Attachment) error {
+    if _, _, _, err := s.SlackClient.unfurl(ctx, channel, timestamp, unfurls); err != nil {
+        return fmt.Errorf("unfurl failed err: %w", err)
     }
 
     return nil
 
 }
 
+// LookupChannelName, the name of any channel the bot can see, configured
+// channels are known without asking Slack
+func (s *SlackEnv) LookupChannelName(ctx context.Context, channelID string) (string, error) {
+    if name, known := s.SlackChannelNamesByID[channelID]; known {
+        return name, nil
+    }
+
+    channel, err := s.SlackClient.getConversationInfo(ctx, channelID)
+    if err != nil {
+        return "", fmt.Errorf("get conversation info failed err: %w", err)
+    }
+
+    return channel.Name, nil
+
+}
+
+// IsChannelMember, whether user is in the channel, the members are paged
+// through until user is found
+func (s *SlackEnv) IsChannelMember(ctx context.Context, channel string, user string) (bool, error) {
+    params := slack.GetUsersInConversationParameters{ChannelID: channel}
+
+    for {
+        members, cursor, err := s.SlackClient.getConversationMembers(ctx, &params)
+        if err != nil {
+            return false, fmt.Errorf("get conversation members failed err: %w", err)
+        }
+
+        for _, member := range members {
+            if member == user {
+                return true, nil
+            }
+        }
+
+        if cursor == "" {
+            return false, nil
+        }
+        params.Cursor = cursor
+    }
+
+}
+
+// AuthTest, verify the bot token is still accepted by Slack, used by readiness checks
+func (s *SlackEnv) AuthTest(ctx context.Context) error {
+    _, err := s.SlackClient.authTest(ctx)
+    return err
+
+}
+
 // getChannelID, given a channel name find the corresponding getChannelID
 //
 // NOTE: This is a naive implementation, assumes the function will be called
 //       very few times. If the program starts monitoring many channels this
 //       should either implement caching or map many channelNames to many
 //       channelIDs for a single method call
-func (s *SlackEnv) getChannelID(channelName string) (string, error) {
+func (s *SlackEnv) getChannelID(ctx context.Context, channelName string) (string, error) {
     params := slack.GetConversationsParameters{
         ExcludeArchived: true,
     }
 
-    channels, nextCursor, err := s.SlackClient.getConversations(&params) 
+    channels, nextCursor, err := s.SlackClient.getConversations(ctx, &params) 
 
     if err != nil {
         return "", err
@@ -191,7 +480,7 @@ func (s *SlackEnv) getChannelID(channelName string) (string, error) {
             ExcludeArchived: true,
         }
 
-        channels, nextCursor, err = s.SlackClient.getConversations(&params) 
+        channels, nextCursor, err = s.SlackClient.getConversations(ctx, &params) 
 
         if err != nil {
             return "", err
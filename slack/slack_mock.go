@@ -5,6 +5,7 @@
 package slack
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -34,10 +35,56 @@ func (m *MockSlacker) EXPECT() *MockSlackerMockRecorder {
 	return m.recorder
 }
 
+// authTest mocks base method.
+func (m *MockSlacker) authTest(arg0 context.Context) (*slack.AuthTestResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "authTest", arg0)
+	ret0, _ := ret[0].(*slack.AuthTestResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// authTest indicates an expected call of authTest.
+func (mr *MockSlackerMockRecorder) authTest(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "authTest", reflect.TypeOf((*MockSlacker)(nil).authTest), arg0)
+}
+
+// getConversationInfo mocks base method.
+func (m *MockSlacker) getConversationInfo(arg0 context.Context, arg1 string) (*slack.Channel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getConversationInfo", arg0, arg1)
+	ret0, _ := ret[0].(*slack.Channel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// getConversationInfo indicates an expected call of getConversationInfo.
+func (mr *MockSlackerMockRecorder) getConversationInfo(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getConversationInfo", reflect.TypeOf((*MockSlacker)(nil).getConversationInfo), arg0, arg1)
+}
+
+// getConversationMembers mocks base method.
+func (m *MockSlacker) getConversationMembers(arg0 context.Context, arg1 *slack.GetUsersInConversationParameters) ([]string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getConversationMembers", arg0, arg1)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// getConversationMembers indicates an expected call of getConversationMembers.
+func (mr *MockSlackerMockRecorder) getConversationMembers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getConversationMembers", reflect.TypeOf((*MockSlacker)(nil).getConversationMembers), arg0, arg1)
+}
+
 // getConversationReplies mocks base method.
-func (m *MockSlacker) getConversationReplies(arg0 *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error) {
+func (m *MockSlacker) getConversationReplies(arg0 context.Context, arg1 *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "getConversationReplies", arg0)
+	ret := m.ctrl.Call(m, "getConversationReplies", arg0, arg1)
 	ret0, _ := ret[0].([]slack.Message)
 	ret1, _ := ret[1].(bool)
 	ret2, _ := ret[2].(string)
@@ -46,15 +93,15 @@ func (m *MockSlacker) getConversationReplies(arg0 *slack.GetConversationRepliesP
 }
 
 // getConversationReplies indicates an expected call of getConversationReplies.
-func (mr *MockSlackerMockRecorder) getConversationReplies(arg0 interface{}) *gomock.Call {
+func (mr *MockSlackerMockRecorder) getConversationReplies(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getConversationReplies", reflect.TypeOf((*MockSlacker)(nil).getConversationReplies), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getConversationReplies", reflect.TypeOf((*MockSlacker)(nil).getConversationReplies), arg0, arg1)
 }
 
 // getConversations mocks base method.
-func (m *MockSlacker) getConversations(arg0 *slack.GetConversationsParameters) ([]slack.Channel, string, error) {
+func (m *MockSlacker) getConversations(arg0 context.Context, arg1 *slack.GetConversationsParameters) ([]slack.Channel, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "getConversations", arg0)
+	ret := m.ctrl.Call(m, "getConversations", arg0, arg1)
 	ret0, _ := ret[0].([]slack.Channel)
 	ret1, _ := ret[1].(string)
 	ret2, _ := ret[2].(error)
@@ -62,15 +109,106 @@ func (m *MockSlacker) getConversations(arg0 *slack.GetConversationsParameters) (
 }
 
 // getConversations indicates an expected call of getConversations.
-func (mr *MockSlackerMockRecorder) getConversations(arg0 interface{}) *gomock.Call {
+func (mr *MockSlackerMockRecorder) getConversations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getConversations", reflect.TypeOf((*MockSlacker)(nil).getConversations), arg0, arg1)
+}
+
+// getPermalink mocks base method.
+func (m *MockSlacker) getPermalink(arg0 context.Context, arg1 *slack.PermalinkParameters) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getPermalink", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// getPermalink indicates an expected call of getPermalink.
+func (mr *MockSlackerMockRecorder) getPermalink(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getPermalink", reflect.TypeOf((*MockSlacker)(nil).getPermalink), arg0, arg1)
+}
+
+// getUserGroupMembers mocks base method.
+func (m *MockSlacker) getUserGroupMembers(arg0 context.Context, arg1 string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getUserGroupMembers", arg0, arg1)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// getUserGroupMembers indicates an expected call of getUserGroupMembers.
+func (mr *MockSlackerMockRecorder) getUserGroupMembers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getUserGroupMembers", reflect.TypeOf((*MockSlacker)(nil).getUserGroupMembers), arg0, arg1)
+}
+
+// getUserInfo mocks base method.
+func (m *MockSlacker) getUserInfo(arg0 context.Context, arg1 string) (*slack.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getUserInfo", arg0, arg1)
+	ret0, _ := ret[0].(*slack.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// getUserInfo indicates an expected call of getUserInfo.
+func (mr *MockSlackerMockRecorder) getUserInfo(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getUserInfo", reflect.TypeOf((*MockSlacker)(nil).getUserInfo), arg0, arg1)
+}
+
+// openView mocks base method.
+func (m *MockSlacker) openView(arg0 context.Context, arg1 string, arg2 slack.ModalViewRequest) (*slack.ViewResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "openView", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*slack.ViewResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// openView indicates an expected call of openView.
+func (mr *MockSlackerMockRecorder) openView(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "openView", reflect.TypeOf((*MockSlacker)(nil).openView), arg0, arg1, arg2)
+}
+
+// postBlocks mocks base method.
+func (m *MockSlacker) postBlocks(arg0 context.Context, arg1, arg2, arg3 string, arg4 []slack.Block) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "postBlocks", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// postBlocks indicates an expected call of postBlocks.
+func (mr *MockSlackerMockRecorder) postBlocks(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "postBlocks", reflect.TypeOf((*MockSlacker)(nil).postBlocks), arg0, arg1, arg2, arg3, arg4)
+}
+
+// postEphemeral mocks base method.
+func (m *MockSlacker) postEphemeral(arg0 context.Context, arg1, arg2, arg3 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "postEphemeral", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// postEphemeral indicates an expected call of postEphemeral.
+func (mr *MockSlackerMockRecorder) postEphemeral(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getConversations", reflect.TypeOf((*MockSlacker)(nil).getConversations), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "postEphemeral", reflect.TypeOf((*MockSlacker)(nil).postEphemeral), arg0, arg1, arg2, arg3)
 }
 
 // postMessage mocks base method.
-func (m *MockSlacker) postMessage(arg0, arg1, arg2 string) (string, string, error) {
+func (m *MockSlacker) postMessage(arg0 context.Context, arg1, arg2, arg3 string) (string, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "postMessage", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "postMessage", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(string)
 	ret2, _ := ret[2].(error)
@@ -78,7 +216,58 @@ func (m *MockSlacker) postMessage(arg0, arg1, arg2 string) (string, string, erro
 }
 
 // postMessage indicates an expected call of postMessage.
-func (mr *MockSlackerMockRecorder) postMessage(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockSlackerMockRecorder) postMessage(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "postMessage", reflect.TypeOf((*MockSlacker)(nil).postMessage), arg0, arg1, arg2, arg3)
+}
+
+// unfurl mocks base method.
+func (m *MockSlacker) unfurl(arg0 context.Context, arg1, arg2 string, arg3 map[string]slack.Attachment) (string, string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "unfurl", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(string)
+	ret3, _ := ret[3].(error)
+	return ret0, ret1, ret2, ret3
+}
+
+// unfurl indicates an expected call of unfurl.
+func (mr *MockSlackerMockRecorder) unfurl(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "unfurl", reflect.TypeOf((*MockSlacker)(nil).unfurl), arg0, arg1, arg2, arg3)
+}
+
+// updateBlocks mocks base method.
+func (m *MockSlacker) updateBlocks(arg0 context.Context, arg1, arg2, arg3 string, arg4 []slack.Block) (string, string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "updateBlocks", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(string)
+	ret3, _ := ret[3].(error)
+	return ret0, ret1, ret2, ret3
+}
+
+// updateBlocks indicates an expected call of updateBlocks.
+func (mr *MockSlackerMockRecorder) updateBlocks(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "updateBlocks", reflect.TypeOf((*MockSlacker)(nil).updateBlocks), arg0, arg1, arg2, arg3, arg4)
+}
+
+// updateMessage mocks base method.
+func (m *MockSlacker) updateMessage(arg0 context.Context, arg1, arg2, arg3 string) (string, string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "updateMessage", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(string)
+	ret3, _ := ret[3].(error)
+	return ret0, ret1, ret2, ret3
+}
+
+// updateMessage indicates an expected call of updateMessage.
+func (mr *MockSlackerMockRecorder) updateMessage(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "postMessage", reflect.TypeOf((*MockSlacker)(nil).postMessage), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "updateMessage", reflect.TypeOf((*MockSlacker)(nil).updateMessage), arg0, arg1, arg2, arg3)
 }
@@ -1,7 +1,9 @@
 package slack
 
 import(
+    "context"
     "testing"
+    "time"
 	"github.com/golang/mock/gomock"
     "github.com/stretchr/testify/assert"
 	"github.com/slack-go/slack"
@@ -53,12 +55,13 @@ func TestNewEnv(t *testing.T) {
 		SlackClient: mockClient,
 		SlackSigningSecret: "some-secret",
         SlackChannelNames: []string{"some-channel-name"},
+        SlackChannelNamesByID: map[string]string{"SOMECHANNELID": "some-channel-name"},
         SlackEmojis: map[string]string{"SOMECHANNELID": "some-emoji"},
         
 	}
 
 
-    mockClient.EXPECT().getConversations(&expectedParams).Times(1).Return([]slack.Channel{expectedChannel}, "", nil)
+    mockClient.EXPECT().getConversations(gomock.Any(), &expectedParams).Times(1).Return([]slack.Channel{expectedChannel}, "", nil)
 
     env, err := NewEnv(mockClient, "some-secret", map[string]string{"some-channel-name":"some-emoji"}, []string{"some-channel-name"})
 
@@ -88,9 +91,9 @@ func TestGetConversationMessages(t *testing.T) {
 
     expectedMessage := createSlackMessage()
 
-    mockClient.EXPECT().getConversationReplies(&expectedParams).Times(1).Return([]slack.Message{expectedMessage}, false, "", nil)
+    mockClient.EXPECT().getConversationReplies(gomock.Any(), &expectedParams).Times(1).Return([]slack.Message{expectedMessage}, false, "", nil)
 
-    messages, err := env.GetConversationMessages("some-channel", "some-timestamp")
+    messages, err := env.GetConversationMessages(context.Background(), "some-channel", "some-timestamp")
 
     assert.True(t, err == nil)
     assert.EqualValues(t, messages, []slack.Message{expectedMessage})
@@ -108,9 +111,92 @@ func TestPostMessage(t *testing.T) {
         
 	}
 
-    mockClient.EXPECT().postMessage("SOMECHANNELID", "some-timestamp", "some-message-body").Times(1).Return("ok", "", nil)
+    mockClient.EXPECT().postMessage(gomock.Any(), "SOMECHANNELID", "some-timestamp", "some-message-body").Times(1).Return("SOMECHANNELID", "some-reply-timestamp", nil)
 
-    env.PostMessageToThread("SOMECHANNELID", "some-timestamp", "some-message-body")
+    replyTimestamp, err := env.PostMessageToThread(context.Background(), "SOMECHANNELID", "some-timestamp", "some-message-body")
+    assert.Nil(t, err)
+    assert.Equal(t, "some-reply-timestamp", replyTimestamp)
 }
 
+func TestUpdateMessage(t *testing.T) {
+    mockClient := newMockSlacker(t)
+    env := &SlackEnv{SlackClient: mockClient}
+
+    mockClient.EXPECT().updateMessage(gomock.Any(), "SOMECHANNELID", "some-reply-timestamp", "some-message-body").Times(1).Return("SOMECHANNELID", "some-reply-timestamp", "some-message-body", nil)
+
+    assert.Nil(t, env.UpdateMessage(context.Background(), "SOMECHANNELID", "some-reply-timestamp", "some-message-body"))
+}
+
+
+
+func TestAuthTest(t *testing.T) {
+    mockClient := newMockSlacker(t)
+    env := &SlackEnv{SlackClient: mockClient}
+
+    mockClient.EXPECT().authTest(gomock.Any()).Times(1).Return(&slack.AuthTestResponse{TeamID: "TSOMETEAM"}, nil)
+
+    assert.Nil(t, env.AuthTest(context.Background()))
+
+}
+
+func TestIsGuest(t *testing.T) {
+    mockClient := newMockSlacker(t)
+    env := &SlackEnv{SlackClient: mockClient}
+
+    mockClient.EXPECT().getUserInfo(gomock.Any(), "USOMEMEMBER").Times(1).Return(&slack.User{ID: "USOMEMEMBER"}, nil)
+    mockClient.EXPECT().getUserInfo(gomock.Any(), "USOMEGUEST").Times(1).Return(&slack.User{ID: "USOMEGUEST", IsUltraRestricted: true}, nil)
+
+    guest, err := env.IsGuest(context.Background(), "USOMEMEMBER")
+    assert.Nil(t, err)
+    assert.False(t, guest)
+
+    guest, err = env.IsGuest(context.Background(), "USOMEGUEST")
+    assert.Nil(t, err)
+    assert.True(t, guest)
 
+}
+
+func TestUserGroupMembers(t *testing.T) {
+    mockClient := newMockSlacker(t)
+    env := &SlackEnv{SlackClient: mockClient}
+
+    mockClient.EXPECT().getUserGroupMembers(gomock.Any(), "SSOMEGROUP").Times(1).Return([]string{"USOMEMEMBER"}, nil)
+
+    members, err := env.UserGroupMembers(context.Background(), "SSOMEGROUP")
+    assert.Nil(t, err)
+    assert.Equal(t, []string{"USOMEMEMBER"}, members)
+
+    // the members are cached, the group is not looked up again
+    members, err = env.UserGroupMembers(context.Background(), "SSOMEGROUP")
+    assert.Nil(t, err)
+    assert.Equal(t, []string{"USOMEMEMBER"}, members)
+
+    // until they expire
+    env.userGroups["SSOMEGROUP"] = cachedUserGroup{members: members, expires: time.Now().Add(-time.Second)}
+    mockClient.EXPECT().getUserGroupMembers(gomock.Any(), "SSOMEGROUP").Times(1).Return([]string{"UOTHERMEMBER"}, nil)
+
+    members, err = env.UserGroupMembers(context.Background(), "SSOMEGROUP")
+    assert.Nil(t, err)
+    assert.Equal(t, []string{"UOTHERMEMBER"}, members)
+
+}
+
+func TestParsePermalink(t *testing.T) {
+    channel, timestamp, ok := ParsePermalink("https://acme.slack.com/archives/C0ALERTS/p1641160800000100")
+    assert.True(t, ok)
+    assert.Equal(t, "C0ALERTS", channel)
+    assert.Equal(t, "1641160800.000100", timestamp)
+
+    // a reply links to its own message, the thread is in thread_ts
+    channel, timestamp, ok = ParsePermalink("<https://acme.slack.com/archives/C0ALERTS/p1641160900000200?thread_ts=1641160800.000100&cid=C0ALERTS>")
+    assert.True(t, ok)
+    assert.Equal(t, "C0ALERTS", channel)
+    assert.Equal(t, "1641160800.000100", timestamp)
+
+    _, _, ok = ParsePermalink("https://acme.slack.com/team/U1")
+    assert.False(t, ok)
+
+    _, _, ok = ParsePermalink("OPS-1")
+    assert.False(t, ok)
+
+}
@@ -0,0 +1,123 @@
+package slack
+
+import (
+    "context"
+    "fmt"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+
+    "slack-jira-integration/logging"
+)
+
+// SocketModeEnv receives events over an outbound Socket Mode websocket
+// instead of the public /slack/events endpoint, events are already
+// authenticated by the app-level token so no request signing is involved
+type SocketModeEnv struct {
+    Client *socketmode.Client
+}
+
+// NewSocketModeEnv, construct a new SocketModeEnv given the bot token and
+// the app-level (xapp-) token with the connections:write scope
+func NewSocketModeEnv(slackBotToken string, slackAppToken string, options ...slack.Option) *SocketModeEnv {
+    options = append(options, slack.OptionAppLevelToken(slackAppToken))
+
+    return &SocketModeEnv{
+        Client: socketmode.New(slack.New(slackBotToken, options...)),
+    }
+
+}
+
+// SocketModeHandlers, what Run hands each kind of event to, the same
+// handlers the HTTP endpoints use, events without a handler are only acked
+type SocketModeHandlers struct {
+    EventsAPI func(context.Context, slackevents.EventsAPIEvent)
+    // Interaction returns the ack's payload, false when the interaction was
+    // not accepted
+    Interaction func(context.Context, *slack.InteractionCallback) (*slack.ViewSubmissionResponse, bool)
+    // SlashCommand returns the reply shown to the user right away
+    SlashCommand func(context.Context, slack.SlashCommand) string
+}
+
+// Run, connect to Slack and hand every Events API event, interaction and
+// slash command to handlers until ctx is cancelled, each is acknowledged
+// within 3 seconds as Slack retries or gives up on anything not
+// acknowledged by then. Cancelling ctx stops new events only, the handlers'
+// context is not cancelled with it so acked events are handled to the end
+func (s *SocketModeEnv) Run(ctx context.Context, handlers SocketModeHandlers) error {
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    errc := make(chan error, 1)
+    go func() {
+        errc <- s.Client.RunContext(ctx)
+    }()
+
+    for {
+        select {
+        case err := <-errc:
+            return err
+
+        case evt := <-s.Client.Events:
+            switch evt.Type {
+            case socketmode.EventTypeEventsAPI:
+                eventsAPIEvent, ok := evt.Data.(slackevents.EventsAPIEvent)
+                if !ok {
+                    continue
+                }
+
+                // Slack will not resend an acked event, it has to be drained not cancelled
+                s.Client.Ack(*evt.Request)
+                if handlers.EventsAPI != nil {
+                    handlers.EventsAPI(context.Background(), eventsAPIEvent)
+                }
+
+            case socketmode.EventTypeInteractive:
+                interaction, ok := evt.Data.(slack.InteractionCallback)
+                if !ok {
+                    continue
+                }
+
+                // the handler answers before acting, its answer is the ack's payload
+                if handlers.Interaction == nil {
+                    s.Client.Ack(*evt.Request)
+                    continue
+                }
+
+                response, accepted := handlers.Interaction(context.Background(), &interaction)
+                if !accepted {
+                    logging.FromContext(ctx).Warn("draining, interaction dropped")
+                }
+
+                if response != nil {
+                    s.Client.Ack(*evt.Request, response)
+                } else {
+                    s.Client.Ack(*evt.Request)
+                }
+
+            case socketmode.EventTypeSlashCommand:
+                command, ok := evt.Data.(slack.SlashCommand)
+                if !ok {
+                    continue
+                }
+
+                if handlers.SlashCommand == nil {
+                    s.Client.Ack(*evt.Request)
+                    continue
+                }
+
+                reply := handlers.SlashCommand(context.Background(), command)
+                s.Client.Ack(*evt.Request, &slack.Msg{ResponseType: slack.ResponseTypeEphemeral, Text: reply})
+
+            case socketmode.EventTypeConnectionError:
+                logging.FromContext(ctx).WithField("err", evt.Data).Warn("socket mode connection error, reconnecting")
+
+            case socketmode.EventTypeInvalidAuth:
+                return fmt.Errorf("socket mode invalid auth, check the app-level token")
+
+            }
+        }
+    }
+
+}
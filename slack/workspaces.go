@@ -0,0 +1,84 @@
+package slack
+
+import (
+    "fmt"
+    "sync"
+    "time"
+)
+
+// Workspaces resolves the SlackEnv for the workspace an event came from,
+// bot tokens come from the TokenStore filled by the OAuth install flow and
+// each SlackEnv is built once per installation and cached
+type Workspaces struct {
+    Store TokenStore
+    SlackSigningSecret string
+    SlackChannelNames []string
+    SlackEmojis map[string]string
+    UserGroupCacheTTL time.Duration
+    NewClient func(slackBotToken string) Slacker
+
+    mu sync.Mutex
+    envs map[string]*workspaceEnv
+}
+
+type workspaceEnv struct {
+    botToken string
+    env *SlackEnv
+}
+
+// NewWorkspaces, construct a new Workspaces, slackEmojis is indexed by channel
+// name as every workspace resolves its own channel ids
+func NewWorkspaces(store TokenStore, slackSigningSecret string, slackEmojis map[string]string, slackChannelNames []string) *Workspaces {
+    return &Workspaces{
+        Store: store,
+        SlackSigningSecret: slackSigningSecret,
+        SlackChannelNames: slackChannelNames,
+        SlackEmojis: slackEmojis,
+        NewClient: func(slackBotToken string) Slacker {
+            return NewClient(slackBotToken)
+        },
+        envs: make(map[string]*workspaceEnv),
+    }
+
+}
+
+// Get, returns the SlackEnv for the given team, falling back to the org wide
+// install of enterpriseID for Enterprise Grid
+func (w *Workspaces) Get(teamID string, enterpriseID string) (*SlackEnv, error) {
+    installation, err := w.Store.Get(teamID)
+    if err != nil {
+        return nil, err
+    }
+
+    if installation == nil && enterpriseID != "" {
+        installation, err = w.Store.Get(enterpriseID)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    if installation == nil {
+        return nil, fmt.Errorf("no installation for team '%s'", teamID)
+    }
+
+    w.mu.Lock()
+    defer w.mu.Unlock()
+
+    key := installation.key()
+
+    // a reinstall rotates the bot token, so rebuild the env when it changes
+    if cached, exists := w.envs[key]; exists && cached.botToken == installation.BotToken {
+        return cached.env, nil
+    }
+
+    env, err := NewEnv(w.NewClient(installation.BotToken), w.SlackSigningSecret, w.SlackEmojis, w.SlackChannelNames)
+    if err != nil {
+        return nil, fmt.Errorf("team '%s' env err: %w", teamID, err)
+    }
+    env.UserGroupCacheTTL = w.UserGroupCacheTTL
+
+    w.envs[key] = &workspaceEnv{botToken: installation.BotToken, env: env}
+
+    return env, nil
+
+}
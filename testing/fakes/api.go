@@ -0,0 +1,83 @@
+package fakes
+
+import (
+    "encoding/json"
+    "net/http"
+    "sync"
+)
+
+// api is the call counting and failure injection every fake embeds, the
+// fake decides how a rejected or failing call is answered
+type api struct {
+    // reject, when set, answers a request the fake refuses e.g. for a bad
+    // token and reports true
+    reject func(resp http.ResponseWriter, req *http.Request) bool
+
+    callsMu sync.Mutex
+    calls map[string]int
+    failures map[string]http.HandlerFunc
+}
+
+// method, count the call, let reject refuse it and answer with an injected
+// failure if there is one
+func (a *api) method(name string, handler http.HandlerFunc) http.HandlerFunc {
+    return func(resp http.ResponseWriter, req *http.Request) {
+        a.callsMu.Lock()
+        if a.calls == nil {
+            a.calls = make(map[string]int)
+        }
+        a.calls[name]++
+        failure, failing := a.failures[name]
+        a.callsMu.Unlock()
+
+        if a.reject != nil && a.reject(resp, req) {
+            return
+        }
+
+        if failing {
+            failure(resp, req)
+            return
+        }
+
+        handler(resp, req)
+
+    }
+
+}
+
+// fail, answer every later call of method with failure, nil stops failing
+func (a *api) fail(method string, failure http.HandlerFunc) {
+    a.callsMu.Lock()
+    defer a.callsMu.Unlock()
+
+    if failure == nil {
+        delete(a.failures, method)
+        return
+    }
+
+    if a.failures == nil {
+        a.failures = make(map[string]http.HandlerFunc)
+    }
+    a.failures[method] = failure
+
+}
+
+// Calls, how many times method was called, the names are the ones the fake
+// registers its handlers under
+func (a *api) Calls(method string) int {
+    a.callsMu.Lock()
+    defer a.callsMu.Unlock()
+
+    return a.calls[method]
+
+}
+
+// reply, answer with body encoded as JSON, nil leaves the body empty
+func (a *api) reply(resp http.ResponseWriter, status int, body interface{}) {
+    resp.Header().Set("Content-Type", "application/json")
+    resp.WriteHeader(status)
+    if body != nil {
+        json.NewEncoder(resp).Encode(body)
+    }
+
+}
@@ -0,0 +1,217 @@
+package fakes
+
+import (
+    "bytes"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    neturl "net/url"
+    "strconv"
+    "strings"
+    "time"
+
+	slackgo "github.com/slack-go/slack"
+)
+
+// Sign, the X-Slack-Signature of body sent at timestamp with secret
+func Sign(secret string, timestamp time.Time, body []byte) string {
+    mac := hmac.New(sha256.New, []byte(secret))
+    fmt.Fprintf(mac, "v0:%d:%s", timestamp.Unix(), body)
+
+    return "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+}
+
+// SignRequest, add the Slack signature headers to req as if Slack sent it at
+// timestamp, the body is read and put back
+func SignRequest(req *http.Request, secret string, timestamp time.Time) error {
+    var body []byte
+    if req.Body != nil {
+        var err error
+        body, err = ioutil.ReadAll(req.Body)
+        if err != nil {
+            return err
+        }
+        req.Body.Close()
+    }
+    req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+    req.Header.Set("X-Slack-Request-Timestamp", strconv.FormatInt(timestamp.Unix(), 10))
+    req.Header.Set("X-Slack-Signature", Sign(secret, timestamp, body))
+
+    return nil
+
+}
+
+// NewSignedRequest, a POST of body to url signed with secret as of now
+func NewSignedRequest(url string, secret string, body []byte) (*http.Request, error) {
+    req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/json")
+
+    return req, SignRequest(req, secret, time.Now())
+
+}
+
+// NewSignedFormRequest, a form-encoded POST of form to url signed with
+// secret as of now, the way Slack sends commands and interactions
+func NewSignedFormRequest(url string, secret string, form neturl.Values) (*http.Request, error) {
+    req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(form.Encode()))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+    return req, SignRequest(req, secret, time.Now())
+
+}
+
+// SlashCommand, the form Slack posts when user runs command with text in
+// channel, its answer is sent to responseURL
+func SlashCommand(team string, user string, channel string, command string, text string, responseURL string) neturl.Values {
+    return neturl.Values{
+        "token": {"fake-verification-token"},
+        "team_id": {team},
+        "channel_id": {channel},
+        "user_id": {user},
+        "command": {command},
+        "text": {text},
+        "api_app_id": {"AFAKEAPP"},
+        "trigger_id": {fmt.Sprintf("%d.fake", time.Now().UnixNano())},
+        "response_url": {responseURL},
+    }
+
+}
+
+// EventCallback, an Events API envelope wrapping event for team
+func EventCallback(team string, event map[string]interface{}) []byte {
+    envelope, _ := json.Marshal(map[string]interface{}{
+        "token": "fake-verification-token",
+        "team_id": team,
+        "api_app_id": "AFAKEAPP",
+        "type": "event_callback",
+        "event_id": fmt.Sprintf("Ev%d", time.Now().UnixNano()),
+        "event_time": time.Now().Unix(),
+        "event": event,
+    })
+
+    return envelope
+
+}
+
+func reactionEvent(eventType string, user string, channel string, timestamp string, reaction string) map[string]interface{} {
+    return map[string]interface{}{
+        "type": eventType,
+        "user": user,
+        "reaction": reaction,
+        "item": map[string]string{
+            "type": "message",
+            "channel": channel,
+            "ts": timestamp,
+        },
+        "event_ts": fmt.Sprintf("%d.000200", time.Now().Unix()),
+    }
+
+}
+
+// ReactionAdded, the envelope of user reacting with reaction to the message
+// at timestamp in channel
+func ReactionAdded(team string, user string, channel string, timestamp string, reaction string) []byte {
+    return EventCallback(team, reactionEvent("reaction_added", user, channel, timestamp, reaction))
+
+}
+
+// ReactionRemoved, the envelope of user removing their reaction
+func ReactionRemoved(team string, user string, channel string, timestamp string, reaction string) []byte {
+    return EventCallback(team, reactionEvent("reaction_removed", user, channel, timestamp, reaction))
+
+}
+
+// LinkShared, the envelope of user posting the message at timestamp in
+// channel containing links of an unfurl domain of the app
+func LinkShared(team string, user string, channel string, timestamp string, links ...string) []byte {
+    shared := []map[string]string{}
+    for _, link := range links {
+        parsed, _ := neturl.Parse(link)
+        shared = append(shared, map[string]string{"domain": parsed.Hostname(), "url": link})
+    }
+
+    return EventCallback(team, map[string]interface{}{
+        "type": "link_shared",
+        "user": user,
+        "channel": channel,
+        "message_ts": timestamp,
+        "links": shared,
+        "event_ts": fmt.Sprintf("%d.000300", time.Now().Unix()),
+    })
+
+}
+
+// Message, the envelope of user posting text at timestamp in channel
+func Message(team string, user string, channel string, timestamp string, text string) []byte {
+    return EventCallback(team, map[string]interface{}{
+        "type": "message",
+        "user": user,
+        "channel": channel,
+        "channel_type": "channel",
+        "text": text,
+        "ts": timestamp,
+        "event_ts": timestamp,
+    })
+
+}
+
+// URLVerification, the challenge Slack sends when the events URL is saved
+func URLVerification(challenge string) []byte {
+    envelope, _ := json.Marshal(map[string]string{
+        "token": "fake-verification-token",
+        "type": "url_verification",
+        "challenge": challenge,
+    })
+
+    return envelope
+
+}
+
+// interaction, the form Slack posts to the interactivity URL with payload
+func interaction(payload map[string]interface{}) neturl.Values {
+    body, _ := json.Marshal(payload)
+    return neturl.Values{"payload": {string(body)}}
+
+}
+
+// BlockAction, the form Slack posts when user clicks the button actionID of
+// blockID with value on the message at timestamp in channel
+func BlockAction(team string, user string, channel string, timestamp string, blockID string, actionID string, value string) neturl.Values {
+    return interaction(map[string]interface{}{
+        "type": "block_actions",
+        "team": map[string]string{"id": team},
+        "user": map[string]string{"id": user},
+        "api_app_id": "AFAKEAPP",
+        "trigger_id": fmt.Sprintf("%d.fake", time.Now().UnixNano()),
+        "channel": map[string]string{"id": channel},
+        "container": map[string]string{"type": "message", "message_ts": timestamp, "channel_id": channel},
+        "message": map[string]string{"type": "message", "ts": timestamp},
+        "actions": []map[string]string{{"type": "button", "block_id": blockID, "action_id": actionID, "value": value}},
+    })
+
+}
+
+// ViewSubmission, the form Slack posts when user submits view, a modal the
+// integration opened with its state filled in
+func ViewSubmission(team string, user string, view slackgo.View) neturl.Values {
+    return interaction(map[string]interface{}{
+        "type": "view_submission",
+        "team": map[string]string{"id": team},
+        "user": map[string]string{"id": user},
+        "api_app_id": "AFAKEAPP",
+        "view": view,
+    })
+
+}
@@ -0,0 +1,242 @@
+package fakes
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "sync"
+
+	"github.com/gorilla/mux"
+)
+
+// GitHubIssue is an issue held by the fake GitHub
+type GitHubIssue struct {
+    Number int `json:"number"`
+    Title string `json:"title"`
+    Body string `json:"body"`
+    Labels []string `json:"labels"`
+    State string `json:"state"`
+    StateReason string `json:"state_reason,omitempty"`
+    HTMLURL string `json:"html_url"`
+    Comments []string `json:"-"`
+}
+
+// GitHub is an in-process fake of the GitHub Issues REST API, repositories
+// must be added before issues can be opened in them
+type GitHub struct {
+    api
+
+    Server *httptest.Server
+    // URL is the API root, it ends with a slash
+    URL string
+    // Token is the only token accepted, empty accepts any
+    Token string
+
+    mu sync.Mutex
+    repositories map[string][]*GitHubIssue
+}
+
+// NewGitHub, start a fake GitHub API with the given owner/repo repositories,
+// it is closed with the test
+func NewGitHub(t interface{ Cleanup(func()) }, repositories ...string) *GitHub {
+    g := &GitHub{
+        repositories: make(map[string][]*GitHubIssue),
+    }
+
+    for _, repository := range repositories {
+        g.repositories[repository] = nil
+    }
+
+    g.reject = g.unauthorized
+
+    router := mux.NewRouter()
+    router.HandleFunc("/repos/{owner}/{repo}", g.method("getRepository", g.getRepository)).Methods("GET")
+    router.HandleFunc("/repos/{owner}/{repo}/issues", g.method("createIssue", g.createIssue)).Methods("POST")
+    router.HandleFunc("/repos/{owner}/{repo}/issues/{number}", g.method("getIssue", g.getIssue)).Methods("GET")
+    router.HandleFunc("/repos/{owner}/{repo}/issues/{number}", g.method("updateIssue", g.updateIssue)).Methods("PATCH")
+    router.HandleFunc("/repos/{owner}/{repo}/issues/{number}/comments", g.method("createComment", g.createComment)).Methods("POST")
+
+    g.Server = httptest.NewServer(router)
+    g.URL = g.Server.URL + "/"
+    t.Cleanup(g.Server.Close)
+
+    return g
+
+}
+
+// unauthorized, reject requests without the expected token
+func (g *GitHub) unauthorized(resp http.ResponseWriter, req *http.Request) bool {
+    if g.Token == "" || req.Header.Get("Authorization") == "Bearer " + g.Token {
+        return false
+    }
+
+    g.error(resp, http.StatusUnauthorized, "Bad credentials")
+    return true
+
+}
+
+// error, answer with a GitHub style error body
+func (g *GitHub) error(resp http.ResponseWriter, status int, message string) {
+    g.reply(resp, status, map[string]string{"message": message, "documentation_url": "https://docs.github.com/rest"})
+
+}
+
+// Issues, a copy of the issues of repository in the order they were opened
+func (g *GitHub) Issues(repository string) []GitHubIssue {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+
+    issues := []GitHubIssue{}
+    for _, issue := range g.repositories[repository] {
+        issues = append(issues, *issue)
+    }
+
+    return issues
+
+}
+
+// repository, the issues of the repository in the path or a 404, called with mu held
+func (g *GitHub) repository(resp http.ResponseWriter, req *http.Request) (string, bool) {
+    vars := mux.Vars(req)
+    repository := vars["owner"] + "/" + vars["repo"]
+
+    if _, exists := g.repositories[repository]; !exists {
+        g.error(resp, http.StatusNotFound, "Not Found")
+        return "", false
+    }
+
+    return repository, true
+
+}
+
+// issue, the issue in the path or a 404, called with mu held
+func (g *GitHub) issue(resp http.ResponseWriter, req *http.Request) (*GitHubIssue, bool) {
+    repository, exists := g.repository(resp, req)
+    if !exists {
+        return nil, false
+    }
+
+    number, _ := strconv.Atoi(mux.Vars(req)["number"])
+    issues := g.repositories[repository]
+    if number <= 0 || number > len(issues) {
+        g.error(resp, http.StatusNotFound, "Not Found")
+        return nil, false
+    }
+
+    return issues[number - 1], true
+
+}
+
+func (g *GitHub) getRepository(resp http.ResponseWriter, req *http.Request) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+
+    if repository, exists := g.repository(resp, req); exists {
+        g.reply(resp, http.StatusOK, map[string]interface{}{"full_name": repository, "has_issues": true})
+    }
+
+}
+
+func (g *GitHub) createIssue(resp http.ResponseWriter, req *http.Request) {
+    var payload struct {
+        Title string `json:"title"`
+        Body string `json:"body"`
+        Labels []string `json:"labels"`
+    }
+    if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+        g.error(resp, http.StatusBadRequest, "Problems parsing JSON")
+        return
+    }
+
+    g.mu.Lock()
+    defer g.mu.Unlock()
+
+    repository, exists := g.repository(resp, req)
+    if !exists {
+        return
+    }
+
+    if payload.Title == "" {
+        g.error(resp, http.StatusUnprocessableEntity, "Validation Failed: title is missing")
+        return
+    }
+
+    number := len(g.repositories[repository]) + 1
+    issue := &GitHubIssue{
+        Number: number,
+        Title: payload.Title,
+        Body: payload.Body,
+        Labels: payload.Labels,
+        State: "open",
+        HTMLURL: fmt.Sprintf("https://github.com/%s/issues/%d", repository, number),
+    }
+    g.repositories[repository] = append(g.repositories[repository], issue)
+
+    g.reply(resp, http.StatusCreated, issue)
+
+}
+
+func (g *GitHub) getIssue(resp http.ResponseWriter, req *http.Request) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+
+    if issue, exists := g.issue(resp, req); exists {
+        g.reply(resp, http.StatusOK, issue)
+    }
+
+}
+
+func (g *GitHub) updateIssue(resp http.ResponseWriter, req *http.Request) {
+    var payload struct {
+        State string `json:"state"`
+        StateReason string `json:"state_reason"`
+    }
+    if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+        g.error(resp, http.StatusBadRequest, "Problems parsing JSON")
+        return
+    }
+
+    g.mu.Lock()
+    defer g.mu.Unlock()
+
+    issue, exists := g.issue(resp, req)
+    if !exists {
+        return
+    }
+
+    if payload.State != "" && payload.State != "open" && payload.State != "closed" {
+        g.error(resp, http.StatusUnprocessableEntity, "Validation Failed: state is invalid")
+        return
+    }
+
+    if payload.State != "" {
+        issue.State, issue.StateReason = payload.State, payload.StateReason
+    }
+
+    g.reply(resp, http.StatusOK, issue)
+
+}
+
+func (g *GitHub) createComment(resp http.ResponseWriter, req *http.Request) {
+    var payload struct {
+        Body string `json:"body"`
+    }
+    if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+        g.error(resp, http.StatusBadRequest, "Problems parsing JSON")
+        return
+    }
+
+    g.mu.Lock()
+    defer g.mu.Unlock()
+
+    issue, exists := g.issue(resp, req)
+    if !exists {
+        return
+    }
+
+    issue.Comments = append(issue.Comments, payload.Body)
+    g.reply(resp, http.StatusCreated, map[string]interface{}{"id": len(issue.Comments), "body": payload.Body})
+
+}
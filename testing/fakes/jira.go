@@ -0,0 +1,549 @@
+package fakes
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/gorilla/mux"
+)
+
+// Jira is an in-process fake of the Jira REST API methods the integration
+// calls, issues are kept in memory with keys numbered per project
+type Jira struct {
+    api
+
+    Server *httptest.Server
+    URL string
+    AccountID string
+    // Transitions are offered for every issue, moving an issue sets its status
+    // to the transition's To
+    Transitions []jira.Transition
+    // Priorities can be given to every issue
+    Priorities []jira.Priority
+    // ServerDC answers like Jira Server and Data Center, users have names but
+    // no account ids and are searched with username= instead of query=
+    ServerDC bool
+
+    mu sync.Mutex
+    projects map[string]int
+    issues map[string]*jira.Issue
+    order []string
+    deleted []string
+    transitioned []Transitioned
+    remoteLinks map[string][]jira.RemoteLink
+    users []jira.User
+    // hiddenEmails are the addresses of the users hiding them by account id
+    hiddenEmails map[string]string
+}
+
+// NewJira, start a fake Jira serving the given project keys, it is closed
+// with the test
+func NewJira(t interface{ Cleanup(func()) }, projects ...string) *Jira {
+    j := &Jira{
+        AccountID: "fake-account-id",
+        Transitions: []jira.Transition{
+            {ID: "11", Name: "Start Progress", To: jira.Status{Name: "In Progress"}},
+            {ID: "21", Name: "Done", To: jira.Status{Name: "Done"}},
+            {ID: "31", Name: "Won't Do", To: jira.Status{Name: "Won't Do"}},
+        },
+        Priorities: []jira.Priority{
+            {ID: "1", Name: "High"},
+            {ID: "2", Name: "Medium"},
+            {ID: "3", Name: "Low"},
+        },
+        projects: make(map[string]int),
+        issues: make(map[string]*jira.Issue),
+        remoteLinks: make(map[string][]jira.RemoteLink),
+    }
+
+    for _, project := range projects {
+        j.projects[project] = 0
+    }
+
+    router := mux.NewRouter()
+    router.HandleFunc("/rest/api/2/myself", j.method("myself", j.myself)).Methods("GET")
+    router.HandleFunc("/rest/api/2/issue", j.method("createIssue", j.createIssue)).Methods("POST")
+    router.HandleFunc("/rest/api/2/issue/{key}", j.method("getIssue", j.getIssue)).Methods("GET")
+    router.HandleFunc("/rest/api/2/issue/{key}", j.method("deleteIssue", j.deleteIssue)).Methods("DELETE")
+    router.HandleFunc("/rest/api/2/issue/{key}", j.method("updateIssue", j.updateIssue)).Methods("PUT")
+    router.HandleFunc("/rest/api/2/issue/{key}/assignee", j.method("updateAssignee", j.updateAssignee)).Methods("PUT")
+    router.HandleFunc("/rest/api/2/issue/{key}/transitions", j.method("getTransitions", j.getTransitions)).Methods("GET")
+    router.HandleFunc("/rest/api/2/issue/{key}/transitions", j.method("doTransition", j.doTransition)).Methods("POST")
+    router.HandleFunc("/rest/api/2/issue/{key}/comment", j.method("addComment", j.addComment)).Methods("POST")
+    router.HandleFunc("/rest/api/2/issue/{key}/remotelink", j.method("addRemoteLink", j.addRemoteLink)).Methods("POST")
+    router.HandleFunc("/rest/api/2/issue/{key}/remotelink", j.method("deleteRemoteLink", j.deleteRemoteLink)).Methods("DELETE")
+    router.HandleFunc("/rest/api/2/user/search", j.method("findUsers", j.findUsers)).Methods("GET")
+    router.HandleFunc("/rest/api/2/priority", j.method("getPriorities", j.getPriorities)).Methods("GET")
+
+    j.Server = httptest.NewServer(router)
+    j.URL = j.Server.URL
+    t.Cleanup(j.Server.Close)
+
+    return j
+
+}
+
+// error, answer with a Jira style error body
+func (j *Jira) error(resp http.ResponseWriter, status int, message string, fields map[string]string) {
+    messages := []string{}
+    if message != "" {
+        messages = append(messages, message)
+    }
+    if fields == nil {
+        fields = map[string]string{}
+    }
+
+    j.reply(resp, status, map[string]interface{}{"errorMessages": messages, "errors": fields})
+
+}
+
+// Transitioned records an issue moved through a transition
+type Transitioned struct {
+    Key string
+    Status string
+}
+
+// AddProject, accept issues for project
+func (j *Jira) AddProject(project string) {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    if _, exists := j.projects[project]; !exists {
+        j.projects[project] = 0
+    }
+
+}
+
+// Fail, answer every call of method with status and a Jira error body, a
+// zero status stops failing
+func (j *Jira) Fail(method string, status int) {
+    if status == 0 {
+        j.fail(method, nil)
+        return
+    }
+
+    j.fail(method, func(resp http.ResponseWriter, req *http.Request) {
+        j.error(resp, status, fmt.Sprintf("injected %s failure", method), nil)
+
+    })
+
+}
+
+// Issues, the issues that still exist in the order they were created
+func (j *Jira) Issues() []jira.Issue {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    issues := []jira.Issue{}
+    for _, key := range j.order {
+        if issue, exists := j.issues[key]; exists {
+            issues = append(issues, *issue)
+        }
+    }
+
+    return issues
+
+}
+
+// Issue, the issue with key, nil when it does not exist
+func (j *Jira) Issue(key string) *jira.Issue {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    issue, exists := j.issues[key]
+    if !exists {
+        return nil
+    }
+
+    copied := *issue
+    return &copied
+
+}
+
+// AddIssue, add an issue to project as if it had been created earlier,
+// returns its key
+func (j *Jira) AddIssue(project string, summary string) string {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    j.projects[project]++
+    issue := &jira.Issue{
+        Key: fmt.Sprintf("%s-%d", project, j.projects[project]),
+        ID: fmt.Sprintf("%d", 10000 + len(j.order)),
+        Fields: &jira.IssueFields{
+            Project: jira.Project{Key: project},
+            Summary: summary,
+            Status: &jira.Status{Name: "In Progress"},
+        },
+    }
+    j.issues[issue.Key] = issue
+    j.order = append(j.order, issue.Key)
+
+    return issue.Key
+
+}
+
+// AddUser, add a Jira user found by its email address
+func (j *Jira) AddUser(accountID string, displayName string, email string) {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    j.users = append(j.users, jira.User{AccountID: accountID, DisplayName: displayName, EmailAddress: email, Active: true})
+
+}
+
+// AddHiddenUser, add a Jira user found by its email address that does not
+// show it, as users can choose to
+func (j *Jira) AddHiddenUser(accountID string, displayName string, email string) {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    if j.hiddenEmails == nil {
+        j.hiddenEmails = make(map[string]string)
+    }
+    j.hiddenEmails[accountID] = email
+    j.users = append(j.users, jira.User{AccountID: accountID, DisplayName: displayName, Active: true})
+
+}
+
+// RemoteLinks, the remote links of the issue with key in the order they were added
+func (j *Jira) RemoteLinks(key string) []jira.RemoteLink {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    return append([]jira.RemoteLink(nil), j.remoteLinks[key]...)
+
+}
+
+// Deleted, the keys of deleted issues
+func (j *Jira) Deleted() []string {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    return append([]string(nil), j.deleted...)
+
+}
+
+// Transitioned, every transition made in order
+func (j *Jira) Transitioned() []Transitioned {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    return append([]Transitioned(nil), j.transitioned...)
+
+}
+
+func (j *Jira) myself(resp http.ResponseWriter, req *http.Request) {
+    if j.ServerDC {
+        j.reply(resp, http.StatusOK, jira.User{Name: "fake", Key: "fake"})
+        return
+    }
+
+    j.reply(resp, http.StatusOK, jira.User{AccountID: j.AccountID, Name: "fake"})
+
+}
+
+func (j *Jira) createIssue(resp http.ResponseWriter, req *http.Request) {
+    var issue jira.Issue
+    if err := json.NewDecoder(req.Body).Decode(&issue); err != nil || issue.Fields == nil {
+        j.error(resp, http.StatusBadRequest, "could not parse the issue", nil)
+        return
+    }
+
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    project := issue.Fields.Project.Key
+    count, exists := j.projects[project]
+    if !exists {
+        j.error(resp, http.StatusBadRequest, "", map[string]string{"project": "valid project is required"})
+        return
+    }
+
+    if strings.TrimSpace(issue.Fields.Summary) == "" {
+        j.error(resp, http.StatusBadRequest, "", map[string]string{"summary": "You must specify a summary of the issue."})
+        return
+    }
+
+    count++
+    j.projects[project] = count
+
+    issue.Key = fmt.Sprintf("%s-%d", project, count)
+    issue.ID = fmt.Sprintf("%d", 10000 + len(j.order))
+    issue.Self = fmt.Sprintf("%s/rest/api/2/issue/%s", j.URL, issue.ID)
+    issue.Fields.Status = &jira.Status{Name: "To Do"}
+    j.issues[issue.Key] = &issue
+    j.order = append(j.order, issue.Key)
+
+    j.reply(resp, http.StatusCreated, map[string]string{"id": issue.ID, "key": issue.Key, "self": issue.Self})
+
+}
+
+// issue, the issue named in the path or a 404, called with mu held
+func (j *Jira) issue(resp http.ResponseWriter, req *http.Request) (*jira.Issue, bool) {
+    issue, exists := j.issues[mux.Vars(req)["key"]]
+    if !exists {
+        j.error(resp, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.", nil)
+    }
+
+    return issue, exists
+
+}
+
+func (j *Jira) getIssue(resp http.ResponseWriter, req *http.Request) {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    if issue, exists := j.issue(resp, req); exists {
+        j.reply(resp, http.StatusOK, issue)
+    }
+
+}
+
+func (j *Jira) deleteIssue(resp http.ResponseWriter, req *http.Request) {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    if issue, exists := j.issue(resp, req); exists {
+        delete(j.issues, issue.Key)
+        j.deleted = append(j.deleted, issue.Key)
+        resp.WriteHeader(http.StatusNoContent)
+    }
+
+}
+
+func (j *Jira) getTransitions(resp http.ResponseWriter, req *http.Request) {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    if _, exists := j.issue(resp, req); exists {
+        j.reply(resp, http.StatusOK, map[string]interface{}{"transitions": j.Transitions})
+    }
+
+}
+
+func (j *Jira) doTransition(resp http.ResponseWriter, req *http.Request) {
+    var payload jira.CreateTransitionPayload
+    if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+        j.error(resp, http.StatusBadRequest, "could not parse the transition", nil)
+        return
+    }
+
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    issue, exists := j.issue(resp, req)
+    if !exists {
+        return
+    }
+
+    for _, transition := range j.Transitions {
+        if transition.ID == payload.Transition.ID {
+            status := transition.To
+            issue.Fields.Status = &status
+            j.transitioned = append(j.transitioned, Transitioned{Key: issue.Key, Status: status.Name})
+            resp.WriteHeader(http.StatusNoContent)
+            return
+        }
+    }
+
+    j.error(resp, http.StatusBadRequest, "", map[string]string{"transition": "invalid transition"})
+
+}
+
+func (j *Jira) addComment(resp http.ResponseWriter, req *http.Request) {
+    var comment jira.Comment
+    if err := json.NewDecoder(req.Body).Decode(&comment); err != nil || comment.Body == "" {
+        j.error(resp, http.StatusBadRequest, "", map[string]string{"comment": "Comment body can not be empty!"})
+        return
+    }
+
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    issue, exists := j.issue(resp, req)
+    if !exists {
+        return
+    }
+
+    if issue.Fields.Comments == nil {
+        issue.Fields.Comments = &jira.Comments{}
+    }
+    comment.ID = fmt.Sprintf("%d", len(issue.Fields.Comments.Comments) + 1)
+    issue.Fields.Comments.Comments = append(issue.Fields.Comments.Comments, &comment)
+
+    j.reply(resp, http.StatusCreated, comment)
+
+}
+
+func (j *Jira) addRemoteLink(resp http.ResponseWriter, req *http.Request) {
+    var remoteLink jira.RemoteLink
+    if err := json.NewDecoder(req.Body).Decode(&remoteLink); err != nil || remoteLink.Object == nil || remoteLink.Object.URL == "" {
+        j.error(resp, http.StatusBadRequest, "", map[string]string{"url": "'url' is required"})
+        return
+    }
+
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    issue, exists := j.issue(resp, req)
+    if !exists {
+        return
+    }
+
+    // a link with the same global id replaces the earlier one
+    links := j.remoteLinks[issue.Key]
+    for i, link := range links {
+        if remoteLink.GlobalID != "" && link.GlobalID == remoteLink.GlobalID {
+            remoteLink.ID = link.ID
+            links[i] = remoteLink
+            j.reply(resp, http.StatusOK, map[string]interface{}{"id": remoteLink.ID})
+            return
+        }
+    }
+
+    remoteLink.ID = len(links) + 1
+    j.remoteLinks[issue.Key] = append(links, remoteLink)
+
+    j.reply(resp, http.StatusCreated, map[string]interface{}{"id": remoteLink.ID, "self": fmt.Sprintf("%s/rest/api/2/issue/%s/remotelink/%d", j.URL, issue.Key, remoteLink.ID)})
+
+}
+
+func (j *Jira) deleteRemoteLink(resp http.ResponseWriter, req *http.Request) {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    issue, exists := j.issue(resp, req)
+    if !exists {
+        return
+    }
+
+    globalID := req.URL.Query().Get("globalId")
+    links := j.remoteLinks[issue.Key]
+    for i, link := range links {
+        if link.GlobalID == globalID {
+            j.remoteLinks[issue.Key] = append(links[:i:i], links[i + 1:]...)
+            resp.WriteHeader(http.StatusNoContent)
+            return
+        }
+    }
+
+    j.error(resp, http.StatusNotFound, fmt.Sprintf("no remote link with global id %s", globalID), nil)
+
+}
+
+func (j *Jira) findUsers(resp http.ResponseWriter, req *http.Request) {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    param := "query"
+    if j.ServerDC {
+        param = "username"
+    }
+
+    query := req.URL.Query().Get(param)
+    if query == "" {
+        j.error(resp, http.StatusBadRequest, fmt.Sprintf("The '%s' query parameter is required.", param), nil)
+        return
+    }
+
+    users := []jira.User{}
+    for _, user := range j.users {
+        hidden, hides := j.hiddenEmails[user.AccountID]
+        if !strings.EqualFold(user.EmailAddress, query) && !(hides && strings.EqualFold(hidden, query)) {
+            continue
+        }
+
+        users = append(users, j.serverUser(user))
+    }
+
+    j.reply(resp, http.StatusOK, users)
+
+}
+
+func (j *Jira) updateAssignee(resp http.ResponseWriter, req *http.Request) {
+    var assignee jira.User
+    if err := json.NewDecoder(req.Body).Decode(&assignee); err != nil {
+        j.error(resp, http.StatusBadRequest, "could not parse the assignee", nil)
+        return
+    }
+
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    issue, exists := j.issue(resp, req)
+    if !exists {
+        return
+    }
+
+    for _, user := range j.users {
+        assigned := j.serverUser(user)
+        if (!j.ServerDC && user.AccountID == assignee.AccountID) || (j.ServerDC && assigned.Name == assignee.Name) {
+            issue.Fields.Assignee = &assigned
+            resp.WriteHeader(http.StatusNoContent)
+            return
+        }
+    }
+
+    j.error(resp, http.StatusBadRequest, "", map[string]string{"assignee": fmt.Sprintf("User '%s' does not exist.", assignee.AccountID + assignee.Name)})
+
+}
+
+// serverUser, user as Server and Data Center show it when the fake answers
+// like them, named by the account id it was added with
+func (j *Jira) serverUser(user jira.User) jira.User {
+    if j.ServerDC {
+        user.Name, user.AccountID = user.AccountID, ""
+    }
+
+    return user
+
+}
+
+func (j *Jira) getPriorities(resp http.ResponseWriter, req *http.Request) {
+    j.reply(resp, http.StatusOK, j.Priorities)
+
+}
+
+func (j *Jira) updateIssue(resp http.ResponseWriter, req *http.Request) {
+    var update struct {
+        Fields struct {
+            Priority *jira.Priority `json:"priority"`
+        } `json:"fields"`
+    }
+    if err := json.NewDecoder(req.Body).Decode(&update); err != nil {
+        j.error(resp, http.StatusBadRequest, "could not parse the update", nil)
+        return
+    }
+
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    issue, exists := j.issue(resp, req)
+    if !exists {
+        return
+    }
+
+    // only the priority is supported
+    if update.Fields.Priority == nil {
+        j.error(resp, http.StatusBadRequest, "", map[string]string{"fields": "only priority can be updated"})
+        return
+    }
+
+    for _, priority := range j.Priorities {
+        if priority.Name == update.Fields.Priority.Name || (update.Fields.Priority.ID != "" && priority.ID == update.Fields.Priority.ID) {
+            set := priority
+            issue.Fields.Priority = &set
+            resp.WriteHeader(http.StatusNoContent)
+            return
+        }
+    }
+
+    j.error(resp, http.StatusBadRequest, "", map[string]string{"priority": "Specify the Priority (name) in the string format"})
+
+}
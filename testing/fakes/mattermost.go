@@ -0,0 +1,514 @@
+package fakes
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "sync"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// MattermostPost is a post held by the fake Mattermost
+type MattermostPost struct {
+    ID string `json:"id"`
+    ChannelID string `json:"channel_id"`
+    RootID string `json:"root_id"`
+    UserID string `json:"user_id"`
+    Message string `json:"message"`
+    CreateAt int64 `json:"create_at"`
+}
+
+// Mattermost is an in-process fake of the Mattermost REST API v4 methods
+// the integration calls and of its websocket, reactions are pushed to every
+// connected websocket with React and Unreact
+type Mattermost struct {
+    api
+
+    Server *httptest.Server
+    // URL is the server root, it ends with a slash
+    URL string
+    // Token is the only token accepted, empty accepts any
+    Token string
+    TeamID string
+    TeamName string
+    BotUserID string
+
+    mu sync.Mutex
+    channels map[string]string
+    posts []*MattermostPost
+    ephemeral []MattermostPost
+    updated []MattermostPost
+    users map[string]string
+    groups map[string][]string
+    conns []*websocket.Conn
+}
+
+// NewMattermost, start a fake Mattermost server with a team named team, it
+// is closed with the test
+func NewMattermost(t interface{ Cleanup(func()) }, team string) *Mattermost {
+    m := &Mattermost{
+        TeamID: "faketeamid",
+        TeamName: team,
+        BotUserID: "fakebotuserid",
+        channels: make(map[string]string),
+        users: make(map[string]string),
+        groups: make(map[string][]string),
+    }
+
+    m.reject = m.unauthorized
+
+    router := mux.NewRouter()
+    api := router.PathPrefix("/api/v4").Subrouter()
+    api.HandleFunc("/users/me", m.method("getMe", m.getMe)).Methods("GET")
+    api.HandleFunc("/users/{id}", m.method("getUser", m.getUser)).Methods("GET")
+    api.HandleFunc("/teams/name/{name}", m.method("getTeam", m.getTeam)).Methods("GET")
+    api.HandleFunc("/teams/{team}/channels/name/{name}", m.method("getChannel", m.getChannel)).Methods("GET")
+    api.HandleFunc("/posts", m.method("createPost", m.createPost)).Methods("POST")
+    api.HandleFunc("/posts/ephemeral", m.method("createEphemeralPost", m.createEphemeralPost)).Methods("POST")
+    api.HandleFunc("/posts/{id}", m.method("getPost", m.getPost)).Methods("GET")
+    api.HandleFunc("/posts/{id}/thread", m.method("getThread", m.getThread)).Methods("GET")
+    api.HandleFunc("/posts/{id}/patch", m.method("patchPost", m.patchPost)).Methods("PUT")
+    api.HandleFunc("/groups/{id}/members", m.method("getGroupMembers", m.getGroupMembers)).Methods("GET")
+    api.HandleFunc("/websocket", m.method("websocket", m.websocket)).Methods("GET")
+
+    m.Server = httptest.NewServer(router)
+    m.URL = m.Server.URL + "/"
+    t.Cleanup(m.Server.Close)
+    t.Cleanup(m.closeConns)
+
+    return m
+
+}
+
+// unauthorized, reject requests without the expected token
+func (m *Mattermost) unauthorized(resp http.ResponseWriter, req *http.Request) bool {
+    if m.Token == "" || req.Header.Get("Authorization") == "Bearer " + m.Token {
+        return false
+    }
+
+    m.error(resp, http.StatusUnauthorized, "Invalid or expired session, please login again.")
+    return true
+
+}
+
+// error, answer with a Mattermost style error body
+func (m *Mattermost) error(resp http.ResponseWriter, status int, message string) {
+    m.reply(resp, status, map[string]interface{}{"id": "fake.error", "message": message, "status_code": status})
+
+}
+
+// AddChannel, add a channel to the team
+func (m *Mattermost) AddChannel(id string, name string) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    m.channels[id] = name
+
+}
+
+// addPost, store a new post, called with mu held
+func (m *Mattermost) addPost(channelID string, rootID string, userID string, message string) *MattermostPost {
+    created := &MattermostPost{
+        ID: fmt.Sprintf("post%d", len(m.posts) + 1),
+        ChannelID: channelID,
+        RootID: rootID,
+        UserID: userID,
+        Message: message,
+        CreateAt: int64(1641160800000 + len(m.posts)),
+    }
+    m.posts = append(m.posts, created)
+
+    return created
+
+}
+
+// AddPost, add a root post to channel, returns its id
+func (m *Mattermost) AddPost(channelID string, userID string, message string) string {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    return m.addPost(channelID, "", userID, message).ID
+
+}
+
+// AddReply, add a reply to the thread of rootID, returns its id
+func (m *Mattermost) AddReply(rootID string, userID string, message string) string {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    root := m.post(rootID)
+    if root == nil {
+        return ""
+    }
+
+    return m.addPost(root.ChannelID, rootID, userID, message).ID
+
+}
+
+// AddUser, add a user, guests have the system_guest role
+func (m *Mattermost) AddUser(id string, guest bool) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    m.users[id] = "system_user"
+    if guest {
+        m.users[id] = "system_guest"
+    }
+
+}
+
+// AddGroup, add a group with the given member ids
+func (m *Mattermost) AddGroup(id string, members ...string) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    m.groups[id] = members
+
+}
+
+// Fail, answer every later call of method with status
+func (m *Mattermost) Fail(method string, status int) {
+    m.fail(method, func(resp http.ResponseWriter, req *http.Request) {
+        m.error(resp, status, fmt.Sprintf("injected %s failure", method))
+
+    })
+
+}
+
+// Posted, the posts the bot created in the order they were created
+func (m *Mattermost) Posted() []MattermostPost {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    posted := []MattermostPost{}
+    for _, p := range m.posts {
+        if p.UserID == m.BotUserID {
+            posted = append(posted, *p)
+        }
+    }
+
+    return posted
+
+}
+
+// Ephemeral, the ephemeral posts sent, UserID is their recipient
+func (m *Mattermost) Ephemeral() []MattermostPost {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    return append([]MattermostPost{}, m.ephemeral...)
+
+}
+
+// Updated, the posts as they were after each patch
+func (m *Mattermost) Updated() []MattermostPost {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    return append([]MattermostPost{}, m.updated...)
+
+}
+
+// Connected, how many websockets are connected
+func (m *Mattermost) Connected() int {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    return len(m.conns)
+
+}
+
+// React, push a reaction_added event for userID's emoji on postID to every websocket
+func (m *Mattermost) React(userID string, postID string, emoji string) {
+    m.broadcastReaction("reaction_added", userID, postID, emoji)
+
+}
+
+// Unreact, push a reaction_removed event for userID's emoji on postID to every websocket
+func (m *Mattermost) Unreact(userID string, postID string, emoji string) {
+    m.broadcastReaction("reaction_removed", userID, postID, emoji)
+
+}
+
+func (m *Mattermost) broadcastReaction(event string, userID string, postID string, emoji string) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    channelID := ""
+    if p := m.post(postID); p != nil {
+        channelID = p.ChannelID
+    }
+
+    reaction, _ := json.Marshal(map[string]interface{}{
+        "user_id": userID,
+        "post_id": postID,
+        "emoji_name": emoji,
+        "create_at": 1641160900000,
+    })
+
+    for _, conn := range m.conns {
+        conn.WriteJSON(map[string]interface{}{
+            "event": event,
+            "data": map[string]string{"reaction": string(reaction)},
+            "broadcast": map[string]string{"channel_id": channelID},
+        })
+    }
+
+}
+
+func (m *Mattermost) closeConns() {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    for _, conn := range m.conns {
+        conn.Close()
+    }
+    m.conns = nil
+
+}
+
+// post, the post with id or nil, called with mu held
+func (m *Mattermost) post(id string) *MattermostPost {
+    for _, p := range m.posts {
+        if p.ID == id {
+            return p
+        }
+    }
+
+    return nil
+
+}
+
+func (m *Mattermost) getMe(resp http.ResponseWriter, req *http.Request) {
+    m.reply(resp, http.StatusOK, map[string]string{"id": m.BotUserID, "username": "fakebot", "roles": "system_user"})
+
+}
+
+func (m *Mattermost) getUser(resp http.ResponseWriter, req *http.Request) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    id := mux.Vars(req)["id"]
+    roles, exists := m.users[id]
+    if !exists {
+        m.error(resp, http.StatusNotFound, "Unable to find the user.")
+        return
+    }
+
+    m.reply(resp, http.StatusOK, map[string]string{"id": id, "roles": roles})
+
+}
+
+func (m *Mattermost) getTeam(resp http.ResponseWriter, req *http.Request) {
+    if mux.Vars(req)["name"] != m.TeamName {
+        m.error(resp, http.StatusNotFound, "Unable to find the team.")
+        return
+    }
+
+    m.reply(resp, http.StatusOK, map[string]string{"id": m.TeamID, "name": m.TeamName})
+
+}
+
+func (m *Mattermost) getChannel(resp http.ResponseWriter, req *http.Request) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    vars := mux.Vars(req)
+    if vars["team"] == m.TeamID {
+        for id, name := range m.channels {
+            if name == vars["name"] {
+                m.reply(resp, http.StatusOK, map[string]string{"id": id, "name": name, "team_id": m.TeamID})
+                return
+            }
+        }
+    }
+
+    m.error(resp, http.StatusNotFound, "Unable to find the channel.")
+
+}
+
+func (m *Mattermost) createPost(resp http.ResponseWriter, req *http.Request) {
+    var payload MattermostPost
+    if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+        m.error(resp, http.StatusBadRequest, "Invalid or missing post in request body.")
+        return
+    }
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    if _, exists := m.channels[payload.ChannelID]; !exists {
+        m.error(resp, http.StatusForbidden, "You do not have the appropriate permissions.")
+        return
+    }
+
+    // like the real server replies may only be made to the root of a thread
+    if payload.RootID != "" {
+        root := m.post(payload.RootID)
+        if root == nil || root.RootID != "" || root.ChannelID != payload.ChannelID {
+            m.error(resp, http.StatusBadRequest, "Invalid RootId parameter.")
+            return
+        }
+    }
+
+    m.reply(resp, http.StatusCreated, m.addPost(payload.ChannelID, payload.RootID, m.BotUserID, payload.Message))
+
+}
+
+func (m *Mattermost) createEphemeralPost(resp http.ResponseWriter, req *http.Request) {
+    var payload struct {
+        UserID string `json:"user_id"`
+        Post MattermostPost `json:"post"`
+    }
+    if err := json.NewDecoder(req.Body).Decode(&payload); err != nil || payload.UserID == "" {
+        m.error(resp, http.StatusBadRequest, "Invalid or missing post in request body.")
+        return
+    }
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    ephemeral := payload.Post
+    ephemeral.ID, ephemeral.UserID = fmt.Sprintf("ephemeral%d", len(m.ephemeral) + 1), payload.UserID
+    m.ephemeral = append(m.ephemeral, ephemeral)
+
+    m.reply(resp, http.StatusCreated, ephemeral)
+
+}
+
+func (m *Mattermost) getPost(resp http.ResponseWriter, req *http.Request) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    p := m.post(mux.Vars(req)["id"])
+    if p == nil {
+        m.error(resp, http.StatusNotFound, "Unable to find the post.")
+        return
+    }
+
+    m.reply(resp, http.StatusOK, p)
+
+}
+
+func (m *Mattermost) getThread(resp http.ResponseWriter, req *http.Request) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    p := m.post(mux.Vars(req)["id"])
+    if p == nil {
+        m.error(resp, http.StatusNotFound, "Unable to find the post.")
+        return
+    }
+
+    rootID := p.ID
+    if p.RootID != "" {
+        rootID = p.RootID
+    }
+
+    // like the real server the order is newest first
+    order := []string{}
+    posts := make(map[string]MattermostPost)
+    for i := len(m.posts) - 1; i >= 0; i-- {
+        if m.posts[i].ID == rootID || m.posts[i].RootID == rootID {
+            order = append(order, m.posts[i].ID)
+            posts[m.posts[i].ID] = *m.posts[i]
+        }
+    }
+
+    m.reply(resp, http.StatusOK, map[string]interface{}{"order": order, "posts": posts})
+
+}
+
+func (m *Mattermost) patchPost(resp http.ResponseWriter, req *http.Request) {
+    var payload struct {
+        Message *string `json:"message"`
+    }
+    if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+        m.error(resp, http.StatusBadRequest, "Invalid or missing post in request body.")
+        return
+    }
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    p := m.post(mux.Vars(req)["id"])
+    if p == nil {
+        m.error(resp, http.StatusNotFound, "Unable to find the post.")
+        return
+    }
+
+    if payload.Message != nil {
+        p.Message = *payload.Message
+    }
+    m.updated = append(m.updated, *p)
+
+    m.reply(resp, http.StatusOK, p)
+
+}
+
+func (m *Mattermost) getGroupMembers(resp http.ResponseWriter, req *http.Request) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    members, exists := m.groups[mux.Vars(req)["id"]]
+    if !exists {
+        m.error(resp, http.StatusNotFound, "Unable to find the group.")
+        return
+    }
+
+    users := []map[string]string{}
+    for _, member := range members {
+        users = append(users, map[string]string{"id": member})
+    }
+
+    m.reply(resp, http.StatusOK, map[string]interface{}{"members": users, "total_member_count": len(users)})
+
+}
+
+var upgrader = websocket.Upgrader{
+    CheckOrigin: func(req *http.Request) bool {
+        return true
+    },
+}
+
+// websocket, upgrade and greet with hello like the real server, events are
+// pushed by React and Unreact, anything the client sends is discarded
+func (m *Mattermost) websocket(resp http.ResponseWriter, req *http.Request) {
+    conn, err := upgrader.Upgrade(resp, req, nil)
+    if err != nil {
+        return
+    }
+
+    m.mu.Lock()
+    conn.WriteJSON(map[string]interface{}{
+        "event": "hello",
+        "data": map[string]string{"server_version": "fake"},
+        "broadcast": map[string]string{"user_id": m.BotUserID},
+    })
+    m.conns = append(m.conns, conn)
+    m.mu.Unlock()
+
+    go func() {
+        for {
+            if _, _, err := conn.ReadMessage(); err != nil {
+                break
+            }
+        }
+
+        m.mu.Lock()
+        defer m.mu.Unlock()
+
+        for i, other := range m.conns {
+            if other == conn {
+                m.conns = append(m.conns[:i], m.conns[i + 1:]...)
+                break
+            }
+        }
+        conn.Close()
+
+    }()
+
+}
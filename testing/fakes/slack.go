@@ -0,0 +1,501 @@
+package fakes
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "strings"
+    "sync"
+
+	slackgo "github.com/slack-go/slack"
+)
+
+// Slack is an in-process fake of the Slack Web API methods the integration
+// calls, channels, threads, users and user groups are held in memory and
+// every message the bot sends is recorded
+type Slack struct {
+    api
+
+    Server *httptest.Server
+    // URL is the API URL for slack.OptionAPIURL, it ends with a slash
+    URL string
+    // PageSize is the page size of conversations.list and conversations.replies
+    PageSize int
+    TeamID string
+    BotUserID string
+    // ResponseURL is the response_url of slash commands, the answers sent to
+    // it are recorded
+    ResponseURL string
+
+    mu sync.Mutex
+    channels []slackgo.Channel
+    threads map[string][]slackgo.Message
+    users map[string]*slackgo.User
+    userGroups map[string][]string
+    members map[string][]string
+    posted []slackgo.Message
+    ephemeral []slackgo.Message
+    updated []slackgo.Message
+    views []slackgo.ModalViewRequest
+    unfurled []Unfurled
+    responses []slackgo.WebhookMessage
+    nextTimestamp int
+}
+
+// NewSlack, start a fake Slack API, it is closed with the test
+func NewSlack(t interface{ Cleanup(func()) }) *Slack {
+    s := &Slack{
+        PageSize: 100,
+        TeamID: "TFAKETEAM",
+        BotUserID: "UFAKEBOT",
+        threads: make(map[string][]slackgo.Message),
+        users: make(map[string]*slackgo.User),
+        userGroups: make(map[string][]string),
+        members: make(map[string][]string),
+        nextTimestamp: 1641160800,
+    }
+
+    methods := map[string]http.HandlerFunc{
+        "auth.test": s.authTest,
+        "conversations.list": s.conversationsList,
+        "conversations.replies": s.conversationsReplies,
+        "chat.postMessage": s.postMessage,
+        "chat.postEphemeral": s.postEphemeral,
+        "chat.update": s.update,
+        "chat.getPermalink": s.getPermalink,
+        "views.open": s.viewsOpen,
+        "chat.unfurl": s.unfurl,
+        "conversations.info": s.conversationsInfo,
+        "conversations.members": s.conversationsMembers,
+        "users.info": s.usersInfo,
+        "usergroups.users.list": s.userGroupsUsersList,
+    }
+
+    mux := http.NewServeMux()
+    for method, handler := range methods {
+        mux.HandleFunc("/" + method, s.method(method, parseForm(handler)))
+    }
+
+    mux.HandleFunc("/commands/response", s.method("response_url", s.respond))
+
+    s.Server = httptest.NewServer(mux)
+    s.URL = s.Server.URL + "/"
+    s.ResponseURL = s.Server.URL + "/commands/response"
+    t.Cleanup(s.Server.Close)
+
+    return s
+
+}
+
+// parseForm, parse the form of every Web API call before handling it
+func parseForm(handler http.HandlerFunc) http.HandlerFunc {
+    return func(resp http.ResponseWriter, req *http.Request) {
+        req.ParseForm()
+        handler(resp, req)
+
+    }
+
+}
+
+func threadKey(channel string, timestamp string) string {
+    return channel + "/" + timestamp
+
+}
+
+// timestamp, a new unique message timestamp, called with mu held
+func (s *Slack) timestamp() string {
+    s.nextTimestamp++
+    return fmt.Sprintf("%d.000100", s.nextTimestamp)
+
+}
+
+// page, the items of one page starting at cursor and the cursor of the next
+func page(cursor string, total int, size int) (int, int, string) {
+    start, _ := strconv.Atoi(cursor)
+    if start > total {
+        start = total
+    }
+
+    end := start + size
+    if end >= total {
+        return start, total, ""
+    }
+
+    return start, end, strconv.Itoa(end)
+
+}
+
+// AddChannel, add a public channel
+func (s *Slack) AddChannel(id string, name string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    channel := slackgo.Channel{}
+    channel.ID, channel.Name = id, name
+    s.channels = append(s.channels, channel)
+
+}
+
+// AddMember, add users to the members of channel
+func (s *Slack) AddMember(channel string, users ...string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.members[channel] = append(s.members[channel], users...)
+
+}
+
+// AddMessage, start a thread in channel with a top level message
+func (s *Slack) AddMessage(channel string, timestamp string, user string, text string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.threads[threadKey(channel, timestamp)] = []slackgo.Message{newMessage(channel, timestamp, "", user, text)}
+
+}
+
+// AddReply, reply to the thread started by threadTimestamp
+func (s *Slack) AddReply(channel string, threadTimestamp string, user string, text string) string {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    timestamp := s.timestamp()
+    key := threadKey(channel, threadTimestamp)
+    s.threads[key] = append(s.threads[key], newMessage(channel, timestamp, threadTimestamp, user, text))
+
+    return timestamp
+
+}
+
+// AddUser, add a workspace member, guest makes them a single channel guest
+func (s *Slack) AddUser(id string, guest bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.users[id] = &slackgo.User{ID: id, IsUltraRestricted: guest}
+
+}
+
+// SetUserProfile, give user a real name and email address, adding them as a
+// member when they do not exist
+func (s *Slack) SetUserProfile(id string, realName string, email string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    user, exists := s.users[id]
+    if !exists {
+        user = &slackgo.User{ID: id}
+        s.users[id] = user
+    }
+    user.RealName = realName
+    user.Profile.RealName = realName
+    user.Profile.Email = email
+
+}
+
+// AddUserGroup, add a user group with the given members
+func (s *Slack) AddUserGroup(id string, members ...string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.userGroups[id] = members
+
+}
+
+// Fail, answer every call of method with the Slack error code e.g.
+// "channel_not_found", an empty code stops failing
+func (s *Slack) Fail(method string, code string) {
+    if code == "" {
+        s.fail(method, nil)
+        return
+    }
+
+    s.fail(method, func(resp http.ResponseWriter, req *http.Request) {
+        s.reply(resp, http.StatusOK, map[string]interface{}{"ok": false, "error": code})
+
+    })
+
+}
+
+// Posted, the messages sent with chat.postMessage
+func (s *Slack) Posted() []slackgo.Message {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return append([]slackgo.Message(nil), s.posted...)
+
+}
+
+// Ephemeral, the messages sent with chat.postEphemeral, User is the recipient
+func (s *Slack) Ephemeral() []slackgo.Message {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return append([]slackgo.Message(nil), s.ephemeral...)
+
+}
+
+// Updated, the new versions of messages edited with chat.update
+func (s *Slack) Updated() []slackgo.Message {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return append([]slackgo.Message(nil), s.updated...)
+
+}
+
+// Responses, the answers sent to the response_url of slash commands
+func (s *Slack) Responses() []slackgo.WebhookMessage {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return append([]slackgo.WebhookMessage(nil), s.responses...)
+
+}
+
+// Views, the modals opened with views.open
+func (s *Slack) Views() []slackgo.ModalViewRequest {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return append([]slackgo.ModalViewRequest(nil), s.views...)
+
+}
+
+// Unfurled records the links of a message shown as attachments with chat.unfurl
+type Unfurled struct {
+    Channel string
+    Timestamp string
+    Unfurls map[string]slackgo.Attachment
+}
+
+// Unfurled, every chat.unfurl call in order
+func (s *Slack) Unfurled() []Unfurled {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return append([]Unfurled(nil), s.unfurled...)
+
+}
+
+func newMessage(channel string, timestamp string, threadTimestamp string, user string, text string) slackgo.Message {
+    return slackgo.Message{Msg: slackgo.Msg{
+        Type: "message",
+        Channel: channel,
+        Timestamp: timestamp,
+        ThreadTimestamp: threadTimestamp,
+        User: user,
+        Text: text,
+    }}
+
+}
+
+func (s *Slack) authTest(resp http.ResponseWriter, req *http.Request) {
+    s.reply(resp, http.StatusOK, map[string]interface{}{"ok": true, "team_id": s.TeamID, "user_id": s.BotUserID})
+
+}
+
+func (s *Slack) conversationsList(resp http.ResponseWriter, req *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    start, end, next := page(req.Form.Get("cursor"), len(s.channels), s.PageSize)
+    s.reply(resp, http.StatusOK, map[string]interface{}{
+        "ok": true,
+        "channels": s.channels[start:end],
+        "response_metadata": map[string]string{"next_cursor": next},
+    })
+
+}
+
+func (s *Slack) conversationsReplies(resp http.ResponseWriter, req *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    messages, exists := s.threads[threadKey(req.Form.Get("channel"), req.Form.Get("ts"))]
+    if !exists {
+        s.reply(resp, http.StatusOK, map[string]interface{}{"ok": false, "error": "thread_not_found"})
+        return
+    }
+
+    start, end, next := page(req.Form.Get("cursor"), len(messages), s.PageSize)
+    s.reply(resp, http.StatusOK, map[string]interface{}{
+        "ok": true,
+        "messages": messages[start:end],
+        "has_more": next != "",
+        "response_metadata": map[string]string{"next_cursor": next},
+    })
+
+}
+
+func (s *Slack) postMessage(resp http.ResponseWriter, req *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    channel, threadTimestamp := req.Form.Get("channel"), req.Form.Get("thread_ts")
+    message := newMessage(channel, s.timestamp(), threadTimestamp, s.BotUserID, req.Form.Get("text"))
+    message.BotID = "BFAKEBOT"
+    message.Blocks.UnmarshalJSON([]byte(req.Form.Get("blocks")))
+    s.posted = append(s.posted, message)
+
+    if threadTimestamp != "" {
+        key := threadKey(channel, threadTimestamp)
+        s.threads[key] = append(s.threads[key], message)
+    }
+
+    s.reply(resp, http.StatusOK, map[string]interface{}{"ok": true, "channel": channel, "ts": message.Timestamp})
+
+}
+
+func (s *Slack) postEphemeral(resp http.ResponseWriter, req *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    message := newMessage(req.Form.Get("channel"), s.timestamp(), req.Form.Get("thread_ts"), req.Form.Get("user"), req.Form.Get("text"))
+    s.ephemeral = append(s.ephemeral, message)
+
+    s.reply(resp, http.StatusOK, map[string]interface{}{"ok": true, "message_ts": message.Timestamp})
+
+}
+
+func (s *Slack) respond(resp http.ResponseWriter, req *http.Request) {
+    var message slackgo.WebhookMessage
+    if err := json.NewDecoder(req.Body).Decode(&message); err != nil {
+        resp.WriteHeader(http.StatusBadRequest)
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.responses = append(s.responses, message)
+    resp.Write([]byte("ok"))
+
+}
+
+func (s *Slack) update(resp http.ResponseWriter, req *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    channel, timestamp := req.Form.Get("channel"), req.Form.Get("ts")
+    for key, messages := range s.threads {
+        for i, message := range messages {
+            if message.Channel != channel || message.Timestamp != timestamp {
+                continue
+            }
+
+            message.Text = req.Form.Get("text")
+            message.Blocks.UnmarshalJSON([]byte(req.Form.Get("blocks")))
+            s.threads[key][i] = message
+            s.updated = append(s.updated, message)
+
+            s.reply(resp, http.StatusOK, map[string]interface{}{"ok": true, "channel": channel, "ts": timestamp, "text": message.Text})
+            return
+        }
+    }
+
+    s.reply(resp, http.StatusOK, map[string]interface{}{"ok": false, "error": "message_not_found"})
+
+}
+
+func (s *Slack) getPermalink(resp http.ResponseWriter, req *http.Request) {
+    channel, timestamp := req.Form.Get("channel"), req.Form.Get("message_ts")
+    permalink := fmt.Sprintf("https://fake.slack.com/archives/%s/p%s", channel, strings.Replace(timestamp, ".", "", 1))
+
+    s.reply(resp, http.StatusOK, map[string]interface{}{"ok": true, "channel": channel, "permalink": permalink})
+
+}
+
+func (s *Slack) viewsOpen(resp http.ResponseWriter, req *http.Request) {
+    var request struct {
+        TriggerID string `json:"trigger_id"`
+        View slackgo.ModalViewRequest `json:"view"`
+    }
+    if err := json.NewDecoder(req.Body).Decode(&request); err != nil || request.TriggerID == "" {
+        s.reply(resp, http.StatusOK, map[string]interface{}{"ok": false, "error": "invalid_trigger_id"})
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.views = append(s.views, request.View)
+
+    s.reply(resp, http.StatusOK, map[string]interface{}{"ok": true, "view": map[string]interface{}{"id": fmt.Sprintf("VFAKE%d", len(s.views)), "callback_id": request.View.CallbackID}})
+
+}
+
+func (s *Slack) unfurl(resp http.ResponseWriter, req *http.Request) {
+    unfurled := Unfurled{Channel: req.Form.Get("channel"), Timestamp: req.Form.Get("ts")}
+    if err := json.Unmarshal([]byte(req.Form.Get("unfurls")), &unfurled.Unfurls); err != nil {
+        s.reply(resp, http.StatusOK, map[string]interface{}{"ok": false, "error": "invalid_unfurls_format"})
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.unfurled = append(s.unfurled, unfurled)
+
+    s.reply(resp, http.StatusOK, map[string]interface{}{"ok": true})
+
+}
+
+func (s *Slack) conversationsInfo(resp http.ResponseWriter, req *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, channel := range s.channels {
+        if channel.ID == req.Form.Get("channel") {
+            s.reply(resp, http.StatusOK, map[string]interface{}{"ok": true, "channel": channel})
+            return
+        }
+    }
+
+    s.reply(resp, http.StatusOK, map[string]interface{}{"ok": false, "error": "channel_not_found"})
+
+}
+
+func (s *Slack) conversationsMembers(resp http.ResponseWriter, req *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    members := s.members[req.Form.Get("channel")]
+    start, end, next := page(req.Form.Get("cursor"), len(members), s.PageSize)
+    s.reply(resp, http.StatusOK, map[string]interface{}{
+        "ok": true,
+        "members": append([]string{}, members[start:end]...),
+        "response_metadata": map[string]string{"next_cursor": next},
+    })
+
+}
+
+func (s *Slack) usersInfo(resp http.ResponseWriter, req *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    user, exists := s.users[req.Form.Get("user")]
+    if !exists {
+        s.reply(resp, http.StatusOK, map[string]interface{}{"ok": false, "error": "user_not_found"})
+        return
+    }
+
+    s.reply(resp, http.StatusOK, map[string]interface{}{"ok": true, "user": user})
+
+}
+
+func (s *Slack) userGroupsUsersList(resp http.ResponseWriter, req *http.Request) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    members, exists := s.userGroups[req.Form.Get("usergroup")]
+    if !exists {
+        s.reply(resp, http.StatusOK, map[string]interface{}{"ok": false, "error": "no_such_subteam"})
+        return
+    }
+
+    s.reply(resp, http.StatusOK, map[string]interface{}{"ok": true, "users": members})
+
+}
@@ -0,0 +1,105 @@
+package tracing
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+    instrumentationName = "slack-jira-integration"
+    serviceName = "slack-jira-integration"
+
+    ExporterNone = "none"
+    ExporterOTLP = "otlp"
+)
+
+// Setup, install the global tracer provider for the given exporter, "otlp"
+// exports over OTLP/HTTP configured by the standard OTEL_EXPORTER_OTLP_*
+// variables, "none" or "" leaves tracing disabled. The returned func flushes
+// and stops the exporter
+func Setup(ctx context.Context, exporter string) (func(context.Context) error, error) {
+    switch exporter {
+    case ExporterNone, "":
+        return func(context.Context) error { return nil }, nil
+
+    case ExporterOTLP:
+        otlpExporter, err := otlptracehttp.New(ctx)
+        if err != nil {
+            return nil, fmt.Errorf("otlp exporter err: %w", err)
+        }
+
+        return SetupWithExporter(otlpExporter).Shutdown, nil
+
+    }
+
+    return nil, fmt.Errorf("unknown trace exporter '%s'", exporter)
+
+}
+
+// SetupWithExporter, install the global tracer provider batching spans to
+// exporter, tests pass an in-memory exporter and ForceFlush the provider
+func SetupWithExporter(exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+    res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+        semconv.SchemaURL,
+        semconv.ServiceNameKey.String(serviceName),
+    ))
+    if err != nil {
+        // only fails on conflicting schema urls, fall back to the sdk defaults
+        res = resource.Default()
+    }
+
+    provider := sdktrace.NewTracerProvider(
+        sdktrace.WithBatcher(exporter),
+        sdktrace.WithResource(res),
+    )
+    otel.SetTracerProvider(provider)
+
+    return provider
+
+}
+
+// Tracer, the tracer for spans created by this service, resolved on every
+// call so it follows the provider installed by Setup
+func Tracer() trace.Tracer {
+    return otel.Tracer(instrumentationName)
+
+}
+
+// Start, start a span named name as a child of any span on ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+    return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+
+}
+
+// End, record err (if any) on span and end it
+func End(span trace.Span, err error) {
+    if err != nil {
+        span.RecordError(err)
+        span.SetStatus(codes.Error, err.Error())
+    }
+
+    span.End()
+
+}
+
+// InstrumentRoundTripper, wrap next creating a client span for every
+// outbound request as a child of the span on the request's context
+func InstrumentRoundTripper(next http.RoundTripper) http.RoundTripper {
+    if next == nil {
+        next = http.DefaultTransport
+    }
+
+    return otelhttp.NewTransport(next)
+
+}
@@ -0,0 +1,42 @@
+package tracing
+
+import (
+    "context"
+    "fmt"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup(t *testing.T) {
+    shutdown, err := Setup(context.Background(), ExporterNone)
+    assert.Nil(t, err)
+    assert.Nil(t, shutdown(context.Background()))
+
+    _, err = Setup(context.Background(), "some-exporter")
+    assert.NotNil(t, err)
+
+}
+
+func TestEndRecordsError(t *testing.T) {
+    exporter := tracetest.NewInMemoryExporter()
+    provider := SetupWithExporter(exporter)
+    defer provider.Shutdown(context.Background())
+
+    ctx, parent := Start(context.Background(), "parent")
+    _, child := Start(ctx, "child")
+    End(child, fmt.Errorf("some error"))
+    End(parent, nil)
+
+    provider.ForceFlush(context.Background())
+
+    spans := exporter.GetSpans()
+    assert.Equal(t, 2, len(spans))
+    assert.Equal(t, "child", spans[0].Name)
+    assert.Equal(t, codes.Error, spans[0].Status.Code)
+    assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent.SpanID())
+    assert.Equal(t, codes.Unset, spans[1].Status.Code)
+
+}
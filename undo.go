@@ -0,0 +1,220 @@
+package runtime
+
+import (
+    "context"
+    "fmt"
+    "sync"
+    "time"
+
+	"github.com/sirupsen/logrus"
+
+    "slack-jira-integration/audit"
+    "slack-jira-integration/chat"
+    "slack-jira-integration/logging"
+    "slack-jira-integration/metrics"
+    "slack-jira-integration/tracing"
+)
+
+const (
+    // UndoDelete deletes the withdrawn issue
+    UndoDelete = "delete"
+    // UndoTransition closes the withdrawn issue through a transition
+    UndoTransition = "transition"
+)
+
+// Undo lets the user whose reaction created an issue withdraw it by removing
+// the reaction within GracePeriod, the issue is deleted or moved through the
+// Transition depending on Action, an issue the thread was only linked to is
+// unlinked
+type Undo struct {
+    GracePeriod time.Duration
+    Action string
+    Transition string
+
+    mu sync.Mutex
+    escalations map[string]*escalation
+}
+
+// escalation, an issue created from a reaction that can still be withdrawn
+type escalation struct {
+    user string
+    issueKey string
+    backend TicketBackend
+    replyTimestamp string
+    link string
+    // linked is set when the thread was linked to an existing ticket, the
+    // ticket is then only unlinked
+    linked bool
+    createdAt time.Time
+}
+
+// NewUndo, construct an Undo, action is UndoDelete or UndoTransition
+func NewUndo(gracePeriod time.Duration, action string, transition string) (*Undo, error) {
+    if action == "" {
+        action = UndoTransition
+    }
+
+    if action != UndoDelete && action != UndoTransition {
+        return nil, fmt.Errorf("unknown undo action '%s'", action)
+    }
+
+    if action == UndoTransition && transition == "" {
+        return nil, fmt.Errorf("undo by transition needs a transition name")
+    }
+
+    return &Undo{
+        GracePeriod: gracePeriod,
+        Action: action,
+        Transition: transition,
+        escalations: make(map[string]*escalation),
+    }, nil
+
+}
+
+// WithUndo, allow escalations to be withdrawn by removing the reaction,
+// every ticket backend must support the undo's Action, register the backends
+// first
+func (r *runtime) WithUndo(undo *Undo) (*runtime, error) {
+    backends := map[string]TicketBackend{"default": r.Backend}
+    for name, backend := range r.Backends {
+        if backend == r.Backend {
+            delete(backends, "default")
+        }
+        backends[name] = backend
+    }
+
+    for name, backend := range backends {
+        _, canDelete := backend.(TicketDeleter)
+        _, canTransition := backend.(TicketTransitioner)
+        if (undo.Action == UndoDelete && !canDelete) || (undo.Action == UndoTransition && !canTransition) {
+            return nil, fmt.Errorf("ticket backend '%s' cannot undo escalations by %s", name, undo.Action)
+        }
+    }
+
+    r.Undo = undo
+    return r, nil
+
+}
+
+// remember, keep the escalation withdrawable for the grace period
+func (u *Undo) remember(channelID string, timestamp string, reaction string, e *escalation) {
+    if u == nil {
+        return
+    }
+
+    u.mu.Lock()
+    defer u.mu.Unlock()
+
+    now := time.Now()
+    for key, other := range u.escalations {
+        if now.Sub(other.createdAt) > u.GracePeriod {
+            delete(u.escalations, key)
+        }
+    }
+
+    e.createdAt = now
+    u.escalations[ballotKey(channelID, timestamp, reaction)] = e
+
+}
+
+// take, the escalation to withdraw when user is its original reactor and
+// the grace period has not passed, it can only be taken once
+func (u *Undo) take(channelID string, timestamp string, reaction string, user string) (*escalation, bool) {
+    if u == nil {
+        return nil, false
+    }
+
+    u.mu.Lock()
+    defer u.mu.Unlock()
+
+    key := ballotKey(channelID, timestamp, reaction)
+    e, exists := u.escalations[key]
+    if !exists || e.user != user || time.Since(e.createdAt) > u.GracePeriod {
+        return nil, false
+    }
+
+    delete(u.escalations, key)
+    return e, true
+
+}
+
+// undoEscalation, delete or close the withdrawn issue, or unlink it when the
+// thread was linked to it, and edit the thread reply to say so, an error
+// means the issue was left as it was
+func (r *runtime) undoEscalation(ctx context.Context, frontend chat.Frontend, ev *chat.ReactionRemoved, e *escalation) (err error) {
+    ctx = logging.WithFields(ctx, logrus.Fields{"issue_key": e.issueKey, "undo_action": r.Undo.Action})
+    ctx, span := tracing.Start(ctx, "undoEscalation")
+
+    record := audit.Record{
+        Time: time.Now(),
+        Action: "undo",
+        TeamID: ev.TeamID,
+        UserID: ev.User,
+        ChannelID: ev.Channel,
+        MessageTS: ev.MessageID,
+        Emoji: ev.Reaction,
+        Project: e.backend.TicketProject(),
+        IssueKey: e.issueKey,
+        Outcome: audit.OutcomeWithdrawn,
+    }
+    defer func() {
+        tracing.End(span, err)
+
+        if err != nil {
+            record.Outcome, record.Error = audit.OutcomeFailed, err.Error()
+        }
+        r.audit(ctx, record)
+    }()
+
+    var reply string
+    deleter, canDelete := e.backend.(TicketDeleter)
+    transitioner, canTransition := e.backend.(TicketTransitioner)
+    switch {
+    case e.linked:
+        err = unlinkThread(ctx, frontend, e.backend, ev.Channel, ev.MessageID, e.issueKey)
+        reply = fmt.Sprintf("Link withdrawn by <@%s>, the thread is no longer linked to %s.", ev.User, e.issueKey)
+
+    case r.Undo.Action == UndoDelete && canDelete:
+        err = deleter.DeleteTicket(ctx, e.issueKey)
+        reply = fmt.Sprintf("Escalation withdrawn by <@%s>, %s was deleted.", ev.User, e.issueKey)
+
+    case r.Undo.Action == UndoTransition && canTransition:
+        err = transitioner.TransitionTicket(ctx, e.issueKey, r.Undo.Transition)
+        reply = fmt.Sprintf("Escalation withdrawn by <@%s>, %s was moved to %s: %s", ev.User, e.issueKey, r.Undo.Transition, e.link)
+
+    default:
+        err = fmt.Errorf("ticket backend cannot %s %s", r.Undo.Action, e.issueKey)
+    }
+
+    if err != nil {
+        return err
+    }
+
+    logging.FromContext(ctx).Info("escalation withdrawn")
+    metrics.Escalations.WithLabelValues(audit.OutcomeWithdrawn).Inc()
+    r.publish(ctx, chat.EscalationWithdrawn{
+        Frontend: frontend.Name(),
+        Channel: ev.Channel,
+        MessageID: ev.MessageID,
+        User: ev.User,
+        TicketKey: e.issueKey,
+    })
+
+    if e.replyTimestamp == "" {
+        return nil
+    }
+
+    // the reply is a card where the frontend can show one
+    update := frontend.UpdateMessage
+    if cards, canCard := frontend.(chat.CardFrontend); canCard {
+        update = cards.ReplaceTicketCard
+    }
+
+    // the issue is withdrawn either way, a stale reply is only logged
+    if err := update(ctx, ev.Channel, e.replyTimestamp, reply); err != nil {
+        logging.FromContext(ctx).WithError(err).Warn("withdrawn escalation reply not updated")
+    }
+
+    return nil
+
+}
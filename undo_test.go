@@ -0,0 +1,138 @@
+package runtime
+
+import (
+    "context"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+
+    "slack-jira-integration/audit"
+    "slack-jira-integration/chat"
+)
+
+func TestNewUndo(t *testing.T) {
+    undo, err := NewUndo(time.Minute, "", "Won't Do")
+    assert.Nil(t, err)
+    assert.Equal(t, UndoTransition, undo.Action)
+
+    _, err = NewUndo(time.Minute, "archive", "")
+    assert.NotNil(t, err)
+
+    _, err = NewUndo(time.Minute, UndoTransition, "")
+    assert.NotNil(t, err)
+
+}
+
+func TestUndoTake(t *testing.T) {
+    undo, err := NewUndo(time.Minute, UndoDelete, "")
+    assert.Nil(t, err)
+
+    undo.remember("C1", "1.1", "mega", &escalation{user: "U1", issueKey: "TEST-1"})
+
+    // only the original reactor can withdraw
+    _, exists := undo.take("C1", "1.1", "mega", "U2")
+    assert.False(t, exists)
+
+    e, exists := undo.take("C1", "1.1", "mega", "U1")
+    assert.True(t, exists)
+    assert.Equal(t, "TEST-1", e.issueKey)
+
+    // and only once
+    _, exists = undo.take("C1", "1.1", "mega", "U1")
+    assert.False(t, exists)
+
+    // not after the grace period
+    undo.remember("C1", "2.2", "mega", &escalation{user: "U1", issueKey: "TEST-2"})
+    undo.GracePeriod = 0
+    _, exists = undo.take("C1", "2.2", "mega", "U1")
+    assert.False(t, exists)
+
+}
+
+func TestReactionRemovedEventUndo(t *testing.T) {
+    for _, action := range []string{UndoDelete, UndoTransition} {
+        t.Run(action, func(t *testing.T) {
+            sink, err := audit.NewFileSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+            assert.Nil(t, err)
+
+            undo, err := NewUndo(time.Minute, action, "Won't Do")
+            assert.Nil(t, err)
+
+            r, err := newTracedRuntime(t).WithAudit(sink).WithVotes(NewVotes(2, nil, map[string]int{"U1": 2}, 0)).WithUndo(undo)
+            assert.Nil(t, err)
+
+            assert.Nil(t, r.reactionAddedEvent(context.Background(), r.SlackEnv, &chat.ReactionAdded{
+                User: "U1", Reaction: "some-emoji", Channel: "SOMECHANNELID", MessageID: "1641160687.000200",
+            }))
+
+            // someone else removing their reaction changes nothing
+            r.reactionRemovedEvent(context.Background(), r.SlackEnv, &chat.ReactionRemoved{
+                User: "U2", Reaction: "some-emoji", Channel: "SOMECHANNELID", MessageID: "1641160687.000200",
+            })
+            r.reactionRemovedEvent(context.Background(), r.SlackEnv, &chat.ReactionRemoved{
+                User: "U1", Reaction: "some-emoji", Channel: "SOMECHANNELID", MessageID: "1641160687.000200",
+            })
+
+            records, err := sink.Search(context.Background(), audit.Query{IssueKey: "TEST-1"})
+            assert.Nil(t, err)
+            assert.Equal(t, 2, len(records))
+            assert.Equal(t, "undo", records[1].Action)
+            assert.Equal(t, audit.OutcomeWithdrawn, records[1].Outcome)
+
+            // the message may be escalated again
+            assert.False(t, r.Votes.ballots[ballotKey("SOMECHANNELID", "1641160687.000200", "some-emoji")].escalated)
+        })
+    }
+
+}
+
+// ticketOnlyBackend hides every optional capability of the backend it wraps
+type ticketOnlyBackend struct {
+    TicketBackend
+}
+
+func TestWithUndoUnsupportedBackend(t *testing.T) {
+    undo, err := NewUndo(time.Minute, UndoDelete, "")
+    assert.Nil(t, err)
+
+    r := newTracedRuntime(t)
+    r.Backends = map[string]TicketBackend{"github": ticketOnlyBackend{r.Backend}}
+
+    _, err = r.WithUndo(undo)
+    assert.EqualError(t, err, "ticket backend 'github' cannot undo escalations by delete")
+    assert.Nil(t, r.Undo)
+
+}
+
+func TestReactionRemovedEventUndoUnsupported(t *testing.T) {
+    sink, err := audit.NewFileSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+    assert.Nil(t, err)
+
+    undo, err := NewUndo(time.Minute, UndoDelete, "")
+    assert.Nil(t, err)
+
+    r, err := newTracedRuntime(t).WithAudit(sink).WithVotes(NewVotes(2, nil, map[string]int{"U1": 2}, 0)).WithUndo(undo)
+    assert.Nil(t, err)
+
+    // a backend swapped after the undo was checked
+    r.Backend = ticketOnlyBackend{r.Backend}
+
+    assert.Nil(t, r.reactionAddedEvent(context.Background(), r.SlackEnv, &chat.ReactionAdded{
+        User: "U1", Reaction: "some-emoji", Channel: "SOMECHANNELID", MessageID: "1641160687.000200",
+    }))
+    r.reactionRemovedEvent(context.Background(), r.SlackEnv, &chat.ReactionRemoved{
+        User: "U1", Reaction: "some-emoji", Channel: "SOMECHANNELID", MessageID: "1641160687.000200",
+    })
+
+    records, err := sink.Search(context.Background(), audit.Query{IssueKey: "TEST-1"})
+    assert.Nil(t, err)
+    assert.Equal(t, 2, len(records))
+    assert.Equal(t, audit.OutcomeFailed, records[1].Outcome)
+    assert.Contains(t, records[1].Error, "cannot delete")
+
+    // the issue still exists, the message cannot be escalated a second time
+    assert.True(t, r.Votes.ballots[ballotKey("SOMECHANNELID", "1641160687.000200", "some-emoji")].escalated)
+
+}
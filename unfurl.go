@@ -0,0 +1,288 @@
+package runtime
+
+import (
+    "context"
+    "regexp"
+    "sort"
+    "sync"
+    "time"
+
+	"github.com/sirupsen/logrus"
+	slackgo "github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"go.opentelemetry.io/otel/attribute"
+
+    "slack-jira-integration/chat"
+    "slack-jira-integration/logging"
+    "slack-jira-integration/slack"
+    "slack-jira-integration/tracing"
+)
+
+// UnfurlEveryChannel opts every channel into unfurling
+const UnfurlEveryChannel = "*"
+
+// maxMentionedTickets caps the cards posted for the keys of one message
+const maxMentionedTickets = 3
+
+// slackLinks, the links, user and channel references of a message's text,
+// keys within them are not pasted bare
+var slackLinks = regexp.MustCompile(`<[^>]*>`)
+
+// DefaultUnfurlCacheTTL, how long a looked up ticket is shown before it is
+// looked up again
+const DefaultUnfurlCacheTTL = 5 * time.Minute
+
+// Unfurls shows links to tickets posted in the opted in Channels as compact
+// cards, tickets and channel names are cached for CacheTTL so a link pasted
+// repeatedly costs one lookup
+type Unfurls struct {
+    Channels []string
+    CacheTTL time.Duration
+
+    mu sync.Mutex
+    tickets map[string]cachedTicket
+    channelNames map[string]cachedChannel
+    now func() time.Time
+}
+
+// cachedTicket, a ticket looked up for a link
+type cachedTicket struct {
+    ticket chat.Ticket
+    expires time.Time
+}
+
+// cachedChannel, the name of a channel looked up by its ID
+type cachedChannel struct {
+    name string
+    expires time.Time
+}
+
+// NewUnfurls, construct Unfurls for the channels named, UnfurlEveryChannel
+// opts in every channel, a zero cacheTTL is DefaultUnfurlCacheTTL
+func NewUnfurls(channels []string, cacheTTL time.Duration) *Unfurls {
+    if cacheTTL <= 0 {
+        cacheTTL = DefaultUnfurlCacheTTL
+    }
+
+    return &Unfurls{
+        Channels: channels,
+        CacheTTL: cacheTTL,
+        tickets: make(map[string]cachedTicket),
+        channelNames: make(map[string]cachedChannel),
+        now: time.Now,
+    }
+
+}
+
+// WithUnfurls, unfurl links to tickets in the opted in channels
+func (r *runtime) WithUnfurls(unfurls *Unfurls) *runtime {
+    r.Unfurls = unfurls
+    return r
+
+}
+
+// optedIn, whether links posted in channelID are unfurled
+func (u *Unfurls) optedIn(ctx context.Context, slackEnv *slack.SlackEnv, channelID string) (bool, error) {
+    if contains(u.Channels, UnfurlEveryChannel) {
+        return true, nil
+    }
+
+    u.mu.Lock()
+    cached, found := u.channelNames[channelID]
+    u.mu.Unlock()
+
+    name := cached.name
+    if !found || u.now().After(cached.expires) {
+        var err error
+        name, err = slackEnv.LookupChannelName(ctx, channelID)
+        if err != nil {
+            return false, err
+        }
+
+        u.mu.Lock()
+        u.channelNames[channelID] = cachedChannel{name: name, expires: u.now().Add(u.CacheTTL)}
+        u.mu.Unlock()
+    }
+
+    return contains(u.Channels, name), nil
+
+}
+
+// ticket, the ticket link opens, looked up through backend unless a lookup
+// for link is still cached, failed lookups are not cached
+func (u *Unfurls) ticket(ctx context.Context, backend TicketBackend, link string, key string) (*chat.Ticket, error) {
+    now := u.now()
+
+    u.mu.Lock()
+    cached, found := u.tickets[link]
+    u.mu.Unlock()
+
+    if found && now.Before(cached.expires) {
+        return &cached.ticket, nil
+    }
+
+    looked, err := lookupTicket(ctx, backend, key)
+    if err != nil {
+        return nil, err
+    }
+
+    u.mu.Lock()
+    defer u.mu.Unlock()
+
+    for cachedLink, entry := range u.tickets {
+        if now.After(entry.expires) {
+            delete(u.tickets, cachedLink)
+        }
+    }
+    u.tickets[link] = cachedTicket{ticket: *looked, expires: now.Add(u.CacheTTL)}
+
+    return looked, nil
+
+}
+
+// unfurlBackends, the backends asked about links and keys, the default
+// backend first then the others by name
+func (r *runtime) unfurlBackends() []TicketBackend {
+    names := make([]string, 0, len(r.Backends))
+    for name := range r.Backends {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    candidates := []TicketBackend{r.Backend}
+    for _, name := range names {
+        candidates = append(candidates, r.Backends[name])
+    }
+
+    return candidates
+
+}
+
+// linkBackend, the backend of the ticket link opens and its key
+func (r *runtime) linkBackend(link string) (TicketBackend, string, bool) {
+    for _, backend := range r.unfurlBackends() {
+        parser, canParse := backend.(TicketURLParser)
+        if !canParse {
+            continue
+        }
+
+        if key, ok := parser.TicketKeyFromURL(link); ok {
+            return backend, key, true
+        }
+    }
+
+    return nil, "", false
+
+}
+
+// linkSharedEvent, unfurl the links to tickets of a message posted in an
+// opted in channel, links that are not to tickets keep Slack's preview
+func (r *runtime) linkSharedEvent(ctx context.Context, slackEnv *slack.SlackEnv, ev *slackevents.LinkSharedEvent) (err error) {
+    // links in a message still being written come from the composer, there
+    // is no message yet to unfurl them in
+    if r.Unfurls == nil || ev.Channel == "COMPOSER" {
+        return nil
+    }
+
+    ctx = logging.WithFields(ctx, logrus.Fields{"channel": ev.Channel, "message_ts": ev.MessageTimeStamp})
+    ctx, span := tracing.Start(ctx, "linkSharedEvent", attribute.String("slack.channel", ev.Channel))
+    defer func() {
+        tracing.End(span, err)
+    }()
+
+    optedIn, err := r.Unfurls.optedIn(ctx, slackEnv, ev.Channel)
+    if err != nil || !optedIn {
+        return err
+    }
+
+    unfurls := make(map[string]slackgo.Attachment)
+    for _, link := range ev.Links {
+        backend, key, ok := r.linkBackend(link.URL)
+        if !ok {
+            continue
+        }
+
+        // a ticket that cannot be looked up keeps Slack's preview
+        ticket, err := r.Unfurls.ticket(ctx, backend, link.URL, key)
+        if err != nil {
+            logging.FromContext(ctx).WithError(err).WithField("issue_key", key).Info("linked ticket not found")
+            continue
+        }
+
+        unfurls[link.URL] = slack.TicketUnfurl(*ticket)
+    }
+
+    if len(unfurls) == 0 {
+        return nil
+    }
+
+    return slackEnv.UnfurlLinks(ctx, ev.Channel, ev.MessageTimeStamp, unfurls)
+
+}
+
+// messageEvent, show the tickets whose keys are pasted bare in a message
+// posted in an opted in channel as compact cards in its thread, Slack only
+// sends link_shared for links so keys are found with FindTicketKeys, linked
+// keys are left to linkSharedEvent
+func (r *runtime) messageEvent(ctx context.Context, slackEnv *slack.SlackEnv, ev *slackevents.MessageEvent) (err error) {
+    // edits, joins and bot posts, the integration's own cards among them,
+    // have a subtype or a bot ID
+    if r.Unfurls == nil || ev.SubType != "" || ev.BotID != "" {
+        return nil
+    }
+
+    text := slackLinks.ReplaceAllString(ev.Text, " ")
+    var found []TicketBackend
+    var keys []string
+    for _, backend := range r.unfurlBackends() {
+        finder, canFind := backend.(TicketKeyFinder)
+        if !canFind {
+            continue
+        }
+
+        for _, key := range finder.FindTicketKeys(text) {
+            if !contains(keys, key) && len(keys) < maxMentionedTickets {
+                found, keys = append(found, backend), append(keys, key)
+            }
+        }
+    }
+
+    if len(keys) == 0 {
+        return nil
+    }
+
+    ctx = logging.WithFields(ctx, logrus.Fields{"channel": ev.Channel, "message_ts": ev.TimeStamp})
+    ctx, span := tracing.Start(ctx, "messageEvent", attribute.String("slack.channel", ev.Channel))
+    defer func() {
+        tracing.End(span, err)
+    }()
+
+    optedIn, err := r.Unfurls.optedIn(ctx, slackEnv, ev.Channel)
+    if err != nil || !optedIn {
+        return err
+    }
+
+    var tickets []chat.Ticket
+    for i, key := range keys {
+        // a key that cannot be looked up is left as text
+        ticket, err := r.Unfurls.ticket(ctx, found[i], found[i].TicketURL(key), key)
+        if err != nil {
+            logging.FromContext(ctx).WithError(err).WithField("issue_key", key).Info("mentioned ticket not found")
+            continue
+        }
+
+        tickets = append(tickets, *ticket)
+    }
+
+    if len(tickets) == 0 {
+        return nil
+    }
+
+    thread := ev.ThreadTimeStamp
+    if thread == "" {
+        thread = ev.TimeStamp
+    }
+
+    return slackEnv.PostTicketUnfurls(ctx, ev.Channel, thread, tickets)
+
+}
@@ -0,0 +1,159 @@
+package runtime
+
+import (
+    "context"
+    "encoding/json"
+    "testing"
+    "time"
+
+	slackgo "github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+    "slack-jira-integration/testing/fakes"
+)
+
+// linkShared, the link_shared event of the message at timestamp in channel
+func linkShared(t *testing.T, channel string, timestamp string, links ...string) *slackevents.LinkSharedEvent {
+    var envelope struct {
+        Event slackevents.LinkSharedEvent `json:"event"`
+    }
+    require.NoError(t, json.Unmarshal(fakes.LinkShared("TFAKETEAM", "U1", channel, timestamp, links...), &envelope))
+
+    return &envelope.Event
+
+}
+
+func TestLinkSharedEventUnfurls(t *testing.T) {
+    r, slackAPI, jiraAPI := newLinkRuntime(t)
+    slackAPI.AddChannel("C0RANDOM", "random")
+    slackAPI.AddChannel("C0DEPLOYS", "deploys")
+    key := jiraAPI.AddIssue("OPS", "checkout latency")
+    link := jiraAPI.URL + "/browse/" + key
+
+    unfurls := NewUnfurls([]string{"alerts", "deploys"}, time.Minute)
+    now := time.Date(2022, 1, 2, 10, 0, 0, 0, time.UTC)
+    unfurls.now = func() time.Time { return now }
+    r.WithUnfurls(unfurls)
+
+    ev := linkShared(t, "C0DEPLOYS", "1641160800.000100", link, jiraAPI.URL + "/browse/OPS-9", "https://example.com/status")
+    require.NoError(t, r.linkSharedEvent(context.Background(), r.SlackEnv, ev))
+
+    unfurled := slackAPI.Unfurled()
+    require.Len(t, unfurled, 1)
+    assert.Equal(t, "C0DEPLOYS", unfurled[0].Channel)
+    assert.Equal(t, "1641160800.000100", unfurled[0].Timestamp)
+    require.Len(t, unfurled[0].Unfurls, 1)
+    title := unfurled[0].Unfurls[link].Blocks.BlockSet[0].(*slackgo.SectionBlock)
+    assert.Equal(t, "*<" + link + "|" + key + ">* checkout latency", title.Text.Text)
+
+    // the issue and the channel name are cached
+    getIssues, channelInfos := jiraAPI.Calls("getIssue"), slackAPI.Calls("conversations.info")
+    require.NoError(t, r.linkSharedEvent(context.Background(), r.SlackEnv, linkShared(t, "C0DEPLOYS", "1641160800.000200", link)))
+    assert.Equal(t, getIssues, jiraAPI.Calls("getIssue"))
+    assert.Equal(t, channelInfos, slackAPI.Calls("conversations.info"))
+    assert.Len(t, slackAPI.Unfurled(), 2)
+
+    // until they expire
+    now = now.Add(2 * time.Minute)
+    require.NoError(t, r.linkSharedEvent(context.Background(), r.SlackEnv, linkShared(t, "C0DEPLOYS", "1641160800.000300", link)))
+    assert.Equal(t, getIssues + 1, jiraAPI.Calls("getIssue"))
+
+    // configured channels are known without asking Slack
+    require.NoError(t, r.linkSharedEvent(context.Background(), r.SlackEnv, linkShared(t, "C0ALERTS", "1641160800.000400", link)))
+    assert.Equal(t, channelInfos + 1, slackAPI.Calls("conversations.info"))
+    assert.Len(t, slackAPI.Unfurled(), 4)
+
+    // channels that have not opted in keep Slack's previews
+    require.NoError(t, r.linkSharedEvent(context.Background(), r.SlackEnv, linkShared(t, "C0RANDOM", "1641160800.000500", link)))
+    assert.Len(t, slackAPI.Unfurled(), 4)
+
+    // as do messages without links to existing issues
+    require.NoError(t, r.linkSharedEvent(context.Background(), r.SlackEnv, linkShared(t, "C0ALERTS", "1641160800.000600", jiraAPI.URL + "/browse/OPS-9")))
+    assert.Len(t, slackAPI.Unfurled(), 4)
+
+    r.WithUnfurls(NewUnfurls([]string{UnfurlEveryChannel}, 0))
+    require.NoError(t, r.linkSharedEvent(context.Background(), r.SlackEnv, linkShared(t, "C0RANDOM", "1641160800.000700", link)))
+    assert.Len(t, slackAPI.Unfurled(), 5)
+
+}
+
+func TestLinkSharedEventWithoutUnfurls(t *testing.T) {
+    r, slackAPI, jiraAPI := newLinkRuntime(t)
+    key := jiraAPI.AddIssue("OPS", "checkout latency")
+
+    require.NoError(t, r.linkSharedEvent(context.Background(), r.SlackEnv, linkShared(t, "C0ALERTS", "1641160800.000100", jiraAPI.URL + "/browse/" + key)))
+    assert.Empty(t, slackAPI.Unfurled())
+    assert.Equal(t, 0, jiraAPI.Calls("getIssue"))
+
+}
+
+// message, the message event of user posting text at timestamp in channel
+func message(t *testing.T, channel string, timestamp string, text string) *slackevents.MessageEvent {
+    var envelope struct {
+        Event slackevents.MessageEvent `json:"event"`
+    }
+    require.NoError(t, json.Unmarshal(fakes.Message("TFAKETEAM", "U1", channel, timestamp, text), &envelope))
+
+    return &envelope.Event
+
+}
+
+func TestMessageEventUnfurlsPastedKeys(t *testing.T) {
+    r, slackAPI, jiraAPI := newLinkRuntime(t)
+    slackAPI.AddChannel("C0RANDOM", "random")
+    first := jiraAPI.AddIssue("OPS", "checkout latency")
+    second := jiraAPI.AddIssue("OPS", "cart errors")
+    r.WithUnfurls(NewUnfurls([]string{"alerts"}, time.Minute))
+
+    ev := message(t, "C0ALERTS", "1641160800.000100", "is " + first + " the same as " + second + " or " + first + "? not OPS-9, UTF-8")
+    require.NoError(t, r.messageEvent(context.Background(), r.SlackEnv, ev))
+
+    posted := slackAPI.Posted()
+    require.Len(t, posted, 1)
+    assert.Equal(t, "1641160800.000100", posted[0].ThreadTimestamp)
+    assert.Equal(t, jiraAPI.URL + "/browse/" + first + " " + jiraAPI.URL + "/browse/" + second, posted[0].Text)
+    require.Len(t, posted[0].Blocks.BlockSet, 4)
+    title := posted[0].Blocks.BlockSet[2].(*slackgo.SectionBlock)
+    assert.Equal(t, "*<" + jiraAPI.URL + "/browse/" + second + "|" + second + ">* cart errors", title.Text.Text)
+
+    // the lookups are cached like those of links
+    getIssues := jiraAPI.Calls("getIssue")
+    require.NoError(t, r.messageEvent(context.Background(), r.SlackEnv, message(t, "C0ALERTS", "1641160800.000200", first)))
+    assert.Equal(t, getIssues, jiraAPI.Calls("getIssue"))
+    assert.Len(t, slackAPI.Posted(), 2)
+
+    // links are unfurled from link_shared, bots and edits are left alone
+    linked := message(t, "C0ALERTS", "1641160800.000300", "see <" + jiraAPI.URL + "/browse/" + first + "|" + first + ">")
+    bot := message(t, "C0ALERTS", "1641160800.000400", first)
+    bot.BotID = "B0INTEGRATION"
+    edited := message(t, "C0ALERTS", "1641160800.000500", first)
+    edited.SubType = "message_changed"
+    for _, ev := range []*slackevents.MessageEvent{linked, bot, edited} {
+        require.NoError(t, r.messageEvent(context.Background(), r.SlackEnv, ev))
+    }
+    assert.Len(t, slackAPI.Posted(), 2)
+
+    // as are channels that have not opted in
+    require.NoError(t, r.messageEvent(context.Background(), r.SlackEnv, message(t, "C0RANDOM", "1641160800.000600", first)))
+    assert.Len(t, slackAPI.Posted(), 2)
+
+    // replies are answered in their thread
+    reply := message(t, "C0ALERTS", "1641160800.000700", second)
+    reply.ThreadTimeStamp = "1641160800.000100"
+    require.NoError(t, r.messageEvent(context.Background(), r.SlackEnv, reply))
+    require.Len(t, slackAPI.Posted(), 3)
+    assert.Equal(t, "1641160800.000100", slackAPI.Posted()[2].ThreadTimestamp)
+
+}
+
+func TestMessageEventWithoutUnfurls(t *testing.T) {
+    r, slackAPI, jiraAPI := newLinkRuntime(t)
+    key := jiraAPI.AddIssue("OPS", "checkout latency")
+
+    require.NoError(t, r.messageEvent(context.Background(), r.SlackEnv, message(t, "C0ALERTS", "1641160800.000100", key)))
+    assert.Empty(t, slackAPI.Posted())
+    assert.Equal(t, 0, jiraAPI.Calls("getIssue"))
+
+}
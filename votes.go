@@ -0,0 +1,192 @@
+package runtime
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "slack-jira-integration/chat"
+)
+
+// Votes counts the distinct users reacting to a message so noisy channels
+// only escalate once enough of them agree, a user's vote counts Weights[user]
+// (default 1) toward the threshold of the channel
+type Votes struct {
+    Threshold int
+    Thresholds map[string]int
+    Weights map[string]int
+    Window time.Duration
+
+    mu sync.Mutex
+    ballots map[string]*ballot
+}
+
+// ballot, the reactors of one message with one emoji
+type ballot struct {
+    voters map[string]bool
+    escalated bool
+    openedAt time.Time
+}
+
+// NewVotes, construct Votes escalating at threshold, or thresholds[channel]
+// for channels given by name or id, ballots older than window (default a
+// day) are forgotten
+func NewVotes(threshold int, thresholds map[string]int, weights map[string]int, window time.Duration) *Votes {
+    if window <= 0 {
+        window = 24 * time.Hour
+    }
+
+    return &Votes{
+        Threshold: threshold,
+        Thresholds: thresholds,
+        Weights: weights,
+        Window: window,
+        ballots: make(map[string]*ballot),
+    }
+
+}
+
+// ParseWeights, parse a comma separated list of user=weight pairs e.g. "U123=3,U456=2"
+func ParseWeights(weights string) (map[string]int, error) {
+    parsed := make(map[string]int)
+
+    for _, pair := range strings.Split(weights, ",") {
+        pair = strings.TrimSpace(pair)
+        if pair == "" {
+            continue
+        }
+
+        parts := strings.SplitN(pair, "=", 2)
+        if len(parts) != 2 {
+            return nil, fmt.Errorf("invalid vote weight '%s', expected user=weight", pair)
+        }
+
+        weight, err := strconv.Atoi(parts[1])
+        if err != nil || weight < 0 {
+            return nil, fmt.Errorf("invalid vote weight '%s', expected user=weight", pair)
+        }
+
+        parsed[parts[0]] = weight
+    }
+
+    return parsed, nil
+
+}
+
+// WithVotes, require votes to reach a threshold before escalating
+func (r *runtime) WithVotes(votes *Votes) *runtime {
+    r.Votes = votes
+    return r
+
+}
+
+// threshold, the votes needed in channelID, configured by id or name
+func (v *Votes) threshold(frontend chat.Frontend, channelID string) int {
+    if threshold, exists := v.Thresholds[channelID]; exists {
+        return threshold
+    }
+
+    if threshold, exists := v.Thresholds[frontend.ChannelName(channelID)]; exists {
+        return threshold
+    }
+
+    return v.Threshold
+
+}
+
+func (v *Votes) weight(user string) int {
+    if weight, exists := v.Weights[user]; exists {
+        return weight
+    }
+
+    return 1
+
+}
+
+func ballotKey(channelID string, timestamp string, reaction string) string {
+    return channelID + "/" + timestamp + "/" + reaction
+
+}
+
+// prune, forget ballots that stayed open longer than Window
+func (v *Votes) prune(now time.Time) {
+    for key, b := range v.ballots {
+        if now.Sub(b.openedAt) > v.Window {
+            delete(v.ballots, key)
+        }
+    }
+
+}
+
+// Add, count user's reaction, reached is true only for the vote that takes
+// the message over the threshold so each message escalates once, a nil Votes
+// or a threshold of one escalates every reaction as before
+func (v *Votes) Add(frontend chat.Frontend, channelID string, timestamp string, reaction string, user string) (reached bool, total int, needed int) {
+    if v == nil {
+        return true, 1, 1
+    }
+
+    needed = v.threshold(frontend, channelID)
+    if needed <= 1 && v.weight(user) >= 1 {
+        return true, 1, needed
+    }
+
+    v.mu.Lock()
+    defer v.mu.Unlock()
+
+    now := time.Now()
+    v.prune(now)
+
+    key := ballotKey(channelID, timestamp, reaction)
+    b, exists := v.ballots[key]
+    if !exists {
+        b = &ballot{voters: make(map[string]bool), openedAt: now}
+        v.ballots[key] = b
+    }
+    b.voters[user] = true
+
+    for voter := range b.voters {
+        total += v.weight(voter)
+    }
+
+    if b.escalated || total < needed {
+        return false, total, needed
+    }
+
+    b.escalated = true
+    return true, total, needed
+
+}
+
+// Reopen, let the message escalate again when its escalation did not go
+// through e.g. it was rate limited or no issue could be created
+func (v *Votes) Reopen(channelID string, timestamp string, reaction string) {
+    if v == nil {
+        return
+    }
+
+    v.mu.Lock()
+    defer v.mu.Unlock()
+
+    if b, exists := v.ballots[ballotKey(channelID, timestamp, reaction)]; exists {
+        b.escalated = false
+    }
+
+}
+
+// Remove, withdraw user's vote, a message that already escalated stays escalated
+func (v *Votes) Remove(channelID string, timestamp string, reaction string, user string) {
+    if v == nil {
+        return
+    }
+
+    v.mu.Lock()
+    defer v.mu.Unlock()
+
+    if b, exists := v.ballots[ballotKey(channelID, timestamp, reaction)]; exists && !b.escalated {
+        delete(b.voters, user)
+    }
+
+}
@@ -0,0 +1,104 @@
+package runtime
+
+import (
+    "context"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+
+    "slack-jira-integration/audit"
+    "slack-jira-integration/chat"
+    "slack-jira-integration/slack"
+)
+
+func TestParseWeights(t *testing.T) {
+    weights, err := ParseWeights("U1=3, U2=0,")
+    assert.Nil(t, err)
+    assert.Equal(t, map[string]int{"U1": 3, "U2": 0}, weights)
+
+    _, err = ParseWeights("U1")
+    assert.NotNil(t, err)
+
+    _, err = ParseWeights("U1=-1")
+    assert.NotNil(t, err)
+
+}
+
+func TestVotes(t *testing.T) {
+    slackEnv := &slack.SlackEnv{SlackChannelNamesByID: map[string]string{"C1": "noisy"}}
+    votes := NewVotes(1, map[string]int{"noisy": 3}, map[string]int{"ULEAD": 2, "UBOT": 0}, time.Hour)
+
+    // other channels keep escalating on every reaction
+    reached, _, _ := votes.Add(slackEnv, "C2", "1.1", "mega", "U1")
+    assert.True(t, reached)
+
+    reached, total, needed := votes.Add(slackEnv, "C1", "1.1", "mega", "U1")
+    assert.False(t, reached)
+    assert.Equal(t, 1, total)
+    assert.Equal(t, 3, needed)
+
+    // the same user reacting again is not a new vote, a zero weight never counts
+    reached, total, _ = votes.Add(slackEnv, "C1", "1.1", "mega", "U1")
+    assert.False(t, reached)
+    assert.Equal(t, 1, total)
+    reached, total, _ = votes.Add(slackEnv, "C1", "1.1", "mega", "UBOT")
+    assert.False(t, reached)
+    assert.Equal(t, 1, total)
+
+    votes.Remove("C1", "1.1", "mega", "U1")
+    reached, total, _ = votes.Add(slackEnv, "C1", "1.1", "mega", "ULEAD")
+    assert.False(t, reached)
+    assert.Equal(t, 2, total)
+
+    reached, total, _ = votes.Add(slackEnv, "C1", "1.1", "mega", "U2")
+    assert.True(t, reached)
+    assert.Equal(t, 3, total)
+
+    // a message escalates once
+    reached, _, _ = votes.Add(slackEnv, "C1", "1.1", "mega", "U3")
+    assert.False(t, reached)
+
+    votes.Reopen("C1", "1.1", "mega")
+    reached, _, _ = votes.Add(slackEnv, "C1", "1.1", "mega", "U4")
+    assert.True(t, reached)
+
+}
+
+func TestReactionAddedEventThreshold(t *testing.T) {
+    sink, err := audit.NewFileSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+    assert.Nil(t, err)
+
+    r := newTracedRuntime(t).WithAudit(sink).WithVotes(NewVotes(2, nil, nil, time.Hour))
+
+    reaction := func(user string) *chat.ReactionAdded {
+        return &chat.ReactionAdded{
+            User: user,
+            Reaction: "some-emoji",
+            Channel: "SOMECHANNELID",
+            MessageID: "1641160687.000200",
+        }
+    }
+
+    assert.Nil(t, r.reactionAddedEvent(context.Background(), r.SlackEnv, reaction("U1")))
+
+    // withdrawing the reaction withdraws the vote
+    r.reactionRemovedEvent(context.Background(), r.SlackEnv, &chat.ReactionRemoved{
+        User: "U1",
+        Reaction: "some-emoji",
+        Channel: "SOMECHANNELID",
+        MessageID: "1641160687.000200",
+    })
+    assert.Nil(t, r.reactionAddedEvent(context.Background(), r.SlackEnv, reaction("U2")))
+    assert.Nil(t, r.reactionAddedEvent(context.Background(), r.SlackEnv, reaction("U3")))
+
+    records, err := sink.Search(context.Background(), audit.Query{})
+    assert.Nil(t, err)
+    assert.Equal(t, 3, len(records))
+    assert.Equal(t, audit.OutcomeVoted, records[0].Outcome)
+    assert.Equal(t, audit.OutcomeVoted, records[1].Outcome)
+    assert.Equal(t, audit.OutcomeCreated, records[2].Outcome)
+    assert.Equal(t, "TEST-1", records[2].IssueKey)
+
+}